@@ -0,0 +1,122 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/parser"
+)
+
+// ValidateBundle checks a bundle before importing it, with the same rules as creating its recipes and lists by hand
+func ValidateBundle(bundle data.Bundle) error {
+	if bundle.Version != data.BundleVersion {
+		return fmt.Errorf("unsupported bundle version %d, expected %d", bundle.Version, data.BundleVersion)
+	}
+
+	for i, recipe := range bundle.Recipes {
+		if err := validateBundleRecipe(recipe); err != nil {
+			return fmt.Errorf("recipe %d %q: %w", i+1, recipe.Name, err)
+		}
+	}
+	for i, list := range bundle.Lists {
+		if err := validateBundleList(list); err != nil {
+			return fmt.Errorf("list %d %q: %w", i+1, list.Name, err)
+		}
+	}
+	return nil
+}
+
+func validateBundleList(list data.BundleList) error {
+	if strings.TrimSpace(list.Name) == "" {
+		return fmt.Errorf("name missing")
+	} else if utf8.RuneCountInString(list.Name) > data.ListNameMaxLength {
+		return fmt.Errorf("name is longer than %d characters", data.ListNameMaxLength)
+	} else if utf8.RuneCountInString(list.Description) > data.ListDescriptionMaxLength {
+		return fmt.Errorf("description is longer than %d characters", data.ListDescriptionMaxLength)
+	}
+
+	productIDs := map[string]bool{}
+	for _, ingredient := range list.Ingredients {
+		if ingredient.ProductID == "" || len(ingredient.ProductID) > data.ProductIDMaxLength {
+			return fmt.Errorf("invalid product ID %q", ingredient.ProductID)
+		} else if productIDs[ingredient.ProductID] {
+			return fmt.Errorf("product %s is listed more than once", ingredient.ProductID)
+		} else if ingredient.Quantity <= 0 {
+			return fmt.Errorf("product %s has invalid quantity %v", ingredient.ProductID, ingredient.Quantity)
+		} else if (ingredient.Unit == "") != (ingredient.Amount == 0) || ingredient.Amount < 0 {
+			return fmt.Errorf("product %s has invalid amount %v %s", ingredient.ProductID, ingredient.Amount, ingredient.Unit)
+		} else if ingredient.Unit != "" && !parser.ConvertibleUnit(ingredient.Unit) {
+			return fmt.Errorf("product %s has unknown unit %q", ingredient.ProductID, ingredient.Unit)
+		} else if utf8.RuneCountInString(ingredient.Note) > data.IngredientNoteMaxLength {
+			return fmt.Errorf("product %s note is longer than %d characters", ingredient.ProductID, data.IngredientNoteMaxLength)
+		} else if utf8.RuneCountInString(ingredient.DisplayName) > data.IngredientDisplayNameMaxLength {
+			return fmt.Errorf("product %s display name is longer than %d characters", ingredient.ProductID, data.IngredientDisplayNameMaxLength)
+		} else if utf8.RuneCountInString(ingredient.Section) > data.IngredientSectionMaxLength {
+			return fmt.Errorf("product %s section is longer than %d characters", ingredient.ProductID, data.IngredientSectionMaxLength)
+		}
+		for _, alternative := range ingredient.Alternatives {
+			if alternative == "" || len(alternative) > data.ProductIDMaxLength {
+				return fmt.Errorf("product %s has invalid alternative %q", ingredient.ProductID, alternative)
+			}
+		}
+		productIDs[ingredient.ProductID] = true
+	}
+	return nil
+}
+
+func validateBundleRecipe(recipe data.BundleRecipe) error {
+	if err := validateBundleList(recipe.BundleList); err != nil {
+		return err
+	}
+
+	if !slices.Contains([]string{data.VisibilityPublic, data.VisibilityFriends, data.VisibilityPrivate}, recipe.Visibility) {
+		return fmt.Errorf("invalid visibility %q", recipe.Visibility)
+	}
+	switch recipe.InstructionType {
+	case data.InstructionTypeNone:
+	case data.InstructionTypeText:
+		if recipe.Instructions == "" && len(recipe.Steps) == 0 {
+			return fmt.Errorf("instructions missing")
+		}
+	case data.InstructionTypeLink:
+		if !WebURL(recipe.Instructions) {
+			return fmt.Errorf("instructions link must be an http or https URL")
+		}
+	default:
+		return fmt.Errorf("invalid instruction type %q", recipe.InstructionType)
+	}
+
+	if len(recipe.Tags) > data.RecipeTagsMax {
+		return fmt.Errorf("has %d tags, it can't have more than %d", len(recipe.Tags), data.RecipeTagsMax)
+	}
+	for _, tag := range recipe.Tags {
+		// Tags are normalized like the ones typed in, but invalid ones are rejected instead of cut short
+		if strings.TrimSpace(tag) == "" {
+			return fmt.Errorf("has an empty tag")
+		} else if !utf8.ValidString(tag) || strings.ContainsFunc(tag, unicode.IsControl) {
+			return fmt.Errorf("tag %q has invalid characters", tag)
+		} else if utf8.RuneCountInString(strings.Join(strings.Fields(tag), " ")) > data.TagNameMaxLength {
+			return fmt.Errorf("tag %q is longer than %d characters", tag, data.TagNameMaxLength)
+		}
+	}
+
+	for i, step := range recipe.Steps {
+		if strings.TrimSpace(step.Text) == "" {
+			return fmt.Errorf("step %d is empty", i+1)
+		} else if utf8.RuneCountInString(step.Text) > data.RecipeStepMaxLength {
+			return fmt.Errorf("step %d is longer than %d characters", i+1, data.RecipeStepMaxLength)
+		} else if step.TimerSeconds < 0 {
+			return fmt.Errorf("step %d timer can't be negative", i+1)
+		}
+		for _, productID := range step.ProductIDs {
+			if !slices.ContainsFunc(recipe.Ingredients, func(ingredient data.BundleIngredient) bool { return ingredient.ProductID == productID }) {
+				return fmt.Errorf("step %d uses product %s, which isn't an ingredient", i+1, productID)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+)
+
+func TestValidateBundle(t *testing.T) {
+	validRecipe := func() data.BundleRecipe {
+		return data.BundleRecipe{
+			BundleList: data.BundleList{
+				Name: "Chili",
+				Ingredients: []data.BundleIngredient{
+					{ProductID: "0001111060903", Quantity: 1, Amount: 1, Unit: "lb"},
+					{ProductID: "0001111090145", Quantity: 0.5, Alternatives: []string{"0001111090146"}},
+				},
+			},
+			InstructionType: data.InstructionTypeText,
+			Instructions:    "Brown the beef",
+			Visibility:      data.VisibilityPrivate,
+			Tags:            []string{"dinner"},
+			Steps:           []data.RecipeStep{{Number: 1, Text: "Brown the beef", ProductIDs: []string{"0001111060903"}}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		edit    func(*data.Bundle)
+		wantErr string // a part of the error, empty when the bundle is valid
+	}{
+		{"valid", func(*data.Bundle) {}, ""},
+		{"version", func(b *data.Bundle) { b.Version++ }, "unsupported bundle version"},
+		{"list name missing", func(b *data.Bundle) { b.Lists[0].Name = " " }, "name missing"},
+		{"long name", func(b *data.Bundle) { b.Lists[0].Name = strings.Repeat("a", data.ListNameMaxLength+1) }, "name is longer"},
+		{"long product ID", func(b *data.Bundle) { b.Lists[0].Ingredients[0].ProductID = "00011110609031" }, "invalid product ID"},
+		{"duplicate product", func(b *data.Bundle) {
+			b.Lists[0].Ingredients = append(b.Lists[0].Ingredients, b.Lists[0].Ingredients[0])
+		}, "listed more than once"},
+		{"zero quantity", func(b *data.Bundle) { b.Lists[0].Ingredients[0].Quantity = 0 }, "invalid quantity"},
+		{"amount without unit", func(b *data.Bundle) { b.Recipes[0].Ingredients[0].Unit = "" }, "invalid amount"},
+		{"unknown unit", func(b *data.Bundle) { b.Recipes[0].Ingredients[0].Unit = "handful" }, "unknown unit"},
+		{"empty alternative", func(b *data.Bundle) { b.Recipes[0].Ingredients[1].Alternatives = []string{""} }, "invalid alternative"},
+		{"visibility", func(b *data.Bundle) { b.Recipes[0].Visibility = "everyone" }, "invalid visibility"},
+		{"instructions missing", func(b *data.Bundle) {
+			b.Recipes[0].Instructions = ""
+			b.Recipes[0].Steps = nil
+		}, "instructions missing"},
+		{"script link", func(b *data.Bundle) {
+			b.Recipes[0].InstructionType = data.InstructionTypeLink
+			b.Recipes[0].Instructions = "javascript:alert(1)"
+		}, "http or https"},
+		{"instruction type", func(b *data.Bundle) { b.Recipes[0].InstructionType = "video" }, "invalid instruction type"},
+		{"too many tags", func(b *data.Bundle) {
+			b.Recipes[0].Tags = make([]string, data.RecipeTagsMax+1)
+			for i := range b.Recipes[0].Tags {
+				b.Recipes[0].Tags[i] = "tag"
+			}
+		}, "tags"},
+		{"empty tag", func(b *data.Bundle) { b.Recipes[0].Tags = []string{"  "} }, "empty tag"},
+		{"control character tag", func(b *data.Bundle) { b.Recipes[0].Tags = []string{"din\x00ner"} }, "invalid characters"},
+		{"long tag", func(b *data.Bundle) {
+			b.Recipes[0].Tags = []string{strings.Repeat("a", data.TagNameMaxLength+1)}
+		}, "tag"},
+		{"spaced tag", func(b *data.Bundle) {
+			b.Recipes[0].Tags = []string{" " + strings.Repeat("a", data.TagNameMaxLength) + "  "}
+		}, ""},
+		{"empty step", func(b *data.Bundle) { b.Recipes[0].Steps[0].Text = "" }, "step 1 is empty"},
+		{"negative timer", func(b *data.Bundle) { b.Recipes[0].Steps[0].TimerSeconds = -1 }, "timer"},
+		{"step product", func(b *data.Bundle) { b.Recipes[0].Steps[0].ProductIDs = []string{"0009999999999"} }, "isn't an ingredient"},
+	}
+	for _, test := range tests {
+		recipe := validRecipe()
+		bundle := data.Bundle{
+			Version: data.BundleVersion,
+			Recipes: []data.BundleRecipe{recipe},
+			Lists:   []data.BundleList{validRecipe().BundleList},
+		}
+		test.edit(&bundle)
+
+		err := ValidateBundle(bundle)
+		if test.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: ValidateBundle() = %v, want nil", test.name, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("%s: ValidateBundle() = %v, want an error containing %q", test.name, err, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/kroger"
+	"github.com/densestvoid/krogerrecipeshopper/parser"
+)
+
+// IngredientPick is the product bought for an ingredient
+type IngredientPick struct {
+	ProductID string
+	Quantity  int // percentage of the picked product's package
+	Staple    bool
+	// The ingredient's product followed by its alternatives, empty when it has none
+	Alternatives []string
+}
+
+// PickIngredientProducts picks the product bought for each ingredient.
+// Only ingredients with alternatives need their products looked up at the account's store, so the manager is only created for them.
+func PickIngredientProducts(ctx context.Context, account data.Account, ingredients []data.Ingredient, newKrogerManager func(context.Context) (*KrogerManager, error)) ([]IngredientPick, error) {
+	productIDs := []string{}
+	for _, ingredient := range ingredients {
+		if len(ingredient.Alternatives) > 0 {
+			productIDs = append(productIDs, ingredient.ProductID)
+			productIDs = append(productIDs, ingredient.Alternatives...)
+		}
+	}
+
+	products := map[string]data.CacheProduct{}
+	offers := map[string]data.CacheProductOffer{}
+	if len(productIDs) > 0 {
+		krogerManager, err := newKrogerManager(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("creating kroger manager: %w", err)
+		}
+		if products, err = krogerManager.GetProducts(ctx, account.LocationID, productIDs...); err != nil {
+			return nil, err
+		}
+		// Without a store nothing is known to be in stock, so the ingredients' own products are bought
+		if account.LocationID != nil {
+			if offers, err = krogerManager.GetProductOffers(ctx, *account.LocationID, productIDs...); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	picks := []IngredientPick{}
+	for _, ingredient := range ingredients {
+		picks = append(picks, PickIngredientProduct(ingredient, products, offers, account.AlternativeChoice))
+	}
+	return picks, nil
+}
+
+// PickIngredientProduct picks the first of the ingredient's products in stock, or the cheapest in stock for the quantity needed.
+// Products without a price are only picked by cheapest when none have one, and the ingredient's own product is picked when none are in stock.
+func PickIngredientProduct(ingredient data.Ingredient, products map[string]data.CacheProduct, offers map[string]data.CacheProductOffer, alternativeChoice string) IngredientPick {
+	pick := IngredientPick{ProductID: ingredient.ProductID, Quantity: ingredient.Quantity, Staple: ingredient.Staple}
+	if len(ingredient.Alternatives) == 0 {
+		return pick
+	}
+	pick.Alternatives = append([]string{ingredient.ProductID}, ingredient.Alternatives...)
+
+	var inStock, cheapest *IngredientPick
+	var cheapestCost float64
+	for _, productID := range pick.Alternatives {
+		offer, ok := offers[productID]
+		if !ok || !ProductOfferInStock(offer) {
+			continue
+		}
+
+		candidate := pick
+		candidate.ProductID, candidate.Quantity = productID, alternativeQuantity(ingredient, products[productID])
+		if inStock == nil {
+			inStock = &candidate
+		}
+		if alternativeChoice != data.AlternativeChoiceCheapest {
+			break
+		}
+
+		// Whole packages are bought, so the cost is of every package the quantity needs
+		cost := offer.Price * math.Ceil(float64(candidate.Quantity)/100)
+		if offer.Price > 0 && (cheapest == nil || cost < cheapestCost) {
+			cheapest, cheapestCost = &candidate, cost
+		}
+	}
+
+	switch {
+	case cheapest != nil:
+		return *cheapest
+	case inStock != nil:
+		return *inStock
+	default:
+		return pick
+	}
+}
+
+// ProductOfferInStock reports whether the store has the product.
+// Kroger leaves out the stock level of some products a store sells, those count as in stock when the store prices them.
+func ProductOfferInStock(offer data.CacheProductOffer) bool {
+	switch offer.StockLevel {
+	case kroger.StockLevelHigh, kroger.StockLevelLow:
+		return true
+	case "":
+		return offer.Price > 0
+	default:
+		return false
+	}
+}
+
+// alternativeQuantity is the ingredient's quantity as a percentage of the product's package.
+// Recipe amounts are converted to the product's size, other quantities are kept as a fraction of whichever package is bought.
+func alternativeQuantity(ingredient data.Ingredient, product data.CacheProduct) int {
+	if product.ProductID == ingredient.ProductID || ingredient.Staple || ingredient.Unit == "" {
+		return ingredient.Quantity
+	}
+	fraction, ok := parser.PackageFraction(parser.Measure{Amount: ingredient.Amount, Unit: ingredient.Unit}, parser.ParseSize(product.Size))
+	if !ok {
+		return ingredient.Quantity
+	}
+	return max(int(math.Round(fraction*100)), 1)
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/kroger"
+)
+
+func TestPickIngredientProduct(t *testing.T) {
+	ingredient := data.Ingredient{ProductID: "0001", Quantity: 50, Amount: 1, Unit: "cup", Alternatives: []string{"0002", "0003"}}
+	products := map[string]data.CacheProduct{
+		"0001": {ProductID: "0001", Size: "16 fl oz"},
+		"0002": {ProductID: "0002", Size: "32 fl oz"},
+		"0003": {ProductID: "0003", Size: "8 fl oz"},
+	}
+
+	tests := []struct {
+		name              string
+		offers            map[string]data.CacheProductOffer
+		alternativeChoice string
+		wantProductID     string
+		wantQuantity      int
+	}{
+		{
+			name: "first in stock",
+			offers: map[string]data.CacheProductOffer{
+				"0001": {ProductID: "0001", Price: 3, StockLevel: kroger.StockLevelTemporarilyOutOfStock},
+				"0002": {ProductID: "0002", Price: 5, StockLevel: kroger.StockLevelLow},
+				"0003": {ProductID: "0003", Price: 1, StockLevel: kroger.StockLevelHigh},
+			},
+			alternativeChoice: data.AlternativeChoiceInStock,
+			wantProductID:     "0002",
+			wantQuantity:      25,
+		},
+		{
+			name: "cheapest for the quantity",
+			offers: map[string]data.CacheProductOffer{
+				"0001": {ProductID: "0001", Price: 3, StockLevel: kroger.StockLevelHigh},
+				"0002": {ProductID: "0002", Price: 5, StockLevel: kroger.StockLevelHigh},
+				"0003": {ProductID: "0003", Price: 4, StockLevel: kroger.StockLevelHigh},
+			},
+			alternativeChoice: data.AlternativeChoiceCheapest,
+			wantProductID:     "0001",
+			wantQuantity:      50,
+		},
+		{
+			name: "priced without a stock level",
+			offers: map[string]data.CacheProductOffer{
+				"0001": {ProductID: "0001", StockLevel: kroger.StockLevelTemporarilyOutOfStock},
+				"0002": {ProductID: "0002"},
+				"0003": {ProductID: "0003", Price: 1},
+			},
+			alternativeChoice: data.AlternativeChoiceInStock,
+			wantProductID:     "0003",
+			wantQuantity:      100,
+		},
+		{
+			name:              "no store",
+			alternativeChoice: data.AlternativeChoiceInStock,
+			wantProductID:     "0001",
+			wantQuantity:      50,
+		},
+	}
+	for _, test := range tests {
+		pick := PickIngredientProduct(ingredient, products, test.offers, test.alternativeChoice)
+		if pick.ProductID != test.wantProductID || pick.Quantity != test.wantQuantity {
+			t.Errorf("%s: picked %s at %d%%, want %s at %d%%", test.name, pick.ProductID, pick.Quantity, test.wantProductID, test.wantQuantity)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package app
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"slices"
 
 	"github.com/densestvoid/krogerrecipeshopper/data"
@@ -23,6 +24,19 @@ func NewKrogerManager(productsClient *kroger.ProductsClient, locationsClient *kr
 	}
 }
 
+// NewClientKrogerManager creates a manager with a client credentials token, for looking up products and locations without an account
+func NewClientKrogerManager(ctx context.Context, authClient *kroger.AuthorizationClient, cache *data.Cache) (*KrogerManager, error) {
+	authResp, err := authClient.PostToken(ctx, kroger.ClientCredentials{
+		Scope: kroger.ScopeProductCompact,
+	})
+	if err != nil {
+		return nil, err
+	}
+	productsClient := kroger.NewProductsClient(http.DefaultClient, kroger.PublicEnvironment, authResp.AccessToken)
+	locationsClient := kroger.NewLocationsClient(http.DefaultClient, kroger.PublicEnvironment, authResp.AccessToken)
+	return NewKrogerManager(productsClient, locationsClient, cache), nil
+}
+
 const MaxProductIds = 50
 
 func (m *KrogerManager) GetProducts(ctx context.Context, locationID *string, productIDs ...string) (map[string]data.CacheProduct, error) {
@@ -64,6 +78,42 @@ func (m *KrogerManager) GetProducts(ctx context.Context, locationID *string, pro
 	return productsByID, nil
 }
 
+// GetProductOffers returns the products' prices and stock levels at the location, products the location doesn't sell are left out
+func (m *KrogerManager) GetProductOffers(ctx context.Context, locationID string, productIDs ...string) (map[string]data.CacheProductOffer, error) {
+	cachedOffers, productIDMisses, err := m.cache.RetrieveKrogerProductOffers(ctx, locationID, productIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	var clientOffers []data.CacheProductOffer
+	for productIDMissesChunk := range slices.Chunk(productIDMisses, MaxProductIds) {
+		productsResp, err := m.productsClient.GetProducts(ctx, kroger.GetProductsRequest{
+			Filters: &kroger.GetProductsByIDsFilter{
+				ProductIDs: productIDMissesChunk,
+			},
+			LocationID: &locationID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		var chunkOffers []data.CacheProductOffer
+		for _, product := range productsResp.Products {
+			chunkOffers = append(chunkOffers, KrogerProductToCacheProductOffer(product))
+		}
+
+		if err := m.cache.StoreKrogerProductOffers(ctx, locationID, chunkOffers...); err != nil {
+			return nil, err
+		}
+		clientOffers = append(clientOffers, chunkOffers...)
+	}
+
+	var offersByID = map[string]data.CacheProductOffer{}
+	for _, offer := range slices.Concat(cachedOffers, clientOffers) {
+		offersByID[offer.ProductID] = offer
+	}
+	return offersByID, nil
+}
+
 func (m *KrogerManager) GetLocation(ctx context.Context, locationID string) (data.CacheLocation, error) {
 	// Get products from cache
 	cachedLocation, err := m.cache.RetrieveKrogerLocation(ctx, locationID)
@@ -126,3 +176,17 @@ func KrogerProductToCacheProduct(product kroger.Product) data.CacheProduct {
 		Location:    location,
 	}
 }
+
+// KrogerProductToCacheProductOffer takes the price and stock level of a product requested for a location
+func KrogerProductToCacheProductOffer(product kroger.Product) data.CacheProductOffer {
+	offer := data.CacheProductOffer{ProductID: product.ProductID}
+	for _, item := range product.Items {
+		offer.StockLevel = item.Inventory.StockLevel
+		offer.Price = float64(item.Price.Regular)
+		if item.Price.Promo > 0 {
+			offer.Price = float64(item.Price.Promo)
+		}
+		break
+	}
+	return offer
+}
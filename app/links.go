@@ -0,0 +1,12 @@
+package app
+
+import "net/url"
+
+// WebURL reports whether the URL is an absolute http or https URL, the only links shown to other users
+func WebURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
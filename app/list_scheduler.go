@@ -0,0 +1,175 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/kroger"
+)
+
+// NextListScheduleRun returns the first run of the schedule after the given time, runs happen at the start of the day
+func NextListScheduleRun(schedule data.ListSchedule, after time.Time) (time.Time, error) {
+	day := time.Date(after.Year(), after.Month(), after.Day(), 0, 0, 0, 0, after.Location())
+	switch schedule.Frequency {
+	case data.ListScheduleFrequencyWeekly:
+		if schedule.Weekday == nil || *schedule.Weekday < 0 || *schedule.Weekday > 6 {
+			return time.Time{}, errors.New("weekly schedules need a weekday")
+		}
+		next := day.AddDate(0, 0, 1)
+		for next.Weekday() != time.Weekday(*schedule.Weekday) {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next, nil
+	case data.ListScheduleFrequencyDays:
+		if schedule.IntervalDays == nil || *schedule.IntervalDays <= 0 {
+			return time.Time{}, errors.New("daily schedules need a positive interval")
+		}
+		return day.AddDate(0, 0, *schedule.IntervalDays), nil
+	case data.ListScheduleFrequencyMonthly:
+		if schedule.DayOfMonth == nil || *schedule.DayOfMonth < 1 || *schedule.DayOfMonth > 28 {
+			return time.Time{}, errors.New("monthly schedules need a day between 1 and 28")
+		}
+		next := time.Date(after.Year(), after.Month(), *schedule.DayOfMonth, 0, 0, 0, 0, after.Location())
+		if !next.After(after) {
+			next = next.AddDate(0, 1, 0)
+		}
+		return next, nil
+	default:
+		return time.Time{}, fmt.Errorf("unknown schedule frequency %q", schedule.Frequency)
+	}
+}
+
+// ListScheduler adds the ingredients of scheduled lists to their owner's cart
+type ListScheduler struct {
+	logger     *slog.Logger
+	repo       *data.Repository
+	cache      *data.Cache
+	authClient *kroger.AuthorizationClient
+	vault      *data.KrogerTokenVault
+}
+
+// NewListScheduler can be given a nil vault, schedules that push to Kroger will then fail
+func NewListScheduler(logger *slog.Logger, repo *data.Repository, cache *data.Cache, authClient *kroger.AuthorizationClient, vault *data.KrogerTokenVault) *ListScheduler {
+	return &ListScheduler{
+		logger:     logger,
+		repo:       repo,
+		cache:      cache,
+		authClient: authClient,
+		vault:      vault,
+	}
+}
+
+// RunDue runs every schedule that is due, each run is recorded in the list's run history
+func (s *ListScheduler) RunDue(ctx context.Context) error {
+	now := time.Now()
+	schedules, err := s.repo.ListDueListSchedules(ctx, now)
+	if err != nil {
+		return fmt.Errorf("listing due list schedules: %w", err)
+	}
+
+	for _, schedule := range schedules {
+		nextRunAt, err := NextListScheduleRun(schedule, now)
+		if err != nil {
+			s.logger.Error("computing next list schedule run", slog.String("listID", schedule.ListID.String()), slog.String("error", err.Error()))
+			continue
+		}
+		claimed, err := s.repo.ClaimListScheduleRun(ctx, schedule.ListID, schedule.NextRunAt, nextRunAt)
+		if err != nil {
+			return fmt.Errorf("claiming list schedule run: %w", err)
+		} else if !claimed {
+			continue
+		}
+
+		status, runErr := s.run(ctx, schedule)
+		if runErr != nil {
+			s.logger.Error("list schedule run failed", slog.String("listID", schedule.ListID.String()), slog.String("error", runErr.Error()))
+		} else {
+			s.logger.Info("list schedule run", slog.String("listID", schedule.ListID.String()), slog.String("status", status))
+		}
+		if err := s.repo.CreateListScheduleRun(ctx, schedule.ListID, status, runErr); err != nil {
+			return fmt.Errorf("recording list schedule run: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *ListScheduler) run(ctx context.Context, schedule data.ListSchedule) (string, error) {
+	if schedule.SkipNext {
+		return data.ListScheduleRunStatusSkipped, nil
+	}
+
+	list, err := s.repo.GetList(ctx, schedule.ListID)
+	if err != nil {
+		return data.ListScheduleRunStatusFailed, fmt.Errorf("getting list: %w", err)
+	}
+	ingredients, err := s.repo.ListIngredients(ctx, schedule.ListID)
+	if err != nil {
+		return data.ListScheduleRunStatusFailed, fmt.Errorf("listing ingredients: %w", err)
+	}
+	picks, err := s.pickIngredientProducts(ctx, list.AccountID, ingredients)
+	if err != nil {
+		return data.ListScheduleRunStatusFailed, fmt.Errorf("picking ingredient products: %w", err)
+	}
+
+	if !schedule.PushToKroger {
+		for _, pick := range picks {
+			if err := s.repo.AddCartProduct(ctx, list.AccountID, pick.ProductID, pick.Quantity, pick.Staple, pick.Alternatives); err != nil {
+				return data.ListScheduleRunStatusFailed, fmt.Errorf("adding cart product: %w", err)
+			}
+		}
+		return data.ListScheduleRunStatusAdded, nil
+	}
+
+	// Pushing skips the app cart and goes straight to Kroger, staples are left out the same as at checkout
+	if s.vault == nil {
+		return data.ListScheduleRunStatusFailed, errors.New("pushing to kroger requires a token vault")
+	}
+	tokenSource, err := NewAccountTokenSource(ctx, s.authClient, s.vault, list.AccountID)
+	if err != nil {
+		return data.ListScheduleRunStatusFailed, err
+	}
+	token, err := tokenSource.Token(ctx)
+	if err != nil {
+		return data.ListScheduleRunStatusFailed, fmt.Errorf("getting kroger token: %w", err)
+	}
+
+	var addProducts []kroger.PutAddProduct
+	for _, pick := range picks {
+		if pick.Staple {
+			continue
+		}
+		addProducts = append(addProducts, kroger.PutAddProduct{
+			ProductID: pick.ProductID,
+			Quantity:  int(math.Ceil(float64(pick.Quantity) / 100)),
+			Modality:  kroger.ModalityPickup,
+		})
+	}
+	if len(addProducts) == 0 {
+		return data.ListScheduleRunStatusPushed, nil
+	}
+
+	cartClient := kroger.NewCartClient(http.DefaultClient, kroger.PublicEnvironment, token.AccessToken)
+	if err := cartClient.PutAdd(ctx, kroger.PutAddRequest{Items: addProducts}); err != nil {
+		return data.ListScheduleRunStatusFailed, fmt.Errorf("adding products to kroger cart: %w", err)
+	}
+	return data.ListScheduleRunStatusPushed, nil
+}
+
+// pickIngredientProducts picks the products bought for the ingredients at the account's store
+func (s *ListScheduler) pickIngredientProducts(ctx context.Context, accountID uuid.UUID, ingredients []data.Ingredient) ([]IngredientPick, error) {
+	account, err := s.repo.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("getting account: %w", err)
+	}
+	return PickIngredientProducts(ctx, account, ingredients, func(ctx context.Context) (*KrogerManager, error) {
+		return NewClientKrogerManager(ctx, s.authClient, s.cache)
+	})
+}
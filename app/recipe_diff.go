@@ -0,0 +1,104 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+)
+
+type RecipeFieldChange struct {
+	Field string
+	From  string
+	To    string
+}
+
+// RecipeIngredientChange has a nil From for added ingredients and a nil To for removed ones
+type RecipeIngredientChange struct {
+	ProductID string
+	From      *data.RecipeSnapshotIngredient
+	To        *data.RecipeSnapshotIngredient
+}
+
+func (c RecipeIngredientChange) Added() bool   { return c.From == nil }
+func (c RecipeIngredientChange) Removed() bool { return c.To == nil }
+
+type RecipeDiff struct {
+	Fields      []RecipeFieldChange
+	Ingredients []RecipeIngredientChange
+}
+
+func (d RecipeDiff) Empty() bool {
+	return len(d.Fields) == 0 && len(d.Ingredients) == 0
+}
+
+// DiffRecipeSnapshots returns the changes that turn one snapshot into the other
+func DiffRecipeSnapshots(from, to data.RecipeSnapshot) RecipeDiff {
+	// Text instructions are the text of their steps, so the steps are compared instead when both have them
+	instructions := RecipeFieldChange{"Instructions", from.Instructions, to.Instructions}
+	if from.InstructionType == data.InstructionTypeText && to.InstructionType == data.InstructionTypeText && from.Steps != nil && to.Steps != nil {
+		instructions = RecipeFieldChange{"Steps", formatRecipeSteps(from.Steps), formatRecipeSteps(to.Steps)}
+	}
+
+	var diff RecipeDiff
+	for _, field := range []RecipeFieldChange{
+		{"Name", from.Name, to.Name},
+		{"Description", from.Description, to.Description},
+		{"Instruction type", from.InstructionType, to.InstructionType},
+		instructions,
+		{"Visibility", from.Visibility, to.Visibility},
+	} {
+		if field.From != field.To {
+			diff.Fields = append(diff.Fields, field)
+		}
+	}
+
+	for _, productID := range data.RecipeSnapshotProductIDs(from, to) {
+		fromIngredient := snapshotIngredient(from, productID)
+		toIngredient := snapshotIngredient(to, productID)
+		if fromIngredient != nil && toIngredient != nil && fromIngredient.Equal(*toIngredient) {
+			continue
+		}
+		diff.Ingredients = append(diff.Ingredients, RecipeIngredientChange{
+			ProductID: productID,
+			From:      fromIngredient,
+			To:        toIngredient,
+		})
+	}
+	return diff
+}
+
+func snapshotIngredient(snapshot data.RecipeSnapshot, productID string) *data.RecipeSnapshotIngredient {
+	i := slices.IndexFunc(snapshot.Ingredients, func(ingredient data.RecipeSnapshotIngredient) bool {
+		return ingredient.ProductID == productID
+	})
+	if i < 0 {
+		return nil
+	}
+	return &snapshot.Ingredients[i]
+}
+
+// formatRecipeSteps writes a numbered line for each step with its timer and ingredients
+func formatRecipeSteps(steps []data.RecipeStep) string {
+	lines := []string{}
+	for _, step := range steps {
+		line := fmt.Sprintf("%d. %s", step.Number, step.Text)
+		if step.TimerSeconds > 0 {
+			line += fmt.Sprintf(" [timer %s]", FormatTimer(step.TimerSeconds))
+		}
+		if len(step.ProductIDs) > 0 {
+			line += fmt.Sprintf(" [uses %s]", strings.Join(step.ProductIDs, ", "))
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FormatTimer formats seconds as minutes and seconds, with hours when needed
+func FormatTimer(seconds int) string {
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
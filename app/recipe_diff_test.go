@@ -0,0 +1,115 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+)
+
+func TestDiffRecipeSnapshots(t *testing.T) {
+	from := data.RecipeSnapshot{
+		Name:            "Chili",
+		Description:     "Weeknight chili",
+		InstructionType: data.InstructionTypeText,
+		Instructions:    "Brown the beef\nSimmer",
+		Visibility:      data.VisibilityPrivate,
+		Ingredients: []data.RecipeSnapshotIngredient{
+			{ProductID: "0001", Quantity: 100},
+			{ProductID: "0002", Quantity: 50},
+			{ProductID: "0003", Quantity: 100, Staple: true},
+		},
+		Steps: []data.RecipeStep{
+			{Number: 1, Text: "Brown the beef", ProductIDs: []string{"0001"}},
+			{Number: 2, Text: "Simmer", TimerSeconds: 1800},
+		},
+	}
+
+	t.Run("same snapshot", func(t *testing.T) {
+		if diff := DiffRecipeSnapshots(from, from); !diff.Empty() {
+			t.Errorf("diff of a snapshot with itself = %+v, want empty", diff)
+		}
+	})
+
+	t.Run("fields", func(t *testing.T) {
+		to := from
+		to.Name = "Texas chili"
+		to.Visibility = data.VisibilityPublic
+
+		diff := DiffRecipeSnapshots(from, to)
+		want := []RecipeFieldChange{
+			{"Name", "Chili", "Texas chili"},
+			{"Visibility", data.VisibilityPrivate, data.VisibilityPublic},
+		}
+		if len(diff.Fields) != len(want) {
+			t.Fatalf("field changes = %+v, want %+v", diff.Fields, want)
+		}
+		for i := range want {
+			if diff.Fields[i] != want[i] {
+				t.Errorf("field change %d = %+v, want %+v", i, diff.Fields[i], want[i])
+			}
+		}
+		if len(diff.Ingredients) != 0 {
+			t.Errorf("ingredient changes = %+v, want none", diff.Ingredients)
+		}
+	})
+
+	t.Run("steps replace instructions", func(t *testing.T) {
+		to := from
+		to.Steps = []data.RecipeStep{
+			{Number: 1, Text: "Brown the beef", ProductIDs: []string{"0001"}},
+			{Number: 2, Text: "Simmer", TimerSeconds: 3600},
+		}
+
+		diff := DiffRecipeSnapshots(from, to)
+		if len(diff.Fields) != 1 || diff.Fields[0].Field != "Steps" {
+			t.Fatalf("field changes = %+v, want only the steps", diff.Fields)
+		}
+		if want := "1. Brown the beef [uses 0001]\n2. Simmer [timer 1:00:00]"; diff.Fields[0].To != want {
+			t.Errorf("steps = %q, want %q", diff.Fields[0].To, want)
+		}
+	})
+
+	t.Run("ingredients", func(t *testing.T) {
+		to := from
+		to.Ingredients = []data.RecipeSnapshotIngredient{
+			{ProductID: "0001", Quantity: 100},
+			{ProductID: "0002", Quantity: 75},
+			{ProductID: "0004", Quantity: 100},
+		}
+
+		diff := DiffRecipeSnapshots(from, to)
+		if len(diff.Fields) != 0 {
+			t.Errorf("field changes = %+v, want none", diff.Fields)
+		}
+		changes := map[string]RecipeIngredientChange{}
+		for _, change := range diff.Ingredients {
+			changes[change.ProductID] = change
+		}
+		if len(changes) != 3 {
+			t.Fatalf("ingredient changes = %+v, want 3", diff.Ingredients)
+		}
+		if change := changes["0002"]; change.Added() || change.Removed() || change.From.Quantity != 50 || change.To.Quantity != 75 {
+			t.Errorf("change of 0002 = %+v, want quantity 50 to 75", change)
+		}
+		if change := changes["0003"]; !change.Removed() {
+			t.Errorf("change of 0003 = %+v, want removed", change)
+		}
+		if change := changes["0004"]; !change.Added() {
+			t.Errorf("change of 0004 = %+v, want added", change)
+		}
+	})
+
+	t.Run("alternatives", func(t *testing.T) {
+		to := from
+		to.Ingredients = []data.RecipeSnapshotIngredient{
+			{ProductID: "0001", Quantity: 100, Alternatives: []string{"0005"}},
+			{ProductID: "0002", Quantity: 50},
+			{ProductID: "0003", Quantity: 100, Staple: true},
+		}
+
+		diff := DiffRecipeSnapshots(from, to)
+		if len(diff.Ingredients) != 1 || diff.Ingredients[0].ProductID != "0001" {
+			t.Errorf("ingredient changes = %+v, want only 0001", diff.Ingredients)
+		}
+	})
+}
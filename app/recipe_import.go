@@ -0,0 +1,200 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ImportedRecipe is a schema.org Recipe pulled out of a page's JSON-LD
+type ImportedRecipe struct {
+	Name         string
+	Description  string
+	Yield        string
+	Instructions []string
+	Ingredients  []string // the recipeIngredient lines, still to be matched to products
+}
+
+var ErrNoRecipeJSONLD = errors.New("no schema.org Recipe JSON-LD found")
+
+var (
+	htmlTagPattern      = regexp.MustCompile(`<[^>]*>`)
+	htmlBreakTagPattern = regexp.MustCompile(`(?i)<\s*(br|/p|/li|/div)\b[^>]*>`)
+	whitespacePattern   = regexp.MustCompile(`\s+`)
+)
+
+// ParseRecipeJSONLD finds the first schema.org Recipe in the page's JSON-LD scripts.
+// Scripts that aren't valid JSON are skipped, since pages often have broken ones unrelated to the recipe.
+func ParseRecipeJSONLD(page io.Reader) (ImportedRecipe, error) {
+	tokenizer := xhtml.NewTokenizer(page)
+	inJSONLD := false
+	for {
+		switch tokenizer.Next() {
+		case xhtml.ErrorToken:
+			if err := tokenizer.Err(); !errors.Is(err, io.EOF) {
+				return ImportedRecipe{}, fmt.Errorf("reading html: %w", err)
+			}
+			return ImportedRecipe{}, ErrNoRecipeJSONLD
+		case xhtml.StartTagToken:
+			token := tokenizer.Token()
+			inJSONLD = false
+			if token.DataAtom != atom.Script {
+				continue
+			}
+			for _, attr := range token.Attr {
+				if attr.Key == "type" && strings.EqualFold(strings.TrimSpace(attr.Val), "application/ld+json") {
+					inJSONLD = true
+				}
+			}
+		case xhtml.TextToken:
+			if !inJSONLD {
+				continue
+			}
+			inJSONLD = false
+
+			var value any
+			if err := json.Unmarshal(tokenizer.Text(), &value); err != nil {
+				continue
+			}
+			if recipe, ok := findJSONLDRecipe(value); ok {
+				return importJSONLDRecipe(recipe)
+			}
+		default:
+			inJSONLD = false
+		}
+	}
+}
+
+// findJSONLDRecipe searches arrays, @graph and mainEntity for an object typed as a Recipe
+func findJSONLDRecipe(value any) (map[string]any, bool) {
+	switch value := value.(type) {
+	case []any:
+		for _, item := range value {
+			if recipe, ok := findJSONLDRecipe(item); ok {
+				return recipe, true
+			}
+		}
+	case map[string]any:
+		if jsonLDIsType(value["@type"], "Recipe") {
+			return value, true
+		}
+		for _, key := range []string{"@graph", "mainEntity"} {
+			if recipe, ok := findJSONLDRecipe(value[key]); ok {
+				return recipe, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// jsonLDIsType handles both a single type and a list of types
+func jsonLDIsType(value any, schemaType string) bool {
+	for _, t := range jsonLDStrings(value) {
+		if t == schemaType || t == "http://schema.org/"+schemaType || t == "https://schema.org/"+schemaType {
+			return true
+		}
+	}
+	return false
+}
+
+func importJSONLDRecipe(recipe map[string]any) (ImportedRecipe, error) {
+	imported := ImportedRecipe{
+		Name:         jsonLDText(recipe["name"]),
+		Description:  jsonLDText(recipe["description"]),
+		Instructions: jsonLDInstructions(recipe["recipeInstructions"]),
+	}
+	if imported.Name == "" {
+		return ImportedRecipe{}, fmt.Errorf("recipe has no name")
+	}
+
+	// Yields are often listed both as a number and as a phrase, the phrase says more
+	for _, yield := range jsonLDStrings(recipe["recipeYield"]) {
+		if yield = cleanJSONLDText(yield); len(yield) > len(imported.Yield) {
+			imported.Yield = yield
+		}
+	}
+
+	// Older pages use the ingredients property
+	ingredients := recipe["recipeIngredient"]
+	if ingredients == nil {
+		ingredients = recipe["ingredients"]
+	}
+	for _, ingredient := range jsonLDStrings(ingredients) {
+		if ingredient = cleanJSONLDText(ingredient); ingredient != "" {
+			imported.Ingredients = append(imported.Ingredients, ingredient)
+		}
+	}
+	return imported, nil
+}
+
+// jsonLDInstructions flattens the instruction text, HowToStep and HowToSection forms into a list of steps
+func jsonLDInstructions(value any) []string {
+	switch value := value.(type) {
+	case string:
+		// Plain text instructions have a step per line or paragraph
+		var steps []string
+		for line := range strings.Lines(htmlBreakTagPattern.ReplaceAllString(value, "\n")) {
+			if line = cleanJSONLDText(line); line != "" {
+				steps = append(steps, line)
+			}
+		}
+		return steps
+	case []any:
+		var steps []string
+		for _, item := range value {
+			steps = append(steps, jsonLDInstructions(item)...)
+		}
+		return steps
+	case map[string]any:
+		if jsonLDIsType(value["@type"], "HowToSection") {
+			return jsonLDInstructions(value["itemListElement"])
+		}
+		text := jsonLDText(value["text"])
+		if text == "" {
+			text = jsonLDText(value["name"])
+		}
+		if text == "" {
+			return nil
+		}
+		return []string{text}
+	}
+	return nil
+}
+
+// jsonLDStrings is the strings and numbers of a value that's either one of them or a list of them
+func jsonLDStrings(value any) []string {
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case float64:
+		return []string{fmt.Sprint(value)}
+	case []any:
+		var strs []string
+		for _, item := range value {
+			strs = append(strs, jsonLDStrings(item)...)
+		}
+		return strs
+	}
+	return nil
+}
+
+func jsonLDText(value any) string {
+	strs := jsonLDStrings(value)
+	if len(strs) == 0 {
+		return ""
+	}
+	return cleanJSONLDText(strs[0])
+}
+
+// cleanJSONLDText removes the html tags and entities pages leave in their JSON-LD text
+func cleanJSONLDText(text string) string {
+	text = html.UnescapeString(htmlTagPattern.ReplaceAllString(text, " "))
+	return strings.TrimSpace(whitespacePattern.ReplaceAllString(text, " "))
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseRecipeJSONLD(t *testing.T) {
+	tests := []struct {
+		name    string
+		page    string
+		want    ImportedRecipe
+		wantErr error
+	}{
+		{
+			name: "recipe",
+			page: `<html><head><script type="application/ld+json">{
+				"@context": "https://schema.org",
+				"@type": "Recipe",
+				"name": "Pancakes &amp; Syrup",
+				"description": "<p>Fluffy   pancakes</p>",
+				"recipeYield": ["8", "8 pancakes"],
+				"recipeIngredient": ["2 cups flour", " ", "1 <b>egg</b>"],
+				"recipeInstructions": [
+					{"@type": "HowToStep", "text": "Mix"},
+					{"@type": "HowToSection", "name": "Cook", "itemListElement": [
+						{"@type": "HowToStep", "text": "Pour"},
+						{"@type": "HowToStep", "name": "Flip"}
+					]}
+				]
+			}</script></head></html>`,
+			want: ImportedRecipe{
+				Name:         "Pancakes & Syrup",
+				Description:  "Fluffy pancakes",
+				Yield:        "8 pancakes",
+				Ingredients:  []string{"2 cups flour", "1 egg"},
+				Instructions: []string{"Mix", "Pour", "Flip"},
+			},
+		},
+		{
+			name: "graph with a type list and text instructions",
+			page: `<script type="application/ld+json">{"@graph": [
+				{"@type": "WebPage", "name": "Soup page"},
+				{"@type": ["Recipe", "NewsArticle"], "name": "Soup", "ingredients": ["1 onion"],
+				 "recipeYield": 4, "recipeInstructions": "Chop<br>Simmer\n\nServe"}
+			]}</script>`,
+			want: ImportedRecipe{
+				Name:         "Soup",
+				Yield:        "4",
+				Ingredients:  []string{"1 onion"},
+				Instructions: []string{"Chop", "Simmer", "Serve"},
+			},
+		},
+		{
+			name: "broken script before the recipe",
+			page: `<script type="application/ld+json">{broken</script>
+				<script type="application/ld+json">{"@type": "http://schema.org/Recipe", "name": "Toast"}</script>`,
+			want: ImportedRecipe{Name: "Toast"},
+		},
+		{
+			name:    "no recipe",
+			page:    `<script type="application/ld+json">{"@type": "Organization", "name": "Kitchen"}</script><script>{"@type": "Recipe"}</script>`,
+			wantErr: ErrNoRecipeJSONLD,
+		},
+	}
+	for _, test := range tests {
+		got, err := ParseRecipeJSONLD(strings.NewReader(test.page))
+		if !errors.Is(err, test.wantErr) {
+			t.Errorf("%s: error = %v, want %v", test.name, err, test.wantErr)
+			continue
+		}
+		if got.Name != test.want.Name || got.Description != test.want.Description || got.Yield != test.want.Yield ||
+			!slices.Equal(got.Ingredients, test.want.Ingredients) || !slices.Equal(got.Instructions, test.want.Instructions) {
+			t.Errorf("%s: ParseRecipeJSONLD() = %+v, want %+v", test.name, got, test.want)
+		}
+	}
+
+	if _, err := ParseRecipeJSONLD(strings.NewReader(`<script type="application/ld+json">{"@type": "Recipe"}</script>`)); err == nil {
+		t.Error("ParseRecipeJSONLD() of a recipe without a name succeeded, want an error")
+	}
+}
@@ -0,0 +1,200 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/storage"
+)
+
+const (
+	RecipePhotoMaxBytes = 10 << 20
+	// recipePhotoMaxPixels keeps small files with huge dimensions from being decoded
+	recipePhotoMaxPixels = 50_000_000
+)
+
+var RecipePhotoContentTypes = []string{"image/jpeg", "image/png", "image/webp"}
+
+// RecipePhotoWidths are the widths of a recipe photo's variant for each image size, close to Kroger's product images
+var RecipePhotoWidths = map[string]int{
+	data.ImageSizeThumbnail:  100,
+	data.ImageSizeSmall:      200,
+	data.ImageSizeMedium:     500,
+	data.ImageSizeLarge:      1000,
+	data.ImageSizeExtraLarge: 1500,
+}
+
+// RecipePhotoKey is the storage key of a recipe photo's variant, photos get a new ID on every upload
+func RecipePhotoKey(photoID uuid.UUID, imageSize string) string {
+	return fmt.Sprintf("recipes/%s-%s.jpg", photoID, imageSize)
+}
+
+// DeleteRecipePhoto removes every variant of a photo, trying them all so as few files as possible are left behind
+func DeleteRecipePhoto(ctx context.Context, photos storage.Storage, photoID uuid.UUID) error {
+	var errs []error
+	for imageSize := range RecipePhotoWidths {
+		if err := photos.Delete(ctx, RecipePhotoKey(photoID, imageSize)); err != nil {
+			errs = append(errs, fmt.Errorf("deleting %s photo: %w", imageSize, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ResizeRecipePhoto checks an uploaded photo and encodes a JPEG variant of it for each image size.
+// Photos are turned upright from their EXIF orientation and never scaled up.
+func ResizeRecipePhoto(photo []byte) (map[string][]byte, error) {
+	if len(photo) > RecipePhotoMaxBytes {
+		return nil, fmt.Errorf("photo is larger than %d MB", RecipePhotoMaxBytes>>20)
+	}
+	if contentType := http.DetectContentType(photo); !slices.Contains(RecipePhotoContentTypes, contentType) {
+		return nil, fmt.Errorf("unsupported photo type %q", contentType)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(photo))
+	if err != nil {
+		return nil, fmt.Errorf("decoding photo: %w", err)
+	} else if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > recipePhotoMaxPixels {
+		return nil, fmt.Errorf("photo is %dx%d, it can't be more than %d pixels", config.Width, config.Height, recipePhotoMaxPixels)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(photo))
+	if err != nil {
+		return nil, fmt.Errorf("decoding photo: %w", err)
+	}
+
+	// Variants are sized by the upright photo, and turned upright after scaling since they're smaller
+	orientation := jpegOrientation(photo)
+	bounds := src.Bounds()
+	uprightWidth, uprightHeight := bounds.Dx(), bounds.Dy()
+	if orientation >= 5 {
+		uprightWidth, uprightHeight = uprightHeight, uprightWidth
+	}
+
+	variants := map[string][]byte{}
+	for imageSize, width := range RecipePhotoWidths {
+		width = min(width, uprightWidth)
+		height := max(1, uprightHeight*width/uprightWidth)
+		if orientation >= 5 {
+			width, height = height, width
+		}
+
+		// JPEGs have no transparency, so transparent photos are put on white
+		dst := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, orientImage(dst, orientation), &jpeg.Options{Quality: 85}); err != nil {
+			return nil, fmt.Errorf("encoding %s photo: %w", imageSize, err)
+		}
+		variants[imageSize] = buf.Bytes()
+	}
+	return variants, nil
+}
+
+// jpegOrientation reads the EXIF orientation of a JPEG, 1 (upright) if it has none
+func jpegOrientation(photo []byte) int {
+	if len(photo) < 2 || photo[0] != 0xFF || photo[1] != 0xD8 {
+		return 1
+	}
+	// The EXIF data is in an APP1 segment before the image data
+	for i := 2; i+4 <= len(photo) && photo[i] == 0xFF; {
+		marker := photo[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(photo[i+2:]))
+		if length < 2 || i+2+length > len(photo) {
+			return 1
+		}
+		if segment := photo[i+4 : i+2+length]; marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation finds the orientation tag in the first IFD of the EXIF TIFF data, 1 (upright) if it has none
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	for i := range int(order.Uint16(tiff[ifd:])) {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if orientation := int(order.Uint16(tiff[entry+8:])); orientation >= 1 && orientation <= 8 {
+				return orientation
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orientImage turns an image stored with an EXIF orientation upright, orientations 5 to 8 swap its width and height
+func orientImage(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := range dstHeight {
+		for x := range dstWidth {
+			var srcX, srcY int
+			switch orientation {
+			case 2: // mirrored
+				srcX, srcY = width-1-x, y
+			case 3: // rotated 180°
+				srcX, srcY = width-1-x, height-1-y
+			case 4: // mirrored vertically
+				srcX, srcY = x, height-1-y
+			case 5: // mirrored and rotated 90° counterclockwise
+				srcX, srcY = y, x
+			case 6: // rotated 90° counterclockwise
+				srcX, srcY = y, height-1-x
+			case 7: // mirrored and rotated 90° clockwise
+				srcX, srcY = width-1-y, height-1-x
+			case 8: // rotated 90° clockwise
+				srcX, srcY = width-1-y, x
+			}
+			dst.SetRGBA(x, y, src.RGBAAt(srcX, srcY))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,32 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/kroger"
+)
+
+// NewAccountTokenSource returns a token source for acting as the account outside of a request,
+// refreshed tokens are written back to the vault
+func NewAccountTokenSource(ctx context.Context, authClient *kroger.AuthorizationClient, vault *data.KrogerTokenVault, accountID uuid.UUID) (kroger.TokenSource, error) {
+	tokens, err := vault.Retrieve(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving kroger tokens: %w", err)
+	}
+
+	return kroger.NewRefreshTokenSource(authClient, kroger.Token{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		Expiry:       tokens.ExpiresAt,
+	}, func(ctx context.Context, token kroger.Token) error {
+		return vault.Store(ctx, accountID, data.KrogerTokens{
+			AccessToken:  token.AccessToken,
+			RefreshToken: token.RefreshToken,
+			ExpiresAt:    token.Expiry,
+		})
+	}), nil
+}
@@ -1,14 +1,31 @@
 package assets
 
 import (
-	_ "embed"
+	"crypto/sha256"
+	"crypto/sha512"
+	"embed"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
 )
 
 //go:embed package.json
 var packageJSON []byte
 
+// Populated by `task assets`, which copies the files below out of node_modules
+//
+//go:embed all:vendor
+var vendorFiles embed.FS
+
+// URL prefix the vendored frontend assets are served under
+const Prefix = "/assets/"
+
 type frontendDependencies struct {
 	Alpinejs        string `json:"alpinejs"`
 	Bootstrap       string `json:"bootstrap"`
@@ -19,6 +36,33 @@ type frontendDependencies struct {
 
 var frontend frontendDependencies
 
+// Asset is a frontend dependency ready to be referenced from a page
+type Asset struct {
+	URL       string
+	Integrity string
+}
+
+type vendoredAsset struct {
+	pkg     string
+	version string
+	file    string // path within the npm package
+	cdnURL  string
+
+	integrity string
+	hash      string
+}
+
+var (
+	bootstrapCSS      *vendoredAsset
+	bootstrapJS       *vendoredAsset
+	bootstrapIconsCSS *vendoredAsset
+	htmx              *vendoredAsset
+	htmxRemoveMe      *vendoredAsset
+	alpineJS          *vendoredAsset
+
+	useCDN bool
+)
+
 func init() {
 	var manifest struct {
 		Dependencies frontendDependencies `json:"dependencies"`
@@ -30,6 +74,19 @@ func init() {
 	if err := frontend.validate(); err != nil {
 		panic(fmt.Sprintf("assets: invalid package.json: %v", err))
 	}
+
+	bootstrapCSS = newVendoredAsset("bootstrap", frontend.Bootstrap, "dist/css/bootstrap.min.css",
+		fmt.Sprintf("https://cdn.jsdelivr.net/npm/bootstrap@%s/dist/css/bootstrap.min.css", frontend.Bootstrap))
+	bootstrapJS = newVendoredAsset("bootstrap", frontend.Bootstrap, "dist/js/bootstrap.bundle.min.js",
+		fmt.Sprintf("https://cdn.jsdelivr.net/npm/bootstrap@%s/dist/js/bootstrap.bundle.min.js", frontend.Bootstrap))
+	bootstrapIconsCSS = newVendoredAsset("bootstrap-icons", frontend.BootstrapIcons, "font/bootstrap-icons.min.css",
+		fmt.Sprintf("https://cdn.jsdelivr.net/npm/bootstrap-icons@%s/font/bootstrap-icons.min.css", frontend.BootstrapIcons))
+	htmx = newVendoredAsset("htmx.org", frontend.HTMXOrg, "dist/htmx.min.js",
+		fmt.Sprintf("https://unpkg.com/htmx.org@%s/dist/htmx.min.js", frontend.HTMXOrg))
+	htmxRemoveMe = newVendoredAsset("htmx-ext-remove-me", frontend.HTMXExtRemoveMe, "remove-me.js",
+		fmt.Sprintf("https://unpkg.com/htmx-ext-remove-me@%s/remove-me.js", frontend.HTMXExtRemoveMe))
+	alpineJS = newVendoredAsset("alpinejs", frontend.Alpinejs, "dist/cdn.min.js",
+		fmt.Sprintf("https://unpkg.com/alpinejs@%s/dist/cdn.min.js", frontend.Alpinejs))
 }
 
 func (d frontendDependencies) validate() error {
@@ -51,26 +108,120 @@ func (d frontendDependencies) validate() error {
 	return nil
 }
 
-func BootstrapCSS() string {
-	return fmt.Sprintf("https://cdn.jsdelivr.net/npm/bootstrap@%s/dist/css/bootstrap.min.css", frontend.Bootstrap)
+func newVendoredAsset(pkg, version, file, cdnURL string) *vendoredAsset {
+	asset := &vendoredAsset{
+		pkg:     pkg,
+		version: version,
+		file:    file,
+		cdnURL:  cdnURL,
+	}
+
+	// The vendored copy may be missing when the CDN is used, in which case no integrity can be provided
+	bs, err := vendorFiles.ReadFile(path.Join("vendor", pkg, file))
+	if err != nil {
+		return asset
+	}
+
+	sri := sha512.Sum384(bs)
+	asset.integrity = "sha384-" + base64.StdEncoding.EncodeToString(sri[:])
+
+	// The hash is part of the directory so relative references (icon fonts) stay versioned too
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s@%s:%x", pkg, version, sri))
+	asset.hash = hex.EncodeToString(sum[:])[:12]
+	return asset
+}
+
+func (a *vendoredAsset) vendored() bool {
+	return a.hash != ""
+}
+
+func (a *vendoredAsset) asset() Asset {
+	if useCDN || !a.vendored() {
+		return Asset{URL: a.cdnURL, Integrity: a.integrity}
+	}
+	return Asset{
+		URL:       fmt.Sprintf("%s%s/%s/%s", Prefix, a.pkg, a.hash, a.file),
+		Integrity: a.integrity,
+	}
+}
+
+func allAssets() []*vendoredAsset {
+	return []*vendoredAsset{bootstrapCSS, bootstrapJS, bootstrapIconsCSS, htmx, htmxRemoveMe, alpineJS}
+}
+
+// UseCDN switches between the embedded assets and the public CDNs.
+// Serving embedded assets requires `task assets` to have been run before building, builds without them fall back to the CDNs.
+func UseCDN(cdn bool) {
+	if !cdn {
+		var missing []string
+		for _, asset := range allAssets() {
+			if !asset.vendored() {
+				missing = append(missing, asset.pkg+"/"+asset.file)
+			}
+		}
+		if len(missing) > 0 {
+			slog.Warn("frontend assets not vendored, run `task assets` to embed them; loading them from the CDNs", "missing", missing)
+			cdn = true
+		}
+	}
+	useCDN = cdn
+}
+
+// Handler serves the vendored assets with immutable caching.
+// Paths are /assets/{package}/{hash}/{file}, the hash segment is dropped when reading the embedded files.
+func Handler() http.Handler {
+	// Any hash belonging to a package may be used to reach its files
+	hashes := map[string]map[string]bool{}
+	for _, asset := range allAssets() {
+		if !asset.vendored() {
+			continue
+		}
+		if hashes[asset.pkg] == nil {
+			hashes[asset.pkg] = map[string]bool{}
+		}
+		hashes[asset.pkg][asset.hash] = true
+	}
+
+	vendor, err := fs.Sub(vendorFiles, "vendor")
+	if err != nil {
+		panic(err)
+	}
+	fileServer := http.FileServer(http.FS(vendor))
+
+	return http.StripPrefix(strings.TrimSuffix(Prefix, "/"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 3)
+		if len(parts) != 3 || !hashes[parts[0]][parts[1]] || parts[2] == "" || strings.HasSuffix(parts[2], "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		r.URL.Path = "/" + path.Join(parts[0], parts[2])
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		fileServer.ServeHTTP(w, r)
+	}))
+}
+
+func BootstrapCSS() Asset {
+	return bootstrapCSS.asset()
 }
 
-func BootstrapJS() string {
-	return fmt.Sprintf("https://cdn.jsdelivr.net/npm/bootstrap@%s/dist/js/bootstrap.bundle.min.js", frontend.Bootstrap)
+func BootstrapJS() Asset {
+	return bootstrapJS.asset()
 }
 
-func BootstrapIconsCSS() string {
-	return fmt.Sprintf("https://cdn.jsdelivr.net/npm/bootstrap-icons@%s/font/bootstrap-icons.min.css", frontend.BootstrapIcons)
+func BootstrapIconsCSS() Asset {
+	return bootstrapIconsCSS.asset()
 }
 
-func HTMX() string {
-	return fmt.Sprintf("https://unpkg.com/htmx.org@%s", frontend.HTMXOrg)
+func HTMX() Asset {
+	return htmx.asset()
 }
 
-func HTMXRemoveMe() string {
-	return fmt.Sprintf("https://unpkg.com/htmx-ext-remove-me@%s/remove-me.js", frontend.HTMXExtRemoveMe)
+func HTMXRemoveMe() Asset {
+	return htmxRemoveMe.asset()
 }
 
-func AlpineJS() string {
-	return fmt.Sprintf("https://unpkg.com/alpinejs@%s/dist/cdn.min.js", frontend.Alpinejs)
+func AlpineJS() Asset {
+	return alpineJS.asset()
 }
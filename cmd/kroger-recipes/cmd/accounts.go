@@ -0,0 +1,199 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+
+	"github.com/densestvoid/krogerrecipeshopper/app"
+	"github.com/densestvoid/krogerrecipeshopper/data"
+)
+
+// accountsCmd represents the accounts command
+var accountsCmd = &cobra.Command{
+	Use:   "accounts",
+	Short: "manage the server accounts",
+}
+
+// accountsListCmd represents the accounts list command
+var accountsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list the accounts and their display names",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		accounts, err := openRepository().ListAccounts(context.Background())
+		if err != nil {
+			return fmt.Errorf("listing accounts: %w", err)
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "ID\tDISPLAY NAME\tKROGER PROFILE ID")
+		for _, account := range accounts {
+			displayName := ""
+			if account.DisplayName != nil {
+				displayName = *account.DisplayName
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\n", account.ID, displayName, account.KrogerProfileID)
+		}
+		return writer.Flush()
+	},
+}
+
+// accountsShowCmd represents the accounts show command
+var accountsShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "show an account's settings and profile",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		repo := openRepository()
+
+		accountID, err := accountFlag(ctx, cmd, repo)
+		if err != nil {
+			return err
+		}
+		account, err := repo.GetAccountByID(ctx, accountID)
+		if isNotFound(err) {
+			return fmt.Errorf("no account %s", accountID)
+		} else if err != nil {
+			return fmt.Errorf("getting account: %w", err)
+		}
+		profile, err := repo.GetProfileByAccountID(ctx, accountID)
+		if err != nil {
+			return fmt.Errorf("getting profile: %w", err)
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(writer, "ID\t%s\n", account.ID)
+		fmt.Fprintf(writer, "Kroger profile ID\t%s\n", account.KrogerProfileID)
+		if profile != nil {
+			fmt.Fprintf(writer, "Display name\t%s\n", profile.DisplayName)
+		} else {
+			fmt.Fprintln(writer, "Display name\t(no profile)")
+		}
+		fmt.Fprintf(writer, "Image size\t%s\n", account.ImageSize)
+		fmt.Fprintf(writer, "Homepage\t%s\n", account.Homepage)
+		if account.LocationID != nil {
+			fmt.Fprintf(writer, "Location ID\t%s\n", *account.LocationID)
+		} else {
+			fmt.Fprintln(writer, "Location ID\t(none)")
+		}
+		return writer.Flush()
+	},
+}
+
+// accountsDeleteCmd represents the accounts delete command
+var accountsDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "delete an account with all of its recipes, photos, lists and sessions",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		repo := openRepository()
+
+		accountID, err := accountFlag(ctx, cmd, repo)
+		if err != nil {
+			return err
+		}
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			return err
+		}
+		if !yes {
+			return fmt.Errorf("deleting account %s can't be undone, rerun with --yes to confirm", accountID)
+		}
+
+		if _, err := repo.GetAccountByID(ctx, accountID); isNotFound(err) {
+			return fmt.Errorf("no account %s", accountID)
+		} else if err != nil {
+			return fmt.Errorf("getting account: %w", err)
+		}
+		photoIDs, err := repo.DeleteAccount(ctx, accountID)
+		if err != nil {
+			return fmt.Errorf("deleting account: %w", err)
+		}
+
+		photos := openPhotos()
+		defer photos.Close()
+		for _, photoID := range photoIDs {
+			if err := app.DeleteRecipePhoto(ctx, photos, photoID); err != nil {
+				fmt.Fprintf(os.Stderr, "deleting recipe photo %s: %v\n", photoID, err)
+			}
+		}
+		fmt.Fprintf(os.Stdout, "deleted account %s\n", accountID)
+		return nil
+	},
+}
+
+// addAccountFlags adds the flags read by accountFlag
+func addAccountFlags(cmd *cobra.Command) {
+	cmd.Flags().String("account", "", "ID of the account")
+	cmd.Flags().String("profile", "", "display name of the account's profile")
+	cmd.MarkFlagsMutuallyExclusive("account", "profile")
+}
+
+// accountFlag returns the account selected by the --account or --profile flag.
+// Profile display names must match exactly, ignoring case.
+func accountFlag(ctx context.Context, cmd *cobra.Command, repo *data.Repository) (uuid.UUID, error) {
+	account, err := cmd.Flags().GetString("account")
+	if err != nil {
+		return uuid.Nil, err
+	}
+	profile, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	switch {
+	case account != "":
+		accountID, err := uuid.Parse(account)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("invalid account ID %q: %w", account, err)
+		}
+		return accountID, nil
+	case profile != "":
+		profiles, err := repo.ListProfiles(ctx, profile)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("listing profiles: %w", err)
+		}
+		var matches []data.Profile
+		for _, p := range profiles {
+			if strings.EqualFold(p.DisplayName, profile) {
+				matches = append(matches, p)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return uuid.Nil, fmt.Errorf("no profile named %q", profile)
+		case 1:
+			return matches[0].AccountID, nil
+		default:
+			return uuid.Nil, fmt.Errorf("%d profiles are named %q, select the account by ID instead", len(matches), profile)
+		}
+	default:
+		return uuid.Nil, errors.New("an --account or --profile is required")
+	}
+}
+
+// isNotFound reports whether the error is from a missing database row
+func isNotFound(err error) bool {
+	return errors.Is(err, sql.ErrNoRows)
+}
+
+func init() {
+	rootCmd.AddCommand(accountsCmd)
+	accountsCmd.AddCommand(accountsListCmd)
+	accountsCmd.AddCommand(accountsShowCmd)
+	accountsCmd.AddCommand(accountsDeleteCmd)
+
+	addAccountFlags(accountsShowCmd)
+	addAccountFlags(accountsDeleteCmd)
+	accountsDeleteCmd.Flags().Bool("yes", false, "confirm deleting the account")
+}
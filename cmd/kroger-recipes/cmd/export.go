@@ -0,0 +1,104 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "export recipes and lists as a bundle",
+	Long: `Export an account's recipes and lists, or only the given recipes, as a JSON bundle
+that can be imported with the import command or from the account page.`,
+	RunE: func(cmd *cobra.Command, args []string) (retErr error) {
+		ctx := context.Background()
+		repo := openRepository()
+
+		recipeIDs, err := cmd.Flags().GetStringSlice("recipe")
+		if err != nil {
+			return err
+		}
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+
+		var bundle data.Bundle
+		if len(recipeIDs) > 0 {
+			var recipes []data.Recipe
+			for _, recipeID := range recipeIDs {
+				listID, err := uuid.Parse(recipeID)
+				if err != nil {
+					return fmt.Errorf("invalid recipe ID %q: %w", recipeID, err)
+				}
+				recipe, err := repo.GetRecipe(ctx, listID, uuid.Nil)
+				if isNotFound(err) {
+					return fmt.Errorf("no recipe %s", listID)
+				} else if err != nil {
+					return fmt.Errorf("getting recipe %s: %w", listID, err)
+				}
+				recipes = append(recipes, recipe)
+			}
+			if bundle, err = repo.ExportBundle(ctx, recipes, nil); err != nil {
+				return fmt.Errorf("exporting recipes: %w", err)
+			}
+		} else {
+			accountID, err := accountFlag(ctx, cmd, repo)
+			if err != nil {
+				return err
+			}
+			if _, err := repo.GetAccountByID(ctx, accountID); isNotFound(err) {
+				return fmt.Errorf("no account %s", accountID)
+			} else if err != nil {
+				return fmt.Errorf("getting account: %w", err)
+			}
+			if bundle, err = repo.ExportAccountBundle(ctx, accountID); err != nil {
+				return fmt.Errorf("exporting account: %w", err)
+			}
+		}
+
+		var writer io.Writer = os.Stdout
+		if output != "" {
+			file, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := file.Close(); err != nil && retErr == nil {
+					retErr = err
+				}
+			}()
+			writer = file
+		}
+
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(bundle); err != nil {
+			return fmt.Errorf("writing bundle: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "exported %d recipes and %d lists\n", len(bundle.Recipes), len(bundle.Lists))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	addAccountFlags(exportCmd)
+	exportCmd.Flags().StringSlice("recipe", nil, "ID of a recipe to export instead of a whole account, can be repeated")
+	exportCmd.Flags().StringP("output", "o", "", "file to write the bundle to, defaults to stdout")
+	exportCmd.MarkFlagsMutuallyExclusive("recipe", "account")
+	exportCmd.MarkFlagsMutuallyExclusive("recipe", "profile")
+}
@@ -0,0 +1,92 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/densestvoid/krogerrecipeshopper/app"
+	"github.com/densestvoid/krogerrecipeshopper/data"
+)
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "import a bundle of recipes and lists into an account",
+	Long: `Import a JSON bundle written by the export command or the account page into an account.
+Nothing is imported if any of the bundle's recipes or lists are invalid.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		repo := openRepository()
+
+		input, err := cmd.Flags().GetString("input")
+		if err != nil {
+			return err
+		}
+		duplicates, err := cmd.Flags().GetString("duplicates")
+		if err != nil {
+			return err
+		}
+		if !slices.Contains([]string{data.BundleDuplicatesSkip, data.BundleDuplicatesRename}, duplicates) {
+			return fmt.Errorf("invalid duplicates option %q, expected %s or %s", duplicates, data.BundleDuplicatesSkip, data.BundleDuplicatesRename)
+		}
+
+		accountID, err := accountFlag(ctx, cmd, repo)
+		if err != nil {
+			return err
+		}
+		if _, err := repo.GetAccountByID(ctx, accountID); isNotFound(err) {
+			return fmt.Errorf("no account %s", accountID)
+		} else if err != nil {
+			return fmt.Errorf("getting account: %w", err)
+		}
+
+		var reader io.Reader = os.Stdin
+		if input != "" {
+			file, err := os.Open(input)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			reader = file
+		}
+
+		var bundle data.Bundle
+		if err := json.NewDecoder(reader).Decode(&bundle); err != nil {
+			return fmt.Errorf("reading bundle: %w", err)
+		}
+		if err := app.ValidateBundle(bundle); err != nil {
+			return fmt.Errorf("invalid bundle: %w", err)
+		}
+
+		result, err := repo.ImportBundle(ctx, accountID, bundle, duplicates)
+		if err != nil {
+			return fmt.Errorf("importing bundle: %w", err)
+		}
+
+		fmt.Fprintf(os.Stdout, "imported %d recipes and %d lists\n", len(result.Recipes), len(result.Lists))
+		for _, rename := range result.Renamed {
+			fmt.Fprintf(os.Stdout, "renamed %q to %q\n", rename.From, rename.To)
+		}
+		for _, name := range result.Skipped {
+			fmt.Fprintf(os.Stdout, "skipped %q, the account already has one with that name\n", name)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	addAccountFlags(importCmd)
+	importCmd.Flags().StringP("input", "i", "", "file to read the bundle from, defaults to stdin")
+	importCmd.Flags().String("duplicates", data.BundleDuplicatesSkip, "how to handle recipes and lists with names the account already has, skip or rename")
+}
@@ -0,0 +1,81 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+)
+
+// jobsCmd represents the jobs command
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "inspect the background jobs",
+}
+
+// jobsRunsCmd represents the jobs runs command
+var jobsRunsCmd = &cobra.Command{
+	Use:   "runs",
+	Short: "list recent job runs and their errors",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		job, err := cmd.Flags().GetString("job")
+		if err != nil {
+			return err
+		}
+		failed, err := cmd.Flags().GetBool("failed")
+		if err != nil {
+			return err
+		}
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return err
+		}
+
+		filters := []data.ListJobRunsFilter{}
+		if job != "" {
+			filters = append(filters, data.ListJobRunsFilterByJob{Job: job})
+		}
+		if failed {
+			filters = append(filters, data.ListJobRunsFilterByFailed{})
+		}
+
+		runs, err := openRepository().ListJobRuns(context.Background(), filters, limit)
+		if err != nil {
+			return fmt.Errorf("listing job runs: %w", err)
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "STARTED\tJOB\tINSTANCE\tDURATION\tERROR")
+		for _, run := range runs {
+			errMsg := ""
+			if run.Error != nil {
+				errMsg = *run.Error
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n",
+				run.StartedAt.Local().Format(time.DateTime),
+				run.Job,
+				run.Instance,
+				run.FinishedAt.Sub(run.StartedAt).Round(time.Millisecond),
+				errMsg,
+			)
+		}
+		return writer.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(jobsCmd)
+	jobsCmd.AddCommand(jobsRunsCmd)
+
+	jobsRunsCmd.Flags().String("job", "", "only list runs of this job")
+	jobsRunsCmd.Flags().Bool("failed", false, "only list failed runs")
+	jobsRunsCmd.Flags().Int("limit", 20, "maximum number of runs to list")
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -38,6 +39,24 @@ func init() {
 	rootCmd.PersistentFlags().String("db-user", "kroger", "database user")
 	rootCmd.PersistentFlags().String("db-password", "password", "database password")
 
+	// Cache details
+	rootCmd.PersistentFlags().String("cache-host", "localhost", "cache host")
+	rootCmd.PersistentFlags().Int("cache-port", 6379, "cache port")
+	rootCmd.PersistentFlags().String("cache-password", "", "cache password")
+	rootCmd.PersistentFlags().Duration("cache-expiration", time.Hour*24, "cache expiration")
+
+	// Kroger application details
+	rootCmd.PersistentFlags().String("client-id", "", "Kroger application id")
+	rootCmd.PersistentFlags().String("client-secret", "", "Kroger application secret")
+	rootCmd.PersistentFlags().String("token-vault-key", "", "base64 encoded 32 byte key for encrypting stored Kroger tokens")
+
+	// Recipe photos, shared by the server and the accounts command
+	rootCmd.PersistentFlags().String("photo-dir", "photos", "directory uploaded recipe photos are stored in")
+
+	// Background jobs, shared by the server and workers
+	rootCmd.PersistentFlags().Duration("session-cleanup-interval", time.Hour, "how often expired sessions are deleted")
+	rootCmd.PersistentFlags().Duration("list-schedule-interval", 15*time.Minute, "how often scheduled lists are checked")
+
 	// Bind all persistent flags to viper configuration variables
 	if err := viper.BindPFlags(rootCmd.PersistentFlags()); err != nil {
 		panic(err)
@@ -12,13 +12,10 @@ import (
 	"net/http"
 	"time"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
-	"github.com/jmoiron/sqlx"
-	"github.com/redis/go-redis/v9"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
-	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/assets"
 	"github.com/densestvoid/krogerrecipeshopper/server"
 )
 
@@ -29,31 +26,27 @@ var serveCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		slog.SetLogLoggerLevel(slog.LevelDebug)
 
-		db, err := sqlx.Open("pgx", fmt.Sprintf(
-			"host=%s port=%d user=%s password=%s sslmode=disable",
-			viper.GetString("db-host"),
-			viper.GetInt("db-port"),
-			viper.GetString("db-user"),
-			viper.GetString("db-password"),
-		))
-		if err != nil {
-			panic(err)
-		}
-		repo := data.NewRepository(db)
-
-		client := redis.NewClient(&redis.Options{
-			Addr:     fmt.Sprintf("%s:%d", viper.GetString("cache-host"), viper.GetInt("cache-port")),
-			Password: viper.GetString("cache-password"),
-		})
-		if err := client.Ping(context.Background()).Err(); err != nil {
-			panic(err)
+		repo := openRepository()
+
+		assets.UseCDN(viper.GetBool("assets-cdn"))
+
+		cache := openCache()
+
+		photos := openPhotos()
+		defer photos.Close()
+		tokenVault := openTokenVault(repo)
+
+		// Jobs can instead be run by separate workers
+		if viper.GetBool("jobs") {
+			go newJobRunner(repo, cache, tokenVault).Run(context.Background())
 		}
-		cache := data.NewCache(client, viper.GetDuration("cache-expiration"))
 
 		handler := server.New(context.Background(), slog.Default(), server.Config{
 			ClientID:     viper.GetString("client-id"),
 			ClientSecret: viper.GetString("client-secret"),
 			Domain:       viper.GetString("domain"),
+			TokenVault:   tokenVault,
+			Photos:       photos,
 		}, repo, cache)
 
 		if !viper.GetBool("secure") {
@@ -94,16 +87,10 @@ func init() {
 	serveCmd.Flags().String("tls-cert", "", "server certificate")
 	serveCmd.Flags().String("tls-key", "", "server key")
 	serveCmd.MarkFlagsRequiredTogether("secure", "tls-cert", "tls-key")
-
-	// Cache details
-	serveCmd.Flags().String("cache-host", "localhost", "cache host")
-	serveCmd.Flags().Int("cache-port", 6379, "cache port")
-	serveCmd.Flags().String("cache-password", "", "cache password")
-	serveCmd.Flags().Duration("cache-expiration", time.Hour*24, "cache expiration")
+	serveCmd.Flags().Bool("assets-cdn", false, "load frontend assets from public CDNs instead of the embedded copies")
+	serveCmd.Flags().Bool("jobs", true, "run the background jobs in the server, disable when running separate workers")
 
 	// Kroger application details
-	serveCmd.Flags().String("client-id", "", "Kroger application id")
-	serveCmd.Flags().String("client-secret", "", "Kroger application secret")
 	serveCmd.Flags().String("domain", "", "Kroger apoplication domain for oath2 redirect url")
 
 	// Bind all local flags to viper configuration variables
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+
+	"github.com/densestvoid/krogerrecipeshopper/app"
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/jobs"
+	"github.com/densestvoid/krogerrecipeshopper/kroger"
+	"github.com/densestvoid/krogerrecipeshopper/storage"
+)
+
+// JobRunsRetention is how long job run history is kept
+const JobRunsRetention = 30 * 24 * time.Hour
+
+func openRepository() *data.Repository {
+	db, err := sqlx.Open("pgx", fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s sslmode=disable",
+		viper.GetString("db-host"),
+		viper.GetInt("db-port"),
+		viper.GetString("db-user"),
+		viper.GetString("db-password"),
+	))
+	if err != nil {
+		panic(err)
+	}
+	return data.NewRepository(db)
+}
+
+func openCache() *data.Cache {
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", viper.GetString("cache-host"), viper.GetInt("cache-port")),
+		Password: viper.GetString("cache-password"),
+	})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		panic(err)
+	}
+	return data.NewCache(client, viper.GetDuration("cache-expiration"))
+}
+
+func openPhotos() *storage.Local {
+	photos, err := storage.NewLocal(viper.GetString("photo-dir"))
+	if err != nil {
+		panic(err)
+	}
+	return photos
+}
+
+// openTokenVault returns nil if no vault key is configured
+func openTokenVault(repo *data.Repository) *data.KrogerTokenVault {
+	vaultKey := viper.GetString("token-vault-key")
+	if vaultKey == "" {
+		slog.Warn("no token vault key configured, background kroger operations are disabled")
+		return nil
+	}
+	key, err := base64.StdEncoding.DecodeString(vaultKey)
+	if err != nil {
+		panic(fmt.Errorf("decoding token vault key: %w", err))
+	}
+	tokenVault, err := data.NewKrogerTokenVault(repo, key)
+	if err != nil {
+		panic(err)
+	}
+	return tokenVault
+}
+
+func newJobRunner(repo *data.Repository, cache *data.Cache, tokenVault *data.KrogerTokenVault) *jobs.Runner {
+	runner := jobs.NewRunner(slog.Default(), repo, cache)
+
+	runner.Register(jobs.Job{
+		Name:     "session-cleanup",
+		Interval: viper.GetDuration("session-cleanup-interval"),
+		Run: func(ctx context.Context) error {
+			deleted, err := repo.DeleteExpiredSessions(ctx)
+			if err != nil {
+				return fmt.Errorf("deleting expired sessions: %w", err)
+			}
+			slog.Info("deleted expired sessions", slog.Int64("count", deleted))
+			return nil
+		},
+	})
+
+	listScheduler := app.NewListScheduler(
+		slog.Default(),
+		repo,
+		cache,
+		kroger.NewAuthorizationClient(http.DefaultClient, kroger.PublicEnvironment, viper.GetString("client-id"), viper.GetString("client-secret")),
+		tokenVault,
+	)
+	runner.Register(jobs.Job{
+		Name:     "list-schedules",
+		Interval: viper.GetDuration("list-schedule-interval"),
+		Run:      listScheduler.RunDue,
+	})
+
+	runner.Register(jobs.Job{
+		Name:     "job-runs-cleanup",
+		Interval: 24 * time.Hour,
+		Run: func(ctx context.Context) error {
+			deleted, err := repo.DeleteJobRuns(ctx, time.Now().Add(-JobRunsRetention))
+			if err != nil {
+				return fmt.Errorf("deleting old job runs: %w", err)
+			}
+			slog.Info("deleted old job runs", slog.Int64("count", deleted))
+			return nil
+		},
+	})
+
+	return runner
+}
@@ -0,0 +1,38 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+// workerCmd represents the worker command
+var workerCmd = &cobra.Command{
+	Use:   "worker",
+	Short: "run the background jobs without serving",
+	Long: `Run the background jobs without serving. Jobs take a lock before running,
+	so any number of workers and servers can run alongside each other.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		repo := openRepository()
+		cache := openCache()
+		tokenVault := openTokenVault(repo)
+
+		slog.Info("starting worker")
+		newJobRunner(repo, cache, tokenVault).Run(ctx)
+		slog.Info("worker stopped")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(workerCmd)
+}
@@ -0,0 +1,53 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AccessTokenScopeRead  = "read"
+	AccessTokenScopeWrite = "write"
+)
+
+// AccessToken is a personal access token; only the hash of the secret is stored
+type AccessToken struct {
+	ID         uuid.UUID  `db:"id"`
+	AccountID  uuid.UUID  `db:"account_id"`
+	Name       string     `db:"name"`
+	TokenHash  string     `db:"token_hash"`
+	Scope      string     `db:"scope"`
+	CreatedAt  time.Time  `db:"created_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+}
+
+func (r *Repository) CreateAccessToken(ctx context.Context, accountID uuid.UUID, name, tokenHash, scope string) (AccessToken, error) {
+	var token AccessToken
+	return token, r.db.GetContext(ctx, &token, `
+		INSERT INTO access_tokens (account_id, name, token_hash, scope)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, account_id, name, token_hash, scope, created_at, last_used_at
+	`, accountID, name, tokenHash, scope)
+}
+
+func (r *Repository) GetAccessTokenByHash(ctx context.Context, tokenHash string) (AccessToken, error) {
+	var token AccessToken
+	return token, r.db.GetContext(ctx, &token, `SELECT id, account_id, name, token_hash, scope, created_at, last_used_at FROM access_tokens WHERE token_hash = $1`, tokenHash)
+}
+
+func (r *Repository) ListAccessTokens(ctx context.Context, accountID uuid.UUID) ([]AccessToken, error) {
+	tokens := []AccessToken{}
+	return tokens, r.db.SelectContext(ctx, &tokens, `SELECT id, account_id, name, token_hash, scope, created_at, last_used_at FROM access_tokens WHERE account_id = $1 ORDER BY created_at`, accountID)
+}
+
+func (r *Repository) TouchAccessToken(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE access_tokens SET last_used_at = NOW() WHERE id = $1`, id)
+	return err
+}
+
+func (r *Repository) DeleteAccessToken(ctx context.Context, accountID, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM access_tokens WHERE account_id = $1 AND id = $2`, accountID, id)
+	return err
+}
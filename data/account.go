@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -24,19 +25,36 @@ const (
 	HomepageOptionExplore   = "explore"
 )
 
+// How the product bought for an ingredient with alternatives is picked
+const (
+	AlternativeChoiceInStock  = "in-stock" // the first product in stock at the account's store
+	AlternativeChoiceCheapest = "cheapest" // the cheapest product in stock for the quantity needed
+)
+
 type Account struct {
-	ID              uuid.UUID
-	KrogerProfileID uuid.UUID
-	ImageSize       string
-	LocationID      *string
-	Homepage        string
+	ID                uuid.UUID
+	KrogerProfileID   uuid.UUID
+	ImageSize         string
+	LocationID        *string
+	Homepage          string
+	AlternativeChoice string
 }
 
 type Session struct {
-	ID        uuid.UUID
-	AccountID uuid.UUID
+	ID         uuid.UUID
+	AccountID  uuid.UUID
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	UserAgent  string
+	ExpiresAt  time.Time
 }
 
+const (
+	SessionUserAgentMaxLength = 512
+	// SessionTouchInterval limits how often a session's last seen time is written
+	SessionTouchInterval = 5 * time.Minute
+)
+
 type Profile struct {
 	AccountID   uuid.UUID
 	DisplayName string
@@ -52,95 +70,226 @@ func (r *Repository) CreateAccount(ctx context.Context, krogerProfileID uuid.UUI
 }
 
 func (r *Repository) GetAccountByKrogerProfileID(ctx context.Context, krogerProfileID uuid.UUID) (Account, error) {
-	row := r.db.QueryRowContext(ctx, `SELECT id, kroger_profile_id, image_size, location_id, homepage FROM accounts WHERE kroger_profile_id = $1`, krogerProfileID)
+	row := r.db.QueryRowContext(ctx, `SELECT id, kroger_profile_id, image_size, location_id, homepage, alternative_choice FROM accounts WHERE kroger_profile_id = $1`, krogerProfileID)
 	if err := row.Err(); err != nil {
 		return Account{}, err
 	}
 	var account Account
-	return account, row.Scan(&account.ID, &account.KrogerProfileID, &account.ImageSize, &account.LocationID, &account.Homepage)
+	return account, row.Scan(&account.ID, &account.KrogerProfileID, &account.ImageSize, &account.LocationID, &account.Homepage, &account.AlternativeChoice)
 }
 
 func (r *Repository) GetAccountByID(ctx context.Context, id uuid.UUID) (Account, error) {
-	row := r.db.QueryRowContext(ctx, `SELECT id, kroger_profile_id, image_size, location_id, homepage FROM accounts WHERE id = $1`, id)
+	row := r.db.QueryRowContext(ctx, `SELECT id, kroger_profile_id, image_size, location_id, homepage, alternative_choice FROM accounts WHERE id = $1`, id)
 	if err := row.Err(); err != nil {
 		return Account{}, err
 	}
 	var account Account
-	return account, row.Scan(&account.ID, &account.KrogerProfileID, &account.ImageSize, &account.LocationID, &account.Homepage)
+	return account, row.Scan(&account.ID, &account.KrogerProfileID, &account.ImageSize, &account.LocationID, &account.Homepage, &account.AlternativeChoice)
 }
 
-func (r *Repository) DeleteAccount(ctx context.Context, id uuid.UUID) (retErr error) {
+// AccountListing is an account with its profile's display name, nil if it has no profile
+type AccountListing struct {
+	Account
+	DisplayName *string
+}
+
+func (r *Repository) ListAccounts(ctx context.Context) ([]AccountListing, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT accounts.id, accounts.kroger_profile_id, accounts.image_size, accounts.location_id, accounts.homepage, accounts.alternative_choice, profiles.display_name
+		FROM accounts LEFT JOIN profiles ON profiles.account_id = accounts.id
+		ORDER BY profiles.display_name, accounts.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []AccountListing
+	for rows.Next() {
+		var account AccountListing
+		if err := rows.Scan(&account.ID, &account.KrogerProfileID, &account.ImageSize, &account.LocationID, &account.Homepage, &account.AlternativeChoice, &account.DisplayName); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, rows.Err()
+}
+
+// DeleteAccount returns the photo IDs of the account's deleted recipes, whose files are removed by the caller
+func (r *Repository) DeleteAccount(ctx context.Context, id uuid.UUID) (photoIDs []uuid.UUID, retErr error) {
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	defer Rollback(tx, &retErr)
 
 	// Clear ingredients
 	if _, err := tx.ExecContext(ctx, `DELETE FROM ingredients USING recipe_list_view AS recipes WHERE ingredients.list_id = recipes.list_id AND recipes.account_id = $1`, id); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Clear favorites
 	if _, err := tx.ExecContext(ctx, `DELETE FROM favorites USING recipe_list_view AS recipes WHERE favorites.list_id = recipes.list_id AND recipes.account_id = $1`, id); err != nil {
-		return err
+		return nil, err
+	}
+
+	// Clear reviews of the account's recipes and by the account
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recipe_reviews USING lists WHERE lists.id = recipe_reviews.list_id AND lists.account_id = $1`, id); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recipe_reviews WHERE recipe_reviews.account_id = $1`, id); err != nil {
+		return nil, err
+	}
+
+	// Clear recipe revisions
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recipe_revisions USING lists WHERE lists.id = recipe_revisions.list_id AND lists.account_id = $1`, id); err != nil {
+		return nil, err
+	}
+
+	// Clear recipe tags
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recipe_tags USING lists WHERE lists.id = recipe_tags.list_id AND lists.account_id = $1`, id); err != nil {
+		return nil, err
+	}
+
+	// Clear recipe steps
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recipe_step_ingredients USING lists WHERE lists.id = recipe_step_ingredients.list_id AND lists.account_id = $1`, id); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recipe_steps USING lists WHERE lists.id = recipe_steps.list_id AND lists.account_id = $1`, id); err != nil {
+		return nil, err
+	}
+
+	// Clear unmatched imported ingredients
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recipe_import_ingredients USING lists WHERE lists.id = recipe_import_ingredients.list_id AND lists.account_id = $1`, id); err != nil {
+		return nil, err
 	}
 
 	// Clear recipes
-	if _, err := tx.ExecContext(ctx, `DELETE FROM recipes USING lists WHERE lists.id = recipes.list_id AND lists.account_id = $1`, id); err != nil {
-		return err
+	if err := tx.SelectContext(ctx, &photoIDs, `
+		WITH deleted AS (
+			DELETE FROM recipes USING lists WHERE lists.id = recipes.list_id AND lists.account_id = $1 RETURNING recipes.photo_id
+		)
+		SELECT photo_id FROM deleted WHERE photo_id IS NOT NULL
+	`, id); err != nil {
+		return nil, err
+	}
+
+	// Clear list schedules
+	if _, err := tx.ExecContext(ctx, `DELETE FROM list_schedule_runs USING lists WHERE lists.id = list_schedule_runs.list_id AND lists.account_id = $1`, id); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM list_schedules USING lists WHERE lists.id = list_schedules.list_id AND lists.account_id = $1`, id); err != nil {
+		return nil, err
+	}
+
+	// Clear ingredient alternatives
+	if _, err := tx.ExecContext(ctx, `DELETE FROM ingredient_alternatives USING lists WHERE lists.id = ingredient_alternatives.list_id AND lists.account_id = $1`, id); err != nil {
+		return nil, err
 	}
 
 	// Clear lists
 	if _, err := tx.ExecContext(ctx, `DELETE FROM lists WHERE lists.account_id = $1`, id); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Clear favorites
 	if _, err := tx.ExecContext(ctx, `DELETE FROM favorites WHERE favorites.account_id = $1`, id); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Clear cart
 	if _, err := tx.ExecContext(ctx, `DELETE FROM cart_products WHERE cart_products.account_id = $1`, id); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Clear profile
 	if _, err := tx.ExecContext(ctx, `DELETE FROM profiles WHERE profiles.account_id = $1`, id); err != nil {
-		return err
+		return nil, err
+	}
+
+	// Clear access tokens
+	if _, err := tx.ExecContext(ctx, `DELETE FROM access_tokens WHERE access_tokens.account_id = $1`, id); err != nil {
+		return nil, err
+	}
+
+	// Clear kroger tokens
+	if _, err := tx.ExecContext(ctx, `DELETE FROM kroger_tokens WHERE kroger_tokens.account_id = $1`, id); err != nil {
+		return nil, err
 	}
 
 	// Clear sessions
 	if _, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE sessions.account_id = $1`, id); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Clear account
 	if _, err := tx.ExecContext(ctx, `DELETE FROM accounts WHERE accounts.id = $1`, id); err != nil {
-		return err
+		return nil, err
 	}
 
-	return tx.Commit()
+	return photoIDs, tx.Commit()
 }
 
-func (r *Repository) CreateSession(ctx context.Context, accountID uuid.UUID) (Session, error) {
-	row := r.db.QueryRowContext(ctx, `INSERT INTO sessions(account_id) VALUES($1) RETURNING id, account_id`, accountID)
+func (r *Repository) CreateSession(ctx context.Context, accountID uuid.UUID, userAgent string, lifetime time.Duration) (Session, error) {
+	if len(userAgent) > SessionUserAgentMaxLength {
+		userAgent = userAgent[:SessionUserAgentMaxLength]
+	}
+	row := r.db.QueryRowContext(ctx, `
+		INSERT INTO sessions(account_id, user_agent, expires_at)
+		VALUES($1, $2, NOW() + make_interval(secs => $3))
+		RETURNING id, account_id, created_at, last_seen_at, user_agent, expires_at
+	`, accountID, userAgent, lifetime.Seconds())
 	if err := row.Err(); err != nil {
 		return Session{}, err
 	}
 	var session Session
-	return session, row.Scan(&session.ID, &session.AccountID)
+	return session, row.Scan(&session.ID, &session.AccountID, &session.CreatedAt, &session.LastSeenAt, &session.UserAgent, &session.ExpiresAt)
 }
 
+// GetSessionByID only returns sessions that haven't expired
 func (r *Repository) GetSessionByID(ctx context.Context, id uuid.UUID) (Session, error) {
-	row := r.db.QueryRowContext(ctx, `SELECT id, account_id FROM sessions WHERE id = $1`, id)
+	row := r.db.QueryRowContext(ctx, `SELECT id, account_id, created_at, last_seen_at, user_agent, expires_at FROM sessions WHERE id = $1 AND expires_at > NOW()`, id)
 	if err := row.Err(); err != nil {
 		return Session{}, err
 	}
 	var session Session
-	return session, row.Scan(&session.ID, &session.AccountID)
+	return session, row.Scan(&session.ID, &session.AccountID, &session.CreatedAt, &session.LastSeenAt, &session.UserAgent, &session.ExpiresAt)
+}
+
+func (r *Repository) ListSessions(ctx context.Context, accountID uuid.UUID) ([]Session, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, account_id, created_at, last_seen_at, user_agent, expires_at
+		FROM sessions
+		WHERE account_id = $1 AND expires_at > NOW()
+		ORDER BY last_seen_at DESC
+	`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		var session Session
+		if err := rows.Scan(&session.ID, &session.AccountID, &session.CreatedAt, &session.LastSeenAt, &session.UserAgent, &session.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// TouchSession records activity on the session and extends its expiry,
+// writes are skipped if the session was seen within the SessionTouchInterval
+func (r *Repository) TouchSession(ctx context.Context, id uuid.UUID, lifetime time.Duration) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE sessions
+		SET last_seen_at = NOW(), expires_at = NOW() + make_interval(secs => $2)
+		WHERE id = $1 AND last_seen_at < NOW() - make_interval(secs => $3)
+	`, id, lifetime.Seconds(), SessionTouchInterval.Seconds())
+	return err
 }
 
 func (r *Repository) DeleteSession(ctx context.Context, id uuid.UUID) error {
@@ -148,6 +297,19 @@ func (r *Repository) DeleteSession(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+func (r *Repository) DeleteAccountSessions(ctx context.Context, accountID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE account_id = $1`, accountID)
+	return err
+}
+
+func (r *Repository) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 func (r *Repository) UpdateAccountImageSize(ctx context.Context, id uuid.UUID, imageSize string) error {
 	_, err := r.db.ExecContext(ctx, `UPDATE accounts SET image_size = $2 WHERE id = $1`, id, imageSize)
 	return err
@@ -163,6 +325,11 @@ func (r *Repository) UpdateAccountHomepage(ctx context.Context, id uuid.UUID, ho
 	return err
 }
 
+func (r *Repository) UpdateAccountAlternativeChoice(ctx context.Context, id uuid.UUID, alternativeChoice string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE accounts SET alternative_choice = $2 WHERE id = $1`, id, alternativeChoice)
+	return err
+}
+
 func (r *Repository) CreateProfile(ctx context.Context, accountID uuid.UUID, displayName string) (Profile, error) {
 	row := r.db.QueryRowContext(ctx, `
         INSERT INTO profiles (account_id, display_name)
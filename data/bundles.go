@@ -0,0 +1,281 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// BundleVersion is the version of the bundle format written by exports, imports only accept this version
+const BundleVersion = 1
+
+// How imports handle a recipe or list with the same name as one the account already has
+const (
+	BundleDuplicatesSkip   = "skip"
+	BundleDuplicatesRename = "rename"
+)
+
+// Bundle is a portable copy of recipes and lists, for backing them up or moving them between accounts
+type Bundle struct {
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exportedAt"`
+	Recipes    []BundleRecipe `json:"recipes"`
+	Lists      []BundleList   `json:"lists"`
+}
+
+type BundleList struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Ingredients []BundleIngredient `json:"ingredients"`
+}
+
+type BundleRecipe struct {
+	BundleList
+	InstructionType string       `json:"instructionType"`
+	Instructions    string       `json:"instructions"`
+	Visibility      string       `json:"visibility"`
+	Tags            []string     `json:"tags,omitempty"`
+	Steps           []RecipeStep `json:"steps,omitempty"` // split from the instructions when missing
+}
+
+type BundleIngredient struct {
+	ProductID    string   `json:"productID"`
+	Quantity     float64  `json:"quantity"` // fraction of the product's package
+	Staple       bool     `json:"staple"`
+	Amount       float64  `json:"amount,omitempty"` // the recipe amount the quantity was computed from
+	Unit         string   `json:"unit,omitempty"`
+	Note         string   `json:"note,omitempty"`
+	DisplayName  string   `json:"displayName,omitempty"`
+	Section      string   `json:"section,omitempty"`
+	Alternatives []string `json:"alternatives,omitempty"` // product IDs that can be bought in place of the product, in order of preference
+}
+
+// quantityPercent converts the quantity to the percentage ingredients are stored as, tiny quantities are kept as 1%
+func (i BundleIngredient) quantityPercent() int {
+	return max(int(math.Round(i.Quantity*100)), 1)
+}
+
+// BundleImportResult lists the names of the imported recipes and lists, after renaming duplicates
+type BundleImportResult struct {
+	Recipes []string
+	Lists   []string
+	Skipped []string
+	Renamed []BundleRename
+}
+
+type BundleRename struct {
+	From string // the name in the bundle
+	To   string // the name it was imported as
+}
+
+// ExportBundle copies the recipes and lists with their ingredients, recipe steps and tags
+func (r *Repository) ExportBundle(ctx context.Context, recipes []Recipe, lists []List) (Bundle, error) {
+	bundle := Bundle{
+		Version:    BundleVersion,
+		ExportedAt: time.Now().UTC(),
+		Recipes:    []BundleRecipe{},
+		Lists:      []BundleList{},
+	}
+
+	for _, recipe := range recipes {
+		ingredients, err := r.exportBundleIngredients(ctx, recipe.ListID)
+		if err != nil {
+			return Bundle{}, err
+		}
+		steps, err := listRecipeSteps(ctx, r.db, recipe.ListID)
+		if err != nil {
+			return Bundle{}, err
+		}
+		tags, err := r.ListRecipeTags(ctx, recipe.ListID)
+		if err != nil {
+			return Bundle{}, err
+		}
+
+		bundleRecipe := BundleRecipe{
+			BundleList: BundleList{
+				Name:        recipe.Name,
+				Description: recipe.Description,
+				Ingredients: ingredients,
+			},
+			InstructionType: recipe.InstructionType,
+			Instructions:    recipe.Instructions,
+			Visibility:      recipe.Visibility,
+			Steps:           steps,
+		}
+		for _, tag := range tags {
+			bundleRecipe.Tags = append(bundleRecipe.Tags, tag.Name)
+		}
+		bundle.Recipes = append(bundle.Recipes, bundleRecipe)
+	}
+
+	for _, list := range lists {
+		ingredients, err := r.exportBundleIngredients(ctx, list.ID)
+		if err != nil {
+			return Bundle{}, err
+		}
+		bundle.Lists = append(bundle.Lists, BundleList{
+			Name:        list.Name,
+			Description: list.Description,
+			Ingredients: ingredients,
+		})
+	}
+
+	return bundle, nil
+}
+
+// ExportAccountBundle exports all of the account's recipes and lists
+func (r *Repository) ExportAccountBundle(ctx context.Context, accountID uuid.UUID) (Bundle, error) {
+	recipes, err := r.ListRecipes(ctx, accountID, []ListRecipesFilter{ListRecipesFilterByAccountID{AccountID: accountID}}, []ListRecipesOrderBy{
+		{Field: "name", Direction: "asc"},
+	})
+	if err != nil {
+		return Bundle{}, err
+	}
+	lists, err := r.ListLists(ctx, accountID, nil, []ListListsOrderBy{
+		{Field: "name", Direction: "asc"},
+	})
+	if err != nil {
+		return Bundle{}, err
+	}
+	return r.ExportBundle(ctx, recipes, lists)
+}
+
+func (r *Repository) exportBundleIngredients(ctx context.Context, listID uuid.UUID) ([]BundleIngredient, error) {
+	ingredients, err := r.ListIngredients(ctx, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	bundleIngredients := []BundleIngredient{}
+	for _, ingredient := range ingredients {
+		bundleIngredients = append(bundleIngredients, BundleIngredient{
+			ProductID:    ingredient.ProductID,
+			Quantity:     float64(ingredient.Quantity) / 100,
+			Staple:       ingredient.Staple,
+			Amount:       ingredient.Amount,
+			Unit:         ingredient.Unit,
+			Note:         ingredient.Note,
+			DisplayName:  ingredient.DisplayName,
+			Section:      ingredient.Section,
+			Alternatives: ingredient.Alternatives,
+		})
+	}
+	return bundleIngredients, nil
+}
+
+// ImportBundle creates the bundle's recipes and lists for the account in a single transaction, so a failed import creates nothing.
+// The bundle must already be validated.
+func (r *Repository) ImportBundle(ctx context.Context, accountID uuid.UUID, bundle Bundle, duplicates string) (result BundleImportResult, retErr error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return BundleImportResult{}, err
+	}
+	defer Rollback(tx, &retErr)
+
+	var recipeNames, listNames []string
+	if err := tx.SelectContext(ctx, &recipeNames, `SELECT name FROM recipe_list_view WHERE account_id = $1`, accountID); err != nil {
+		return BundleImportResult{}, err
+	}
+	if err := tx.SelectContext(ctx, &listNames, `
+		SELECT name FROM lists LEFT JOIN recipes ON recipes.list_id = lists.id
+		WHERE account_id = $1 AND recipes.list_id IS NULL
+	`, accountID); err != nil {
+		return BundleImportResult{}, err
+	}
+	existingRecipes, existingLists := map[string]bool{}, map[string]bool{}
+	for _, name := range recipeNames {
+		existingRecipes[name] = true
+	}
+	for _, name := range listNames {
+		existingLists[name] = true
+	}
+
+	result = BundleImportResult{Recipes: []string{}, Lists: []string{}, Skipped: []string{}, Renamed: []BundleRename{}}
+	// importName is the name to import as, or false if the duplicate is skipped
+	importName := func(name string, existing map[string]bool) (string, bool) {
+		if !existing[name] {
+			existing[name] = true
+			return name, true
+		} else if duplicates != BundleDuplicatesRename {
+			result.Skipped = append(result.Skipped, name)
+			return "", false
+		}
+
+		for i := 2; ; i++ {
+			suffix := fmt.Sprintf(" (%d)", i)
+			renamed := name
+			if runes := []rune(renamed); len(runes)+len(suffix) > ListNameMaxLength {
+				renamed = string(runes[:ListNameMaxLength-len(suffix)])
+			}
+			renamed += suffix
+			if !existing[renamed] {
+				existing[renamed] = true
+				result.Renamed = append(result.Renamed, BundleRename{From: name, To: renamed})
+				return renamed, true
+			}
+		}
+	}
+
+	for _, recipe := range bundle.Recipes {
+		name, ok := importName(recipe.Name, existingRecipes)
+		if !ok {
+			continue
+		}
+
+		listID, err := r.createList(ctx, tx, accountID, name, recipe.Description)
+		if err != nil {
+			return BundleImportResult{}, err
+		}
+		if err := importBundleIngredients(ctx, tx, listID, recipe.Ingredients); err != nil {
+			return BundleImportResult{}, err
+		}
+		if err := createRecipe(ctx, tx, listID, recipe.InstructionType, recipe.Instructions, recipe.Visibility, recipe.Steps); err != nil {
+			return BundleImportResult{}, err
+		}
+		if err := setRecipeTags(ctx, tx, listID, recipe.Tags); err != nil {
+			return BundleImportResult{}, err
+		}
+		if err := recordRecipeRevision(ctx, tx, listID, nil); err != nil {
+			return BundleImportResult{}, err
+		}
+		result.Recipes = append(result.Recipes, name)
+	}
+
+	for _, list := range bundle.Lists {
+		name, ok := importName(list.Name, existingLists)
+		if !ok {
+			continue
+		}
+
+		listID, err := r.createList(ctx, tx, accountID, name, list.Description)
+		if err != nil {
+			return BundleImportResult{}, err
+		}
+		if err := importBundleIngredients(ctx, tx, listID, list.Ingredients); err != nil {
+			return BundleImportResult{}, err
+		}
+		result.Lists = append(result.Lists, name)
+	}
+
+	return result, tx.Commit()
+}
+
+// importBundleIngredients adds the ingredients in the order they are in the bundle
+func importBundleIngredients(ctx context.Context, tx *sqlx.Tx, listID uuid.UUID, ingredients []BundleIngredient) error {
+	for position, ingredient := range ingredients {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO ingredients (`+ingredientColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`, ingredient.ProductID, listID, ingredient.quantityPercent(), ingredient.Staple, ingredient.Amount, ingredient.Unit,
+			ingredient.Note, ingredient.DisplayName, ingredient.Section, position); err != nil {
+			return err
+		}
+		if err := setIngredientAlternatives(ctx, tx, listID, ingredient.ProductID, ingredient.Alternatives); err != nil {
+			return err
+		}
+	}
+	return nil
+}
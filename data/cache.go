@@ -85,6 +85,74 @@ func (c *Cache) RetrieveKrogerProduct(ctx context.Context, productIDs ...string)
 	return products, productIDMisses, nil
 }
 
+// CacheProductOffer is a product's price and stock level at a store, they change through the day so are kept apart from the product
+type CacheProductOffer struct {
+	ProductID  string  `json:"productID"`
+	Price      float64 `json:"price,omitempty"`
+	StockLevel string  `json:"stockLevel,omitempty"`
+}
+
+// productOfferExpiration keeps offers long enough to be reused while shopping, but not for the day products are kept
+const productOfferExpiration = 15 * time.Minute
+
+func productOffersKey(locationID string) string {
+	return "product-offers:" + locationID
+}
+
+func (c *Cache) StoreKrogerProductOffers(ctx context.Context, locationID string, offers ...CacheProductOffer) error {
+	var offersJSON = map[string]any{}
+	for _, offer := range offers {
+		offerJSON, err := json.Marshal(offer)
+		if err != nil {
+			return err
+		}
+		offersJSON[offer.ProductID] = string(offerJSON)
+	}
+	if len(offersJSON) == 0 {
+		return nil
+	}
+
+	_, err := c.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		if err := p.HSet(ctx, productOffersKey(locationID), offersJSON).Err(); err != nil {
+			slog.Error("caching product offers", "error", err)
+			return err
+		}
+
+		if err := p.HExpire(ctx, productOffersKey(locationID), productOfferExpiration, slices.Collect(maps.Keys(offersJSON))...).Err(); err != nil {
+			slog.Error("setting product offer cache expiration", "error", err)
+			return err
+		}
+
+		return nil
+	})
+	return err
+}
+
+func (c *Cache) RetrieveKrogerProductOffers(ctx context.Context, locationID string, productIDs ...string) ([]CacheProductOffer, []string, error) {
+	values, err := c.client.HMGet(ctx, productOffersKey(locationID), productIDs...).Result()
+	if err != nil {
+		return nil, productIDs, err
+	}
+
+	var offers []CacheProductOffer
+	var productIDMisses []string
+	for i, value := range values {
+		if value == nil {
+			productIDMisses = append(productIDMisses, productIDs[i])
+			continue
+		}
+
+		var offer CacheProductOffer
+		if err := json.Unmarshal([]byte(value.(string)), &offer); err != nil {
+			productIDMisses = append(productIDMisses, productIDs[i])
+			continue
+		}
+
+		offers = append(offers, offer)
+	}
+	return offers, productIDMisses, nil
+}
+
 type CacheLocation struct {
 	LocationID string `json:"locationID"`
 	Name       string `json:"name"`
@@ -125,3 +193,40 @@ func (c *Cache) RetrieveKrogerLocation(ctx context.Context, locationID string) (
 	var location CacheLocation
 	return &location, json.Unmarshal([]byte(values), &location)
 }
+
+// LoginState is kept for the duration of a login, between redirecting to Kroger and the auth callback
+type LoginState struct {
+	CodeVerifier string `json:"codeVerifier"`
+	ReturnTo     string `json:"returnTo"`
+}
+
+func loginStateKey(state string) string {
+	return "login-state:" + state
+}
+
+func (c *Cache) StoreLoginState(ctx context.Context, state string, loginState LoginState, expiration time.Duration) error {
+	loginStateJSON, err := json.Marshal(loginState)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, loginStateKey(state), loginStateJSON, expiration).Err()
+}
+
+// TakeLoginState returns nil if the state is unknown or expired, a state can only be taken once
+func (c *Cache) TakeLoginState(ctx context.Context, state string) (*LoginState, error) {
+	value, err := c.client.GetDel(ctx, loginStateKey(state)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var loginState LoginState
+	return &loginState, json.Unmarshal([]byte(value), &loginState)
+}
+
+// AcquireLock sets the lock key if it isn't already held, it's released when the ttl expires
+func (c *Cache) AcquireLock(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, "lock:"+key, owner, ttl).Result()
+}
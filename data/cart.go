@@ -13,6 +13,8 @@ type CartProduct struct {
 	ProductID string
 	Quantity  int
 	Staple    bool
+	// The products of the ingredient the product was picked from, in order of preference, empty when it had no alternatives
+	Alternatives []string
 }
 
 func (r *Repository) GetCartProduct(ctx context.Context, accountID uuid.UUID, productID string) (CartProduct, error) {
@@ -21,7 +23,35 @@ func (r *Repository) GetCartProduct(ctx context.Context, accountID uuid.UUID, pr
 		return CartProduct{}, err
 	}
 	var cartProduct CartProduct
-	return cartProduct, row.Scan(&cartProduct.AccountID, &cartProduct.ProductID, &cartProduct.Quantity, &cartProduct.Staple)
+	if err := row.Scan(&cartProduct.AccountID, &cartProduct.ProductID, &cartProduct.Quantity, &cartProduct.Staple); err != nil {
+		return CartProduct{}, err
+	}
+
+	alternatives, err := r.listCartProductAlternatives(ctx, accountID)
+	if err != nil {
+		return CartProduct{}, err
+	}
+	cartProduct.Alternatives = alternatives[productID]
+	return cartProduct, nil
+}
+
+// listCartProductAlternatives returns the alternatives of the account's cart products by the picked product's ID
+func (r *Repository) listCartProductAlternatives(ctx context.Context, accountID uuid.UUID) (map[string][]string, error) {
+	var rows []struct {
+		ProductID            string `db:"product_id"`
+		AlternativeProductID string `db:"alternative_product_id"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, `
+		SELECT product_id, alternative_product_id FROM cart_product_alternatives WHERE account_id = $1 ORDER BY product_id, position
+	`, accountID); err != nil {
+		return nil, err
+	}
+
+	alternatives := map[string][]string{}
+	for _, row := range rows {
+		alternatives[row.ProductID] = append(alternatives[row.ProductID], row.AlternativeProductID)
+	}
+	return alternatives, nil
 }
 
 type ListCartProductsFilter interface {
@@ -57,11 +87,30 @@ func (r *Repository) ListCartProducts(ctx context.Context, accountID uuid.UUID,
 		}
 		cartProducts = append(cartProducts, &cartProduct)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	alternatives, err := r.listCartProductAlternatives(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	for _, cartProduct := range cartProducts {
+		cartProduct.Alternatives = alternatives[cartProduct.ProductID]
+	}
 	return cartProducts, nil
 }
 
-func (r *Repository) AddCartProduct(ctx context.Context, accountID uuid.UUID, productID string, quantity int, staple bool) error {
-	_, err := r.db.ExecContext(ctx, `
+// AddCartProduct adds the quantity to the product in the cart.
+// Alternatives the product was picked from replace the ones it was last picked from, and are kept when none are given.
+func (r *Repository) AddCartProduct(ctx context.Context, accountID uuid.UUID, productID string, quantity int, staple bool, alternatives []string) (retErr error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer Rollback(tx, &retErr)
+
+	if _, err := tx.ExecContext(ctx, `
 		INSERT INTO cart_products (account_id, product_id, quantity, staple)
 		VALUES ($1, $2, $3, $4)
 		ON CONFLICT (account_id, product_id)
@@ -69,8 +118,23 @@ func (r *Repository) AddCartProduct(ctx context.Context, accountID uuid.UUID, pr
 			WHEN EXCLUDED.staple THEN 0
 			WHEN not EXCLUDED.staple THEN EXCLUDED.quantity
 		END;
-	`, accountID, productID, quantity, staple)
-	return err
+	`, accountID, productID, quantity, staple); err != nil {
+		return err
+	}
+
+	if len(alternatives) > 0 {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM cart_product_alternatives WHERE account_id = $1 AND product_id = $2`, accountID, productID); err != nil {
+			return err
+		}
+		for position, alternative := range alternatives {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO cart_product_alternatives (account_id, product_id, alternative_product_id, position) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING
+			`, accountID, productID, alternative, position); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
 }
 
 func (r *Repository) SetCartProduct(ctx context.Context, accountID uuid.UUID, productID string, quantity *int, staple *bool) error {
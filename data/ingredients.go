@@ -3,8 +3,10 @@ package data
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 )
 
 type Ingredient struct {
@@ -12,8 +14,39 @@ type Ingredient struct {
 	ListID    uuid.UUID `db:"list_id"`
 	Quantity  int       `db:"quantity"` // represents a percentage of the total product
 	Staple    bool      `db:"staple"`
+	// The amount in recipe units the quantity was computed from, 0 and empty when it was entered as a fraction of the product
+	Amount float64 `db:"amount"`
+	Unit   string  `db:"unit"`
+	// How the recipe uses the ingredient, like "finely chopped"
+	Note string `db:"note"`
+	// Shown in place of the product description when set, like "yellow onion"
+	DisplayName string `db:"display_name"`
+	// Groups the recipe's ingredients, like "Sauce" or "Topping"
+	Section  string `db:"section"`
+	Position int    `db:"position"`
+	// Products that can be bought in place of the ingredient's product, in order of preference after it
+	Alternatives []string `db:"-"`
 }
 
+const (
+	// ProductIDMaxLength is the length of the product ID columns
+	ProductIDMaxLength             = 13
+	IngredientNoteMaxLength        = 256
+	IngredientDisplayNameMaxLength = 256
+	IngredientSectionMaxLength     = 64
+)
+
+const ingredientColumns = `product_id, list_id, quantity, staple, amount, unit, note, display_name, section, position`
+
+// insertIngredient adds a named ingredient after the list's other ingredients
+const insertIngredient = `
+	INSERT INTO ingredients (` + ingredientColumns + `)
+	VALUES (
+		:product_id, :list_id, :quantity, :staple, :amount, :unit, :note, :display_name, :section,
+		(SELECT COALESCE(MAX(position) + 1, 0) FROM ingredients WHERE list_id = :list_id)
+	)
+`
+
 func (i Ingredient) QuantityDecimalString() string {
 	if i.Quantity == 0 {
 		return ""
@@ -23,25 +56,181 @@ func (i Ingredient) QuantityDecimalString() string {
 
 func (r *Repository) GetIngredient(ctx context.Context, listID uuid.UUID, productID string) (Ingredient, error) {
 	var ingredient Ingredient
-	return ingredient, r.db.GetContext(ctx, &ingredient, `SELECT product_id, list_id, quantity, staple FROM ingredients WHERE product_id = $1 AND list_id = $2`, productID, listID)
+	if err := r.db.GetContext(ctx, &ingredient, `SELECT `+ingredientColumns+` FROM ingredients WHERE product_id = $1 AND list_id = $2`, productID, listID); err != nil {
+		return Ingredient{}, err
+	}
+
+	alternatives, err := listIngredientAlternatives(ctx, r.db, listID)
+	if err != nil {
+		return Ingredient{}, err
+	}
+	ingredient.Alternatives = alternatives[productID]
+	return ingredient, nil
 }
 
 func (m *Repository) ListIngredients(ctx context.Context, listID uuid.UUID) ([]Ingredient, error) {
 	ingredients := []Ingredient{}
-	return ingredients, m.db.SelectContext(ctx, &ingredients, `SELECT product_id, list_id, quantity, staple FROM ingredients WHERE list_id = $1 ORDER BY staple`, listID)
+	if err := m.db.SelectContext(ctx, &ingredients, `SELECT `+ingredientColumns+` FROM ingredients WHERE list_id = $1 ORDER BY position, product_id`, listID); err != nil {
+		return nil, err
+	}
+
+	alternatives, err := listIngredientAlternatives(ctx, m.db, listID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range ingredients {
+		ingredients[i].Alternatives = alternatives[ingredients[i].ProductID]
+	}
+	return ingredients, nil
+}
+
+// listIngredientAlternatives returns the alternatives of the list's ingredients by the ingredient's product ID
+func listIngredientAlternatives(ctx context.Context, q sqlx.QueryerContext, listID uuid.UUID) (map[string][]string, error) {
+	var rows []struct {
+		ProductID            string `db:"product_id"`
+		AlternativeProductID string `db:"alternative_product_id"`
+	}
+	if err := sqlx.SelectContext(ctx, q, &rows, `
+		SELECT product_id, alternative_product_id FROM ingredient_alternatives WHERE list_id = $1 ORDER BY product_id, position
+	`, listID); err != nil {
+		return nil, err
+	}
+
+	alternatives := map[string][]string{}
+	for _, row := range rows {
+		alternatives[row.ProductID] = append(alternatives[row.ProductID], row.AlternativeProductID)
+	}
+	return alternatives, nil
+}
+
+// setIngredientAlternatives replaces the ingredient's alternatives, in order, leaving out the ingredient's own product and repeats
+func setIngredientAlternatives(ctx context.Context, tx *sqlx.Tx, listID uuid.UUID, productID string, alternatives []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM ingredient_alternatives WHERE list_id = $1 AND product_id = $2`, listID, productID); err != nil {
+		return err
+	}
+	for position, alternative := range alternatives {
+		if alternative == productID {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO ingredient_alternatives (list_id, product_id, alternative_product_id, position) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING
+		`, listID, productID, alternative, position); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListIngredientSections returns the list's named sections in the order of their first ingredient
+func (m *Repository) ListIngredientSections(ctx context.Context, listID uuid.UUID) ([]string, error) {
+	sections := []string{}
+	return sections, m.db.SelectContext(ctx, &sections, `
+		SELECT section FROM ingredients WHERE list_id = $1 AND section <> '' GROUP BY section ORDER BY MIN(position)
+	`, listID)
+}
+
+func (m *Repository) CreateIngredient(ctx context.Context, ingredient Ingredient) (retErr error) {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer Rollback(tx, &retErr)
+
+	if _, err := tx.NamedExecContext(ctx, insertIngredient, ingredient); err != nil {
+		return err
+	}
+	if err := setIngredientAlternatives(ctx, tx, ingredient.ListID, ingredient.ProductID, ingredient.Alternatives); err != nil {
+		return err
+	}
+	if err := recordRecipeRevision(ctx, tx, ingredient.ListID, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (m *Repository) CreateIngredient(ctx context.Context, productID string, listID uuid.UUID, quantity int, staple bool) error {
-	row := m.db.QueryRowContext(ctx, `INSERT INTO ingredients(product_id, list_id , quantity, staple) VALUES ($1, $2, $3, $4)`, productID, listID, quantity, staple)
-	return row.Err()
+// UpdateIngredient updates everything but the ingredient's alternatives, which are set by SetIngredientAlternatives
+func (m *Repository) UpdateIngredient(ctx context.Context, ingredient Ingredient) (retErr error) {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer Rollback(tx, &retErr)
+
+	if _, err := tx.NamedExecContext(ctx, `
+		UPDATE ingredients SET
+			quantity = :quantity, staple = :staple, amount = :amount, unit = :unit,
+			note = :note, display_name = :display_name, section = :section
+		WHERE product_id = :product_id AND list_id = :list_id
+	`, ingredient); err != nil {
+		return err
+	}
+	if err := recordRecipeRevision(ctx, tx, ingredient.ListID, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetIngredientAlternatives replaces the products that can be bought in place of the ingredient's product
+func (m *Repository) SetIngredientAlternatives(ctx context.Context, listID uuid.UUID, productID string, alternatives []string) (retErr error) {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer Rollback(tx, &retErr)
+
+	if err := setIngredientAlternatives(ctx, tx, listID, productID, alternatives); err != nil {
+		return err
+	}
+	if err := recordRecipeRevision(ctx, tx, listID, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (m *Repository) UpdateIngredient(ctx context.Context, productID string, listID uuid.UUID, quantity int, staple bool) error {
-	_, err := m.db.ExecContext(ctx, `UPDATE ingredients SET quantity=$1, staple=$2 WHERE product_id=$3 and list_id=$4`, quantity, staple, productID, listID)
-	return err
+// ReorderIngredients positions the list's ingredients in the order of the product IDs, which are ignored if they aren't in the list
+func (m *Repository) ReorderIngredients(ctx context.Context, listID uuid.UUID, productIDs []string) (retErr error) {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer Rollback(tx, &retErr)
+
+	// Saving the order a list is already in, like dropping an ingredient where it was picked up, isn't a new revision
+	var current []string
+	if err := tx.SelectContext(ctx, &current, `SELECT product_id FROM ingredients WHERE list_id = $1 ORDER BY position, product_id FOR UPDATE`, listID); err != nil {
+		return err
+	}
+	reordered := slices.DeleteFunc(slices.Clone(productIDs), func(productID string) bool { return !slices.Contains(current, productID) })
+	if slices.Equal(reordered, current) {
+		return tx.Commit()
+	}
+
+	for position, productID := range productIDs {
+		if _, err := tx.ExecContext(ctx, `UPDATE ingredients SET position = $1 WHERE product_id = $2 AND list_id = $3`, position, productID, listID); err != nil {
+			return err
+		}
+	}
+	if err := recordRecipeRevision(ctx, tx, listID, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (m *Repository) DeleteIngredient(ctx context.Context, productID string, listID uuid.UUID) error {
-	_, err := m.db.ExecContext(ctx, `DELETE FROM ingredients WHERE product_id=$1 and list_id=$2`, productID, listID)
-	return err
+func (m *Repository) DeleteIngredient(ctx context.Context, productID string, listID uuid.UUID) (retErr error) {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer Rollback(tx, &retErr)
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM ingredient_alternatives WHERE product_id = $1 AND list_id = $2`, productID, listID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM ingredients WHERE product_id=$1 and list_id=$2`, productID, listID); err != nil {
+		return err
+	}
+	if err := recordRecipeRevision(ctx, tx, listID, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
@@ -0,0 +1,77 @@
+package data
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type JobRun struct {
+	ID         uuid.UUID `db:"id"`
+	Job        string    `db:"job"`
+	Instance   string    `db:"instance"`
+	StartedAt  time.Time `db:"started_at"`
+	FinishedAt time.Time `db:"finished_at"`
+	Error      *string   `db:"error"`
+}
+
+func (r *Repository) CreateJobRun(ctx context.Context, run JobRun) error {
+	_, err := r.db.NamedExecContext(ctx, `
+		INSERT INTO job_runs (job, instance, started_at, finished_at, error)
+		VALUES (:job, :instance, :started_at, :finished_at, :error)
+	`, run)
+	return err
+}
+
+type ListJobRunsFilter interface {
+	listJobRunsFilter(args map[string]any) string
+}
+
+type ListJobRunsFilterByJob struct {
+	Job string
+}
+
+func (f ListJobRunsFilterByJob) listJobRunsFilter(args map[string]any) string {
+	args["job"] = f.Job
+	return `job = :job`
+}
+
+type ListJobRunsFilterByFailed struct{}
+
+func (f ListJobRunsFilterByFailed) listJobRunsFilter(args map[string]any) string {
+	return `error IS NOT NULL`
+}
+
+// ListJobRuns returns the most recent runs first
+func (r *Repository) ListJobRuns(ctx context.Context, filters []ListJobRunsFilter, limit int) ([]JobRun, error) {
+	query := `SELECT id, job, instance, started_at, finished_at, error FROM job_runs`
+	namedArgs := map[string]any{"limit": limit}
+	if len(filters) > 0 {
+		filterStrings := []string{}
+		for _, filter := range filters {
+			filterStrings = append(filterStrings, filter.listJobRunsFilter(namedArgs))
+		}
+		query += " WHERE " + strings.Join(filterStrings, " AND ")
+	}
+	query += " ORDER BY started_at DESC LIMIT :limit"
+
+	namedQuery, err := r.db.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer namedQuery.Close()
+
+	runs := []JobRun{}
+	return runs, namedQuery.SelectContext(ctx, &runs, namedArgs)
+}
+
+// DeleteJobRuns removes runs that started before the given time
+func (r *Repository) DeleteJobRuns(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM job_runs WHERE started_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
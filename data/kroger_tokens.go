@@ -0,0 +1,113 @@
+package data
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const KrogerTokenVaultKeySize = 32 // AES-256
+
+// KrogerTokens are an account's Kroger OAuth2 credentials
+type KrogerTokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+// KrogerTokenVault stores each account's Kroger tokens encrypted with AES-GCM,
+// so the server can act for the account outside of a request
+type KrogerTokenVault struct {
+	repo *Repository
+	aead cipher.AEAD
+}
+
+func NewKrogerTokenVault(repo *Repository, key []byte) (*KrogerTokenVault, error) {
+	if len(key) != KrogerTokenVaultKeySize {
+		return nil, fmt.Errorf("token vault key must be %d bytes, got %d", KrogerTokenVaultKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &KrogerTokenVault{
+		repo: repo,
+		aead: aead,
+	}, nil
+}
+
+// The account ID is authenticated with each token so a ciphertext can't be moved to another account
+func (v *KrogerTokenVault) seal(accountID uuid.UUID, plaintext string) ([]byte, error) {
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return v.aead.Seal(nonce, nonce, []byte(plaintext), accountID[:]), nil
+}
+
+func (v *KrogerTokenVault) open(accountID uuid.UUID, ciphertext []byte) (string, error) {
+	nonceSize := v.aead.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return "", errors.New("token ciphertext too short")
+	}
+	plaintext, err := v.aead.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], accountID[:])
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func (v *KrogerTokenVault) Store(ctx context.Context, accountID uuid.UUID, tokens KrogerTokens) error {
+	accessToken, err := v.seal(accountID, tokens.AccessToken)
+	if err != nil {
+		return fmt.Errorf("encrypting access token: %w", err)
+	}
+	refreshToken, err := v.seal(accountID, tokens.RefreshToken)
+	if err != nil {
+		return fmt.Errorf("encrypting refresh token: %w", err)
+	}
+
+	_, err = v.repo.db.ExecContext(ctx, `
+		INSERT INTO kroger_tokens (account_id, access_token, refresh_token, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (account_id) DO UPDATE
+		SET access_token = EXCLUDED.access_token,
+			refresh_token = EXCLUDED.refresh_token,
+			expires_at = EXCLUDED.expires_at,
+			updated_at = NOW()
+	`, accountID, accessToken, refreshToken, tokens.ExpiresAt)
+	return err
+}
+
+func (v *KrogerTokenVault) Retrieve(ctx context.Context, accountID uuid.UUID) (KrogerTokens, error) {
+	var accessToken, refreshToken []byte
+	var tokens KrogerTokens
+	row := v.repo.db.QueryRowContext(ctx, `SELECT access_token, refresh_token, expires_at FROM kroger_tokens WHERE account_id = $1`, accountID)
+	if err := row.Scan(&accessToken, &refreshToken, &tokens.ExpiresAt); err != nil {
+		return KrogerTokens{}, err
+	}
+
+	var err error
+	if tokens.AccessToken, err = v.open(accountID, accessToken); err != nil {
+		return KrogerTokens{}, fmt.Errorf("decrypting access token: %w", err)
+	}
+	if tokens.RefreshToken, err = v.open(accountID, refreshToken); err != nil {
+		return KrogerTokens{}, fmt.Errorf("decrypting refresh token: %w", err)
+	}
+	return tokens, nil
+}
+
+func (v *KrogerTokenVault) Delete(ctx context.Context, accountID uuid.UUID) error {
+	_, err := v.repo.db.ExecContext(ctx, `DELETE FROM kroger_tokens WHERE account_id = $1`, accountID)
+	return err
+}
@@ -0,0 +1,121 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ListScheduleFrequencyWeekly  = "weekly"
+	ListScheduleFrequencyDays    = "days"
+	ListScheduleFrequencyMonthly = "monthly"
+)
+
+const (
+	ListScheduleRunStatusAdded   = "added"
+	ListScheduleRunStatusPushed  = "pushed"
+	ListScheduleRunStatusSkipped = "skipped"
+	ListScheduleRunStatusFailed  = "failed"
+)
+
+// ListSchedule adds a list's ingredients to its owner's cart on a schedule.
+// Only the field for the schedule's frequency is set: Weekday, IntervalDays or DayOfMonth.
+type ListSchedule struct {
+	ListID       uuid.UUID `db:"list_id"`
+	Frequency    string    `db:"frequency"`
+	Weekday      *int      `db:"weekday"`
+	IntervalDays *int      `db:"interval_days"`
+	DayOfMonth   *int      `db:"day_of_month"`
+	PushToKroger bool      `db:"push_to_kroger"`
+	Paused       bool      `db:"paused"`
+	SkipNext     bool      `db:"skip_next"`
+	NextRunAt    time.Time `db:"next_run_at"`
+}
+
+type ListScheduleRun struct {
+	ID     uuid.UUID `db:"id"`
+	ListID uuid.UUID `db:"list_id"`
+	RanAt  time.Time `db:"ran_at"`
+	Status string    `db:"status"`
+	Error  *string   `db:"error"`
+}
+
+func (r *Repository) GetListSchedule(ctx context.Context, listID uuid.UUID) (ListSchedule, error) {
+	var schedule ListSchedule
+	return schedule, r.db.GetContext(ctx, &schedule, `
+		SELECT list_id, frequency, weekday, interval_days, day_of_month, push_to_kroger, paused, skip_next, next_run_at
+		FROM list_schedules
+		WHERE list_id = $1
+	`, listID)
+}
+
+// ListDueListSchedules returns the unpaused schedules whose next run is at or before now
+func (r *Repository) ListDueListSchedules(ctx context.Context, now time.Time) ([]ListSchedule, error) {
+	schedules := []ListSchedule{}
+	return schedules, r.db.SelectContext(ctx, &schedules, `
+		SELECT list_id, frequency, weekday, interval_days, day_of_month, push_to_kroger, paused, skip_next, next_run_at
+		FROM list_schedules
+		WHERE NOT paused AND next_run_at <= $1
+		ORDER BY next_run_at
+	`, now)
+}
+
+// SetListSchedule creates or replaces the list's schedule
+func (r *Repository) SetListSchedule(ctx context.Context, schedule ListSchedule) error {
+	_, err := r.db.NamedExecContext(ctx, `
+		INSERT INTO list_schedules (list_id, frequency, weekday, interval_days, day_of_month, push_to_kroger, paused, skip_next, next_run_at)
+		VALUES (:list_id, :frequency, :weekday, :interval_days, :day_of_month, :push_to_kroger, :paused, :skip_next, :next_run_at)
+		ON CONFLICT (list_id) DO UPDATE
+		SET frequency = EXCLUDED.frequency,
+			weekday = EXCLUDED.weekday,
+			interval_days = EXCLUDED.interval_days,
+			day_of_month = EXCLUDED.day_of_month,
+			push_to_kroger = EXCLUDED.push_to_kroger,
+			paused = EXCLUDED.paused,
+			skip_next = EXCLUDED.skip_next,
+			next_run_at = EXCLUDED.next_run_at
+	`, schedule)
+	return err
+}
+
+// ClaimListScheduleRun moves the schedule to its next run, returning false if another worker already claimed the run
+func (r *Repository) ClaimListScheduleRun(ctx context.Context, listID uuid.UUID, runAt, nextRunAt time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE list_schedules
+		SET next_run_at = $3, skip_next = FALSE
+		WHERE list_id = $1 AND next_run_at = $2 AND NOT paused
+	`, listID, runAt, nextRunAt)
+	if err != nil {
+		return false, err
+	}
+	claimed, err := result.RowsAffected()
+	return claimed == 1, err
+}
+
+func (r *Repository) DeleteListSchedule(ctx context.Context, listID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM list_schedules WHERE list_id = $1`, listID)
+	return err
+}
+
+func (r *Repository) CreateListScheduleRun(ctx context.Context, listID uuid.UUID, status string, runErr error) error {
+	var errMsg *string
+	if runErr != nil {
+		msg := runErr.Error()
+		errMsg = &msg
+	}
+	_, err := r.db.ExecContext(ctx, `INSERT INTO list_schedule_runs (list_id, status, error) VALUES ($1, $2, $3)`, listID, status, errMsg)
+	return err
+}
+
+func (r *Repository) ListListScheduleRuns(ctx context.Context, listID uuid.UUID, limit int) ([]ListScheduleRun, error) {
+	runs := []ListScheduleRun{}
+	return runs, r.db.SelectContext(ctx, &runs, `
+		SELECT id, list_id, ran_at, status, error
+		FROM list_schedule_runs
+		WHERE list_id = $1
+		ORDER BY ran_at DESC
+		LIMIT $2
+	`, listID, limit)
+}
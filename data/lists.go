@@ -8,6 +8,11 @@ import (
 	"github.com/google/uuid"
 )
 
+const (
+	ListNameMaxLength        = 256
+	ListDescriptionMaxLength = 1024
+)
+
 type List struct {
 	ID          uuid.UUID `db:"id"`
 	AccountID   uuid.UUID `db:"account_id"`
@@ -20,6 +25,17 @@ func (r *Repository) GetList(ctx context.Context, listID uuid.UUID) (List, error
 	return list, r.db.GetContext(ctx, &list, `SELECT id, account_id, name, description FROM lists WHERE id = $1`, listID)
 }
 
+// GetPlainList is GetList for lists that aren't recipes, a recipe's list isn't found
+func (r *Repository) GetPlainList(ctx context.Context, listID uuid.UUID) (List, error) {
+	var list List
+	return list, r.db.GetContext(ctx, &list, `
+		SELECT id, account_id, name, description
+		FROM lists
+			LEFT JOIN recipes ON recipes.list_id = lists.id
+		WHERE id = $1 AND recipes.list_id IS NULL
+	`, listID)
+}
+
 type ListListsFilter interface {
 	listListsFilter(args map[string]any) string
 }
@@ -133,9 +149,18 @@ func (r *Repository) DeleteList(ctx context.Context, id uuid.UUID) (retErr error
 	}
 	defer Rollback(tx, &retErr)
 
+	if _, err := tx.ExecContext(ctx, `DELETE FROM ingredient_alternatives WHERE list_id = $1`, id); err != nil {
+		return err
+	}
 	if _, err := tx.ExecContext(ctx, `DELETE FROM ingredients where list_id = $1`, id); err != nil {
 		return err
 	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM list_schedule_runs WHERE list_id = $1`, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM list_schedules WHERE list_id = $1`, id); err != nil {
+		return err
+	}
 	if _, err := tx.ExecContext(ctx, `DELETE FROM lists WHERE id = $1`, id); err != nil {
 		return err
 	}
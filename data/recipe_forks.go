@@ -0,0 +1,170 @@
+package data
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// recipeSourceJoin adds the source and fork columns of a recipe listing, the listing must have an accountID argument
+const recipeSourceJoin = `
+	LEFT JOIN recipe_list_view AS sources ON sources.list_id = recipes.source_list_id
+	LEFT JOIN profiles AS source_profiles ON source_profiles.account_id = sources.account_id
+	LEFT JOIN (
+		SELECT list_id, MAX(number) AS number
+		FROM recipe_revisions
+		GROUP BY list_id
+	) AS source_revisions ON source_revisions.list_id = recipes.source_list_id
+	LEFT JOIN (
+		SELECT source_list_id, COUNT(*) AS fork_count
+		FROM recipes
+		GROUP BY source_list_id
+	) AS forks ON forks.source_list_id = recipes.list_id
+`
+
+// recipeSourceColumns hides the name and owner of sources the account can't view
+const recipeSourceColumns = `
+	recipes.source_list_id,
+	recipes.source_revision,
+	CASE WHEN sources.account_id = :accountID OR sources.visibility = 'public' THEN sources.name END AS source_name,
+	CASE WHEN sources.account_id = :accountID OR sources.visibility = 'public' THEN source_profiles.display_name END AS source_owner_name,
+	source_revisions.number AS source_latest_revision,
+	coalesce(forks.fork_count, 0) AS fork_count
+`
+
+// SourceChanged reports whether the recipe this was forked from has changed since it was forked or last synced
+func (r Recipe) SourceChanged() bool {
+	return r.SourceListID != nil && r.SourceLatestRevision != nil &&
+		(r.SourceRevision == nil || *r.SourceRevision < *r.SourceLatestRevision)
+}
+
+// ForkRecipe creates a recipe with the ingredients of the source recipe, remembering the source and its latest revision.
+// Text instructions are split into steps when steps is nil.
+func (r *Repository) ForkRecipe(ctx context.Context, sourceListID, accountID uuid.UUID, name, description, instructionType, instructions, visibility string, steps []RecipeStep) (listID uuid.UUID, retErr error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer Rollback(tx, &retErr)
+
+	listID, err = r.createList(ctx, tx, accountID, name, description)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO recipes (list_id, instruction_type, instructions, visibility, source_list_id, source_revision)
+		VALUES ($1, $2, $3, $4, $5, (SELECT MAX(number) FROM recipe_revisions WHERE list_id = $5))
+	`, listID, instructionType, instructions, visibility, sourceListID); err != nil {
+		return uuid.Nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO ingredients (`+ingredientColumns+`)
+		SELECT product_id, $1, quantity, staple, amount, unit, note, display_name, section, position FROM ingredients WHERE list_id = $2
+	`, listID, sourceListID); err != nil {
+		return uuid.Nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO ingredient_alternatives (list_id, product_id, alternative_product_id, position)
+		SELECT $1, product_id, alternative_product_id, position FROM ingredient_alternatives WHERE list_id = $2
+	`, listID, sourceListID); err != nil {
+		return uuid.Nil, err
+	}
+
+	if steps == nil {
+		steps = RecipeStepsFromText(instructions)
+	}
+	if err := setRecipeSteps(ctx, tx, listID, instructionType, steps); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := recordRecipeRevision(ctx, tx, listID, nil); err != nil {
+		return uuid.Nil, err
+	}
+
+	return listID, tx.Commit()
+}
+
+// SyncRecipeSource marks the source recipe's latest revision as seen by the fork.
+// When pulling ingredients, the ingredient changes the source made since the fork last synced are applied to the fork.
+func (r *Repository) SyncRecipeSource(ctx context.Context, listID uuid.UUID, pullIngredients bool) (retErr error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer Rollback(tx, &retErr)
+
+	var fork struct {
+		SourceListID   uuid.UUID `db:"source_list_id"`
+		SourceRevision *int      `db:"source_revision"`
+	}
+	if err := tx.GetContext(ctx, &fork, `
+		SELECT source_list_id, source_revision FROM recipes WHERE list_id = $1 AND source_list_id IS NOT NULL FOR UPDATE
+	`, listID); err != nil {
+		return err
+	}
+
+	var latest RecipeRevision
+	if err := tx.GetContext(ctx, &latest, `
+		SELECT * FROM recipe_revisions WHERE list_id = $1 ORDER BY number DESC LIMIT 1
+	`, fork.SourceListID); err != nil {
+		return err
+	}
+
+	if pullIngredients {
+		// Forks of recipes without revisions compare against an empty recipe
+		var synced RecipeRevision
+		if fork.SourceRevision != nil {
+			if err := tx.GetContext(ctx, &synced, `
+				SELECT * FROM recipe_revisions WHERE list_id = $1 AND number = $2
+			`, fork.SourceListID, *fork.SourceRevision); err != nil {
+				return err
+			}
+		}
+
+		from := map[string]RecipeSnapshotIngredient{}
+		for _, ingredient := range synced.Snapshot.Ingredients {
+			from[ingredient.ProductID] = ingredient
+		}
+		to := map[string]RecipeSnapshotIngredient{}
+		for _, ingredient := range latest.Snapshot.Ingredients {
+			to[ingredient.ProductID] = ingredient
+		}
+
+		for _, productID := range RecipeSnapshotProductIDs(synced.Snapshot, latest.Snapshot) {
+			fromIngredient, inFrom := from[productID]
+			toIngredient, inTo := to[productID]
+			if inFrom == inTo && fromIngredient.Equal(toIngredient) {
+				continue
+			}
+
+			if _, err := tx.ExecContext(ctx, `DELETE FROM ingredient_alternatives WHERE product_id = $1 AND list_id = $2`, productID, listID); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, `DELETE FROM ingredients WHERE product_id = $1 AND list_id = $2`, productID, listID); err != nil {
+				return err
+			}
+			if !inTo {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO ingredients (`+ingredientColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			`, productID, listID, toIngredient.Quantity, toIngredient.Staple, toIngredient.Amount, toIngredient.Unit,
+				toIngredient.Note, toIngredient.DisplayName, toIngredient.Section, toIngredient.Position); err != nil {
+				return err
+			}
+			if err := setIngredientAlternatives(ctx, tx, listID, productID, toIngredient.Alternatives); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE recipes SET source_revision = $1 WHERE list_id = $2`, latest.Number, listID); err != nil {
+		return err
+	}
+	if err := recordRecipeRevision(ctx, tx, listID, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
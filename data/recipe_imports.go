@@ -0,0 +1,137 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+const RecipeImportIngredientMaxLength = 512
+
+// RecipeImportIngredient is an ingredient line of an imported recipe still waiting to be matched to a product
+type RecipeImportIngredient struct {
+	ListID uuid.UUID `db:"list_id"`
+	Number int       `db:"number"` // the line's position in the imported recipe, starting at 1
+	Text   string    `db:"text"`
+}
+
+// recipeImportJoin adds the import_ingredient_count column of a recipe listing
+const recipeImportJoin = `
+	LEFT JOIN (
+		SELECT list_id, COUNT(*) AS import_ingredient_count
+		FROM recipe_import_ingredients
+		GROUP BY list_id
+	) AS import_ingredients ON import_ingredients.list_id = recipes.list_id
+`
+
+const recipeImportColumns = `
+	coalesce(import_ingredients.import_ingredient_count, 0) AS import_ingredient_count
+`
+
+// CreateImportedRecipe creates an imported recipe with its ingredient lines in the matching queue.
+// Imported recipes are private drafts until the ingredients are matched and the owner shares them.
+func (r *Repository) CreateImportedRecipe(ctx context.Context, accountID uuid.UUID, name, description string, steps []RecipeStep, lines []string) (listID uuid.UUID, retErr error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer Rollback(tx, &retErr)
+
+	listID, err = r.createList(ctx, tx, accountID, name, description)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	instructionType := InstructionTypeNone
+	if len(steps) > 0 {
+		instructionType = InstructionTypeText
+	}
+	if err := createRecipe(ctx, tx, listID, instructionType, JoinRecipeSteps(steps), VisibilityPrivate, steps); err != nil {
+		return uuid.Nil, err
+	}
+
+	for i, line := range lines {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO recipe_import_ingredients (list_id, number, text) VALUES ($1, $2, $3)
+		`, listID, i+1, line); err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	if err := recordRecipeRevision(ctx, tx, listID, nil); err != nil {
+		return uuid.Nil, err
+	}
+
+	return listID, tx.Commit()
+}
+
+func (r *Repository) ListRecipeImportIngredients(ctx context.Context, listID uuid.UUID) ([]RecipeImportIngredient, error) {
+	ingredients := []RecipeImportIngredient{}
+	return ingredients, r.db.SelectContext(ctx, &ingredients, `
+		SELECT list_id, number, text FROM recipe_import_ingredients WHERE list_id = $1 ORDER BY number
+	`, listID)
+}
+
+func (r *Repository) GetRecipeImportIngredient(ctx context.Context, listID uuid.UUID, number int) (RecipeImportIngredient, error) {
+	var ingredient RecipeImportIngredient
+	return ingredient, r.db.GetContext(ctx, &ingredient, `
+		SELECT list_id, number, text FROM recipe_import_ingredients WHERE list_id = $1 AND number = $2
+	`, listID, number)
+}
+
+// MatchRecipeImportIngredient takes the line off the matching queue and adds the ingredient to the recipe.
+// A product already in the recipe has the line's quantity added to it, unless the line is a staple.
+// Amounts are only added up when both are in the same unit, otherwise the sum is kept as a fraction of the product.
+func (r *Repository) MatchRecipeImportIngredient(ctx context.Context, number int, ingredient Ingredient) (retErr error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer Rollback(tx, &retErr)
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recipe_import_ingredients WHERE list_id = $1 AND number = $2`, ingredient.ListID, number); err != nil {
+		return err
+	}
+
+	var existing Ingredient
+	err = tx.GetContext(ctx, &existing, `
+		SELECT `+ingredientColumns+` FROM ingredients WHERE product_id = $1 AND list_id = $2 FOR UPDATE
+	`, ingredient.ProductID, ingredient.ListID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if _, err := tx.NamedExecContext(ctx, insertIngredient, ingredient); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case !ingredient.Staple:
+		// A measured line replaces a staple's placeholder quantity
+		if !existing.Staple {
+			ingredient.Quantity += existing.Quantity
+			if existing.Unit == ingredient.Unit {
+				ingredient.Amount += existing.Amount
+			} else {
+				ingredient.Amount, ingredient.Unit = 0, ""
+			}
+		}
+		if _, err := tx.NamedExecContext(ctx, `
+			UPDATE ingredients SET quantity = :quantity, staple = false, amount = :amount, unit = :unit
+			WHERE product_id = :product_id AND list_id = :list_id
+		`, ingredient); err != nil {
+			return err
+		}
+	}
+
+	if err := recordRecipeRevision(ctx, tx, ingredient.ListID, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SkipRecipeImportIngredient takes the line off the matching queue without adding an ingredient
+func (r *Repository) SkipRecipeImportIngredient(ctx context.Context, listID uuid.UUID, number int) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM recipe_import_ingredients WHERE list_id = $1 AND number = $2`, listID, number)
+	return err
+}
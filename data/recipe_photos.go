@@ -0,0 +1,24 @@
+package data
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// SetRecipePhoto replaces the recipe's photo, a nil photoID removes it. The replaced photo's ID is returned so it can be deleted from storage.
+func (r *Repository) SetRecipePhoto(ctx context.Context, listID uuid.UUID, photoID *uuid.UUID) (replaced *uuid.UUID, retErr error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer Rollback(tx, &retErr)
+
+	if err := tx.GetContext(ctx, &replaced, `SELECT photo_id FROM recipes WHERE list_id = $1 FOR UPDATE`, listID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE recipes SET photo_id = $1 WHERE list_id = $2`, photoID, listID); err != nil {
+		return nil, err
+	}
+	return replaced, tx.Commit()
+}
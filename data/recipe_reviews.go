@@ -0,0 +1,72 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RecipeRatingMin       = 1
+	RecipeRatingMax       = 5
+	RecipeReviewMaxLength = 2048
+)
+
+type RecipeReview struct {
+	ListID      uuid.UUID `db:"list_id"`
+	AccountID   uuid.UUID `db:"account_id"`
+	DisplayName *string   `db:"display_name"` // nil if the reviewer has no profile
+	Rating      int       `db:"rating"`
+	Review      string    `db:"review"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+// recipeRatingsJoin adds the rating and review_count columns of a recipe listing
+const recipeRatingsJoin = `
+	LEFT JOIN (
+		SELECT list_id, CAST(AVG(rating) AS FLOAT8) AS rating, COUNT(*) AS review_count
+		FROM recipe_reviews
+		GROUP BY list_id
+	) AS ratings ON ratings.list_id = recipes.list_id
+`
+
+const recipeRatingsColumns = `
+	coalesce(ratings.rating, 0) AS rating,
+	coalesce(ratings.review_count, 0) AS review_count
+`
+
+func (r *Repository) ListRecipeReviews(ctx context.Context, listID uuid.UUID) ([]RecipeReview, error) {
+	var reviews = []RecipeReview{}
+	return reviews, r.db.SelectContext(ctx, &reviews, `
+		SELECT
+			recipe_reviews.list_id,
+			recipe_reviews.account_id,
+			profiles.display_name,
+			recipe_reviews.rating,
+			recipe_reviews.review,
+			recipe_reviews.created_at,
+			recipe_reviews.updated_at
+		FROM recipe_reviews
+			LEFT JOIN profiles ON profiles.account_id = recipe_reviews.account_id
+		WHERE recipe_reviews.list_id = $1
+		ORDER BY recipe_reviews.updated_at DESC
+	`, listID)
+}
+
+// SetRecipeReview creates or replaces the account's review of the recipe
+func (r *Repository) SetRecipeReview(ctx context.Context, listID, accountID uuid.UUID, rating int, review string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO recipe_reviews (list_id, account_id, rating, review)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (list_id, account_id) DO UPDATE
+		SET rating = EXCLUDED.rating, review = EXCLUDED.review, updated_at = NOW()
+	`, listID, accountID, rating, review)
+	return err
+}
+
+func (r *Repository) DeleteRecipeReview(ctx context.Context, listID, accountID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM recipe_reviews WHERE list_id = $1 AND account_id = $2`, listID, accountID)
+	return err
+}
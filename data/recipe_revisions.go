@@ -0,0 +1,229 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// RecipeSnapshot is the state of a recipe's fields and ingredients recorded by a revision
+type RecipeSnapshot struct {
+	Name            string                     `json:"name"`
+	Description     string                     `json:"description"`
+	InstructionType string                     `json:"instructionType"`
+	Instructions    string                     `json:"instructions"`
+	Visibility      string                     `json:"visibility"`
+	Ingredients     []RecipeSnapshotIngredient `json:"ingredients"` // sorted by product ID
+	Steps           []RecipeStep               `json:"steps,omitempty"`
+}
+
+type RecipeSnapshotIngredient struct {
+	ProductID    string   `json:"productID" db:"product_id"`
+	Quantity     int      `json:"quantity" db:"quantity"`
+	Staple       bool     `json:"staple" db:"staple"`
+	Amount       float64  `json:"amount,omitempty" db:"amount"`
+	Unit         string   `json:"unit,omitempty" db:"unit"`
+	Note         string   `json:"note,omitempty" db:"note"`
+	DisplayName  string   `json:"displayName,omitempty" db:"display_name"`
+	Section      string   `json:"section,omitempty" db:"section"`
+	Position     int      `json:"position,omitempty" db:"position"`
+	Alternatives []string `json:"alternatives,omitempty" db:"-"`
+}
+
+func (i RecipeSnapshotIngredient) Equal(other RecipeSnapshotIngredient) bool {
+	return i.ProductID == other.ProductID &&
+		i.Quantity == other.Quantity &&
+		i.Staple == other.Staple &&
+		i.Amount == other.Amount &&
+		i.Unit == other.Unit &&
+		i.Note == other.Note &&
+		i.DisplayName == other.DisplayName &&
+		i.Section == other.Section &&
+		i.Position == other.Position &&
+		slices.Equal(i.Alternatives, other.Alternatives)
+}
+
+func (s RecipeSnapshot) Equal(other RecipeSnapshot) bool {
+	return s.Name == other.Name &&
+		s.Description == other.Description &&
+		s.InstructionType == other.InstructionType &&
+		s.Instructions == other.Instructions &&
+		s.Visibility == other.Visibility &&
+		slices.EqualFunc(s.Ingredients, other.Ingredients, RecipeSnapshotIngredient.Equal) &&
+		slices.EqualFunc(s.Steps, other.Steps, RecipeStep.Equal)
+}
+
+func (s RecipeSnapshot) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+func (s *RecipeSnapshot) Scan(src any) error {
+	switch src := src.(type) {
+	case []byte:
+		return json.Unmarshal(src, s)
+	case string:
+		return json.Unmarshal([]byte(src), s)
+	default:
+		return fmt.Errorf("scanning recipe snapshot: unsupported type %T", src)
+	}
+}
+
+type RecipeRevision struct {
+	ID           uuid.UUID      `db:"id"`
+	ListID       uuid.UUID      `db:"list_id"`
+	Number       int            `db:"number"`
+	Snapshot     RecipeSnapshot `db:"snapshot"`
+	RestoredFrom *int           `db:"restored_from"`
+	CreatedAt    time.Time      `db:"created_at"`
+}
+
+func getRecipeSnapshot(ctx context.Context, tx *sqlx.Tx, listID uuid.UUID) (RecipeSnapshot, error) {
+	var snapshot RecipeSnapshot
+	if err := tx.QueryRowContext(ctx, `
+		SELECT name, coalesce(description, ''), instruction_type, instructions, visibility
+		FROM recipe_list_view
+		WHERE list_id = $1
+	`, listID).Scan(&snapshot.Name, &snapshot.Description, &snapshot.InstructionType, &snapshot.Instructions, &snapshot.Visibility); err != nil {
+		return RecipeSnapshot{}, err
+	}
+
+	snapshot.Ingredients = []RecipeSnapshotIngredient{}
+	if err := tx.SelectContext(ctx, &snapshot.Ingredients, `
+		SELECT product_id, quantity, staple, amount, unit, note, display_name, section, position
+		FROM ingredients WHERE list_id = $1 ORDER BY product_id
+	`, listID); err != nil {
+		return RecipeSnapshot{}, err
+	}
+	alternatives, err := listIngredientAlternatives(ctx, tx, listID)
+	if err != nil {
+		return RecipeSnapshot{}, err
+	}
+	for i := range snapshot.Ingredients {
+		snapshot.Ingredients[i].Alternatives = alternatives[snapshot.Ingredients[i].ProductID]
+	}
+
+	steps, err := listRecipeSteps(ctx, tx, listID)
+	if err != nil {
+		return RecipeSnapshot{}, err
+	}
+	snapshot.Steps = steps
+	return snapshot, nil
+}
+
+// recordRecipeRevision adds a revision with the recipe's current state unless it matches the latest revision.
+// Lists that aren't recipes have no revisions.
+func recordRecipeRevision(ctx context.Context, tx *sqlx.Tx, listID uuid.UUID, restoredFrom *int) error {
+	// Lock the recipe so concurrent changes number their revisions in order
+	if _, err := tx.ExecContext(ctx, `SELECT list_id FROM recipes WHERE list_id = $1 FOR UPDATE`, listID); err != nil {
+		return err
+	}
+
+	snapshot, err := getRecipeSnapshot(ctx, tx, listID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var latest RecipeRevision
+	err = tx.GetContext(ctx, &latest, `SELECT * FROM recipe_revisions WHERE list_id = $1 ORDER BY number DESC LIMIT 1`, listID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	} else if err == nil && restoredFrom == nil && latest.Snapshot.Equal(snapshot) {
+		return nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO recipe_revisions (list_id, number, snapshot, restored_from)
+		VALUES ($1, $2, $3, $4)
+	`, listID, latest.Number+1, snapshot, restoredFrom)
+	return err
+}
+
+// ListRecipeRevisions returns the revisions of the recipe, newest first
+func (r *Repository) ListRecipeRevisions(ctx context.Context, listID uuid.UUID) ([]RecipeRevision, error) {
+	var revisions = []RecipeRevision{}
+	return revisions, r.db.SelectContext(ctx, &revisions, `SELECT * FROM recipe_revisions WHERE list_id = $1 ORDER BY number DESC`, listID)
+}
+
+func (r *Repository) GetRecipeRevision(ctx context.Context, listID uuid.UUID, number int) (RecipeRevision, error) {
+	var revision RecipeRevision
+	return revision, r.db.GetContext(ctx, &revision, `SELECT * FROM recipe_revisions WHERE list_id = $1 AND number = $2`, listID, number)
+}
+
+// RestoreRecipeRevision sets the recipe's fields and ingredients to those of the revision, recording the restore as a new revision
+func (r *Repository) RestoreRecipeRevision(ctx context.Context, listID uuid.UUID, number int) (retErr error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer Rollback(tx, &retErr)
+
+	var revision RecipeRevision
+	if err := tx.GetContext(ctx, &revision, `SELECT * FROM recipe_revisions WHERE list_id = $1 AND number = $2`, listID, number); err != nil {
+		return err
+	}
+	snapshot := revision.Snapshot
+
+	if _, err := tx.ExecContext(ctx, `UPDATE lists SET name = $1, description = $2 WHERE id = $3`, snapshot.Name, snapshot.Description, listID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE recipes SET instruction_type = $1, instructions = $2, visibility = $3 WHERE list_id = $4
+	`, snapshot.InstructionType, snapshot.Instructions, snapshot.Visibility, listID); err != nil {
+		return err
+	}
+
+	// Revisions from before steps were added only have the text of their instructions
+	steps := snapshot.Steps
+	if steps == nil {
+		steps = RecipeStepsFromText(snapshot.Instructions)
+	}
+	if err := setRecipeSteps(ctx, tx, listID, snapshot.InstructionType, steps); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM ingredient_alternatives WHERE list_id = $1`, listID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM ingredients WHERE list_id = $1`, listID); err != nil {
+		return err
+	}
+	for _, ingredient := range snapshot.Ingredients {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO ingredients (`+ingredientColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`, ingredient.ProductID, listID, ingredient.Quantity, ingredient.Staple, ingredient.Amount, ingredient.Unit,
+			ingredient.Note, ingredient.DisplayName, ingredient.Section, ingredient.Position); err != nil {
+			return err
+		}
+		if err := setIngredientAlternatives(ctx, tx, listID, ingredient.ProductID, ingredient.Alternatives); err != nil {
+			return err
+		}
+	}
+
+	if err := recordRecipeRevision(ctx, tx, listID, &number); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RecipeSnapshotProductIDs returns the product IDs of the snapshots' ingredients without duplicates
+func RecipeSnapshotProductIDs(snapshots ...RecipeSnapshot) []string {
+	productIDs := []string{}
+	for _, snapshot := range snapshots {
+		for _, ingredient := range snapshot.Ingredients {
+			if !slices.Contains(productIDs, ingredient.ProductID) {
+				productIDs = append(productIDs, ingredient.ProductID)
+			}
+		}
+	}
+	return productIDs
+}
@@ -0,0 +1,112 @@
+package data
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const RecipeStepMaxLength = 2048
+
+// RecipeStep is one step of a recipe's text instructions
+type RecipeStep struct {
+	Number       int      `json:"number" db:"number"` // starts at 1
+	Text         string   `json:"text" db:"text"`
+	TimerSeconds int      `json:"timerSeconds,omitempty" db:"timer_seconds"` // 0 if the step has no timer
+	ProductIDs   []string `json:"productIDs,omitempty" db:"-"`               // the recipe's ingredients used by the step
+}
+
+func (s RecipeStep) Equal(other RecipeStep) bool {
+	return s.Number == other.Number &&
+		s.Text == other.Text &&
+		s.TimerSeconds == other.TimerSeconds &&
+		slices.Equal(s.ProductIDs, other.ProductIDs)
+}
+
+// RecipeStepsFromText makes a step of each non-blank line, the way text instructions were split into steps
+func RecipeStepsFromText(text string) []RecipeStep {
+	steps := []RecipeStep{}
+	for line := range strings.Lines(text) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		steps = append(steps, RecipeStep{Number: len(steps) + 1, Text: line})
+	}
+	return steps
+}
+
+// JoinRecipeSteps is the text instructions of the steps, kept in the recipe's instructions for searching
+func JoinRecipeSteps(steps []RecipeStep) string {
+	texts := []string{}
+	for _, step := range steps {
+		texts = append(texts, step.Text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+func (r *Repository) ListRecipeSteps(ctx context.Context, listID uuid.UUID) ([]RecipeStep, error) {
+	return listRecipeSteps(ctx, r.db, listID)
+}
+
+func listRecipeSteps(ctx context.Context, q sqlx.QueryerContext, listID uuid.UUID) ([]RecipeStep, error) {
+	steps := []RecipeStep{}
+	if err := sqlx.SelectContext(ctx, q, &steps, `
+		SELECT number, text, timer_seconds FROM recipe_steps WHERE list_id = $1 ORDER BY number
+	`, listID); err != nil {
+		return nil, err
+	}
+
+	var stepIngredients []struct {
+		Number    int    `db:"number"`
+		ProductID string `db:"product_id"`
+	}
+	if err := sqlx.SelectContext(ctx, q, &stepIngredients, `
+		SELECT number, product_id FROM recipe_step_ingredients WHERE list_id = $1 ORDER BY number, product_id
+	`, listID); err != nil {
+		return nil, err
+	}
+
+	for _, stepIngredient := range stepIngredients {
+		i := slices.IndexFunc(steps, func(step RecipeStep) bool { return step.Number == stepIngredient.Number })
+		if i >= 0 {
+			steps[i].ProductIDs = append(steps[i].ProductIDs, stepIngredient.ProductID)
+		}
+	}
+	return steps, nil
+}
+
+// setRecipeSteps replaces the recipe's steps, numbering them in order, and updates its instructions to match.
+// Only text instructions have steps.
+func setRecipeSteps(ctx context.Context, tx *sqlx.Tx, listID uuid.UUID, instructionType string, steps []RecipeStep) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recipe_step_ingredients WHERE list_id = $1`, listID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recipe_steps WHERE list_id = $1`, listID); err != nil {
+		return err
+	}
+	if instructionType != InstructionTypeText {
+		return nil
+	}
+
+	for i, step := range steps {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO recipe_steps (list_id, number, text, timer_seconds) VALUES ($1, $2, $3, $4)
+		`, listID, i+1, step.Text, step.TimerSeconds); err != nil {
+			return err
+		}
+		for _, productID := range step.ProductIDs {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO recipe_step_ingredients (list_id, number, product_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING
+			`, listID, i+1, productID); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := tx.ExecContext(ctx, `UPDATE recipes SET instructions = $1 WHERE list_id = $2`, JoinRecipeSteps(steps), listID)
+	return err
+}
@@ -3,9 +3,12 @@ package data
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
+	"unicode"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 )
 
 const (
@@ -21,23 +24,63 @@ const (
 )
 
 type Recipe struct {
-	ListID          uuid.UUID `db:"list_id"`
-	AccountID       uuid.UUID `db:"account_id"`
-	Name            string    `db:"name"`
-	Description     string    `db:"description"`
-	InstructionType string    `db:"instruction_type"`
-	Instructions    string    `db:"instructions"`
-	Visibility      string    `db:"visibility"`
-	Favorite        bool      `db:"favorite"`
+	ListID          uuid.UUID    `db:"list_id"`
+	AccountID       uuid.UUID    `db:"account_id"`
+	Name            string       `db:"name"`
+	Description     string       `db:"description"`
+	InstructionType string       `db:"instruction_type"`
+	Instructions    string       `db:"instructions"`
+	Visibility      string       `db:"visibility"`
+	PhotoID         *uuid.UUID   `db:"photo_id"` // nil if the recipe has no photo
+	Favorite        bool         `db:"favorite"`
+	Tags            []string     `db:"-"`
+	Steps           []RecipeStep `db:"-"`      // only loaded by GetRecipe
+	Rating          float64      `db:"rating"` // average of the reviews, 0 if there are none
+	ReviewCount     int          `db:"review_count"`
+	// Set on recipes forked from another recipe, the source's name and owner are nil when it isn't visible
+	SourceListID         *uuid.UUID `db:"source_list_id"`
+	SourceRevision       *int       `db:"source_revision"` // the source's revision last synced by the fork
+	SourceName           *string    `db:"source_name"`
+	SourceOwnerName      *string    `db:"source_owner_name"`
+	SourceLatestRevision *int       `db:"source_latest_revision"`
+	ForkCount            int        `db:"fork_count"`
+	// Ingredient lines of an imported recipe that haven't been matched to products yet
+	ImportIngredientCount int `db:"import_ingredient_count"`
+	// Only set by ListRecipes when searching with ListRecipesFilterBySearch
+	SearchRank    float64 `db:"search_rank"`
+	SearchSnippet string  `db:"search_snippet"`
 }
 
+// SearchSnippetStart and SearchSnippetStop surround the matched words of a search snippet
+const (
+	SearchSnippetStart = "\x02"
+	SearchSnippetStop  = "\x03"
+)
+
+const recipeColumns = `
+	recipes.list_id,
+	recipes.account_id,
+	recipes.name,
+	recipes.description,
+	recipes.instruction_type,
+	recipes.instructions,
+	recipes.visibility,
+	recipes.photo_id
+`
+
 func (r *Repository) GetRecipe(ctx context.Context, listID uuid.UUID, accountID uuid.UUID) (Recipe, error) {
 	namedQuery, err := r.db.PrepareNamedContext(ctx, `
 		SELECT
-			recipes.*,
+			`+recipeColumns+`,
+			`+recipeRatingsColumns+`,
+			`+recipeSourceColumns+`,
+			`+recipeImportColumns+`,
 			favorites.account_id IS NOT NULL as favorite
 		FROM recipe_list_view AS recipes
 			LEFT JOIN favorites ON favorites.list_id = recipes.list_id AND favorites.account_id = :accountID
+			`+recipeRatingsJoin+`
+			`+recipeSourceJoin+`
+			`+recipeImportJoin+`
 		WHERE recipes.list_id = :listID
 	`)
 	if err != nil {
@@ -46,10 +89,24 @@ func (r *Repository) GetRecipe(ctx context.Context, listID uuid.UUID, accountID
 	defer namedQuery.Close()
 
 	var recipe Recipe
-	return recipe, namedQuery.GetContext(ctx, &recipe, map[string]any{
+	if err := namedQuery.GetContext(ctx, &recipe, map[string]any{
 		"listID":    listID,
 		"accountID": accountID,
-	})
+	}); err != nil {
+		return Recipe{}, err
+	}
+
+	recipes := []Recipe{recipe}
+	if err := r.loadRecipeTags(ctx, recipes); err != nil {
+		return Recipe{}, err
+	}
+	recipe = recipes[0]
+
+	recipe.Steps, err = r.ListRecipeSteps(ctx, listID)
+	if err != nil {
+		return Recipe{}, err
+	}
+	return recipe, nil
 }
 
 type ListRecipesFilter interface {
@@ -71,7 +128,59 @@ type ListRecipesFilterByName struct {
 
 func (f ListRecipesFilterByName) listRecipoesFilter(args map[string]any) string {
 	args["recipeName"] = fmt.Sprintf("%%%s%%", f.Name)
-	return `name ILIKE :recipeName`
+	return `recipes.name ILIKE :recipeName`
+}
+
+// ListRecipesFilterBySearch matches recipes whose name, description or text instructions contain words
+// starting with each of the search terms, and adds the search rank and snippet columns
+type ListRecipesFilterBySearch struct {
+	Search string
+}
+
+// searchTSQuery converts the search into a prefix query of its words joined by the operator, since searches happen as the user types
+func searchTSQuery(search, operator string) string {
+	terms := []string{}
+	for _, word := range strings.FieldsFunc(search, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		terms = append(terms, word+":*")
+	}
+	return strings.Join(terms, " "+operator+" ")
+}
+
+func (f ListRecipesFilterBySearch) listRecipoesFilter(args map[string]any) string {
+	query := searchTSQuery(f.Search, "&")
+	if query == "" {
+		return `true`
+	}
+	args["recipeSearch"] = query
+	args["recipeSearchAny"] = searchTSQuery(f.Search, "|")
+	// The indexed halves of the search vector narrow the recipes to those matching any of the words,
+	// then every word must be in the view's combined vector, since they can be split between the name and instructions
+	return `recipes.list_id IN (
+		SELECT id FROM lists WHERE search_vector @@ to_tsquery('english', :recipeSearchAny)
+		UNION
+		SELECT list_id FROM recipes WHERE search_vector @@ to_tsquery('english', :recipeSearchAny)
+	) AND recipes.search_vector @@ to_tsquery('english', :recipeSearch)`
+}
+
+func (f ListRecipesFilterBySearch) listRecipesColumns(args map[string]any) string {
+	args["recipeSearch"] = searchTSQuery(f.Search, "&")
+	args["recipeSearchHeadline"] = fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxWords=20, MinWords=8, MaxFragments=2`, SearchSnippetStart, SearchSnippetStop)
+	return `
+		ts_rank(recipes.search_vector, to_tsquery('english', :recipeSearch)) AS search_rank,
+		ts_headline(
+			'english',
+			coalesce(recipes.description, '') || ' ' || CASE WHEN recipes.instruction_type = 'text' THEN recipes.instructions ELSE '' END,
+			to_tsquery('english', :recipeSearch),
+			:recipeSearchHeadline
+		) AS search_snippet
+	`
+}
+
+// listRecipesColumnsFilter is implemented by filters that compute columns of the listed recipes
+type listRecipesColumnsFilter interface {
+	listRecipesColumns(args map[string]any) string
 }
 
 type ListRecipesFilterByFavorites struct{}
@@ -89,22 +198,69 @@ func (f ListRecipesFilterByVisibilities) listRecipoesFilter(args map[string]any)
 		return `false`
 	}
 	args["recipeVisibilities"] = f.Visibilities
-	return `visibility = ANY(:recipeVisibilities)`
+	return `recipes.visibility = ANY(:recipeVisibilities)`
+}
+
+// ListRecipesFilterByTags matches recipes with all of the tags, or any of them when All is false
+type ListRecipesFilterByTags struct {
+	Tags []string
+	All  bool
+}
+
+func (f ListRecipesFilterByTags) listRecipoesFilter(args map[string]any) string {
+	if len(f.Tags) == 0 {
+		return `true`
+	}
+	tags := []string{}
+	for _, tag := range f.Tags {
+		tags = append(tags, NormalizeTagName(tag))
+	}
+	args["recipeTags"] = tags
+	query := `recipes.list_id IN (
+		SELECT recipe_tags.list_id FROM recipe_tags
+			INNER JOIN tags ON tags.id = recipe_tags.tag_id
+		WHERE tags.name = ANY(:recipeTags)`
+	if f.All {
+		args["recipeTagCount"] = len(tags)
+		query += ` GROUP BY recipe_tags.list_id HAVING COUNT(DISTINCT tags.id) = :recipeTagCount`
+	}
+	return query + `)`
+}
+
+// ListRecipesFilterByProducts matches recipes with ingredients of all of the products, or any of them when All is false
+type ListRecipesFilterByProducts struct {
+	ProductIDs []string
+	All        bool
 }
 
+func (f ListRecipesFilterByProducts) listRecipoesFilter(args map[string]any) string {
+	if len(f.ProductIDs) == 0 {
+		return `true`
+	}
+	// Repeated products would otherwise never all be counted
+	productIDs := slices.Compact(slices.Sorted(slices.Values(f.ProductIDs)))
+	args["recipeProductIDs"] = productIDs
+	query := `recipes.list_id IN (
+		SELECT ingredients.list_id FROM ingredients
+		WHERE ingredients.product_id = ANY(:recipeProductIDs)`
+	if f.All {
+		args["recipeProductCount"] = len(productIDs)
+		query += ` GROUP BY ingredients.list_id HAVING COUNT(DISTINCT ingredients.product_id) = :recipeProductCount`
+	}
+	return query + `)`
+}
+
+// ListRecipesOrderBy sorts by a column of the listing, such as name, rating, review_count,
+// or search_rank when searching with ListRecipesFilterBySearch
 type ListRecipesOrderBy struct {
 	Field     string
 	Direction string
 }
 
-func (r *Repository) ListRecipes(ctx context.Context, accountID uuid.UUID, filters []ListRecipesFilter, orderBys []ListRecipesOrderBy) ([]Recipe, error) {
+// listRecipesWhere builds the WHERE clause shared by recipe listings, which join favorites for the account
+func listRecipesWhere(accountID uuid.UUID, filters []ListRecipesFilter) (string, map[string]any) {
 	query := `
-		SELECT
-			recipes.*,
-			favorites.account_id IS NOT NULL as favorite
-		FROM recipe_list_view AS recipes
-			LEFT JOIN favorites ON favorites.list_id = recipes.list_id AND favorites.account_id = :accountID
-		WHERE (recipes.account_id = :accountID OR visibility = 'public')
+		WHERE (recipes.account_id = :accountID OR recipes.visibility = 'public')
 	`
 	namedArgs := map[string]any{"accountID": accountID}
 	if len(filters) > 0 {
@@ -115,6 +271,22 @@ func (r *Repository) ListRecipes(ctx context.Context, accountID uuid.UUID, filte
 		}
 		query += " AND " + strings.Join(filterStrings, " AND ")
 	}
+	return query, namedArgs
+}
+
+func (r *Repository) ListRecipes(ctx context.Context, accountID uuid.UUID, filters []ListRecipesFilter, orderBys []ListRecipesOrderBy) ([]Recipe, error) {
+	where, namedArgs := listRecipesWhere(accountID, filters)
+	columns := []string{recipeColumns, recipeRatingsColumns, recipeSourceColumns, recipeImportColumns, `favorites.account_id IS NOT NULL as favorite`}
+	for _, filter := range filters {
+		if columnsFilter, ok := filter.(listRecipesColumnsFilter); ok {
+			columns = append(columns, columnsFilter.listRecipesColumns(namedArgs))
+		}
+	}
+	query := `
+		SELECT ` + strings.Join(columns, ",") + `
+		FROM recipe_list_view AS recipes
+			LEFT JOIN favorites ON favorites.list_id = recipes.list_id AND favorites.account_id = :accountID
+	` + recipeRatingsJoin + recipeSourceJoin + recipeImportJoin + where
 	if len(orderBys) > 0 {
 		query += " ORDER BY "
 		orderStrings := []string{}
@@ -131,10 +303,14 @@ func (r *Repository) ListRecipes(ctx context.Context, accountID uuid.UUID, filte
 	defer namedQuery.Close()
 
 	var recipes = []Recipe{}
-	return recipes, namedQuery.Select(&recipes, namedArgs)
+	if err := namedQuery.Select(&recipes, namedArgs); err != nil {
+		return nil, err
+	}
+	return recipes, r.loadRecipeTags(ctx, recipes)
 }
 
-func (r *Repository) CreateRecipe(ctx context.Context, accountID uuid.UUID, name, description, instructionType, instructions, visibility string) (listID uuid.UUID, retErr error) {
+// CreateRecipe splits text instructions into steps when steps is nil
+func (r *Repository) CreateRecipe(ctx context.Context, accountID uuid.UUID, name, description, instructionType, instructions, visibility string, steps []RecipeStep) (listID uuid.UUID, retErr error) {
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return uuid.Nil, err
@@ -146,6 +322,19 @@ func (r *Repository) CreateRecipe(ctx context.Context, accountID uuid.UUID, name
 		return uuid.Nil, err
 	}
 
+	if err := createRecipe(ctx, tx, listID, instructionType, instructions, visibility, steps); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := recordRecipeRevision(ctx, tx, listID, nil); err != nil {
+		return uuid.Nil, err
+	}
+
+	return listID, tx.Commit()
+}
+
+// createRecipe adds the recipe to a created list, splitting text instructions into steps when steps is nil
+func createRecipe(ctx context.Context, tx *sqlx.Tx, listID uuid.UUID, instructionType, instructions, visibility string, steps []RecipeStep) error {
 	namedQuery, err := tx.PrepareNamedContext(ctx, `
 		INSERT INTO recipes(
 		    list_id,
@@ -160,22 +349,25 @@ func (r *Repository) CreateRecipe(ctx context.Context, accountID uuid.UUID, name
 		)
 	`)
 	if err != nil {
-		return uuid.Nil, err
+		return err
 	}
 
-	var id uuid.UUID
 	if _, err := namedQuery.ExecContext(ctx, map[string]any{
 		"listID":          listID,
 		"instructionType": instructionType,
 		"instructions":    instructions,
 		"visibility":      visibility,
 	}); err != nil {
-		return uuid.Nil, err
+		return err
 	}
 
-	return id, tx.Commit()
+	if steps == nil {
+		steps = RecipeStepsFromText(instructions)
+	}
+	return setRecipeSteps(ctx, tx, listID, instructionType, steps)
 }
 
+// UpdateRecipe splits text instructions into steps when the recipe's steps are nil
 func (r *Repository) UpdateRecipe(ctx context.Context, recipe Recipe) (retErr error) {
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -192,7 +384,7 @@ func (r *Repository) UpdateRecipe(ctx context.Context, recipe Recipe) (retErr er
 		return err
 	}
 
-	namedQuery, err := r.db.PrepareNamedContext(ctx, `
+	namedQuery, err := tx.PrepareNamedContext(ctx, `
 		UPDATE recipes
 		SET instruction_type = :instructionType, instructions = :instructions, visibility = :visibility
 		WHERE list_id = :listID
@@ -210,6 +402,18 @@ func (r *Repository) UpdateRecipe(ctx context.Context, recipe Recipe) (retErr er
 		return err
 	}
 
+	steps := recipe.Steps
+	if steps == nil {
+		steps = RecipeStepsFromText(recipe.Instructions)
+	}
+	if err := setRecipeSteps(ctx, tx, recipe.ListID, recipe.InstructionType, steps); err != nil {
+		return err
+	}
+
+	if err := recordRecipeRevision(ctx, tx, recipe.ListID, nil); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
@@ -233,9 +437,36 @@ func (m *Repository) DeleteRecipe(ctx context.Context, listID uuid.UUID) (retErr
 	if _, err := tx.ExecContext(ctx, `DELETE FROM favorites WHERE list_id = $1`, listID); err != nil {
 		return err
 	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recipe_revisions WHERE list_id = $1`, listID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recipe_reviews WHERE list_id = $1`, listID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recipe_tags WHERE list_id = $1`, listID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recipe_step_ingredients WHERE list_id = $1`, listID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recipe_steps WHERE list_id = $1`, listID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recipe_import_ingredients WHERE list_id = $1`, listID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM ingredient_alternatives WHERE list_id = $1`, listID); err != nil {
+		return err
+	}
 	if _, err := tx.ExecContext(ctx, `DELETE FROM ingredients where list_id = $1`, listID); err != nil {
 		return err
 	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM list_schedule_runs WHERE list_id = $1`, listID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM list_schedules WHERE list_id = $1`, listID); err != nil {
+		return err
+	}
 	if _, err := tx.ExecContext(ctx, `DELETE FROM recipes WHERE list_id = $1`, listID); err != nil {
 		return err
 	}
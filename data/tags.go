@@ -0,0 +1,163 @@
+package data
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	TagCategoryCuisine = "cuisine"
+	TagCategoryMeal    = "meal"
+	TagCategoryDiet    = "diet"
+)
+
+const (
+	TagNameMaxLength = 64
+	// RecipeTagsMax is the most tags a recipe can have
+	RecipeTagsMax = 20
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type Tag struct {
+	ID       uuid.UUID `db:"id"`
+	Name     string    `db:"name"`
+	Category *string   `db:"category"` // nil for free-form tags
+}
+
+type TagFacet struct {
+	Tag
+	Count int `db:"count"`
+}
+
+// NormalizeTagName lowercases the name and collapses whitespace so equivalent tags share a row
+func NormalizeTagName(name string) string {
+	name = strings.Join(strings.Fields(strings.ToLower(name)), " ")
+	if runes := []rune(name); len(runes) > TagNameMaxLength {
+		name = strings.TrimSpace(string(runes[:TagNameMaxLength]))
+	}
+	return name
+}
+
+// ListTags returns the tags starting with the prefix, curated tags first
+func (r *Repository) ListTags(ctx context.Context, prefix string, limit int) ([]Tag, error) {
+	var tags = []Tag{}
+	return tags, r.db.SelectContext(ctx, &tags, `
+		SELECT * FROM tags
+		WHERE name LIKE $1
+		ORDER BY category IS NULL, name
+		LIMIT $2
+	`, likeEscaper.Replace(NormalizeTagName(prefix))+"%", limit)
+}
+
+func (r *Repository) ListCuratedTags(ctx context.Context) ([]Tag, error) {
+	var tags = []Tag{}
+	return tags, r.db.SelectContext(ctx, &tags, `SELECT * FROM tags WHERE category IS NOT NULL ORDER BY category, name`)
+}
+
+func (r *Repository) ListRecipeTags(ctx context.Context, listID uuid.UUID) ([]Tag, error) {
+	var tags = []Tag{}
+	return tags, r.db.SelectContext(ctx, &tags, `
+		SELECT tags.* FROM tags
+			INNER JOIN recipe_tags ON recipe_tags.tag_id = tags.id
+		WHERE recipe_tags.list_id = $1
+		ORDER BY tags.category IS NULL, tags.category, tags.name
+	`, listID)
+}
+
+// SetRecipeTags replaces the recipe's tags, creating free-form tags that don't exist yet
+func (r *Repository) SetRecipeTags(ctx context.Context, listID uuid.UUID, names []string) (retErr error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer Rollback(tx, &retErr)
+
+	if err := setRecipeTags(ctx, tx, listID, names); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func setRecipeTags(ctx context.Context, tx *sqlx.Tx, listID uuid.UUID, names []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recipe_tags WHERE list_id = $1`, listID); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		name = NormalizeTagName(name)
+		if name == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`, name); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO recipe_tags (list_id, tag_id)
+			SELECT $1, id FROM tags WHERE name = $2
+			ON CONFLICT DO NOTHING
+		`, listID, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListRecipeTagFacets counts the tags of the recipes that ListRecipes would return for the same filters
+func (r *Repository) ListRecipeTagFacets(ctx context.Context, accountID uuid.UUID, filters []ListRecipesFilter) ([]TagFacet, error) {
+	where, namedArgs := listRecipesWhere(accountID, filters)
+	namedQuery, err := r.db.PrepareNamedContext(ctx, `
+		SELECT tags.*, COUNT(*) AS count
+		FROM recipe_list_view AS recipes
+			LEFT JOIN favorites ON favorites.list_id = recipes.list_id AND favorites.account_id = :accountID
+			INNER JOIN recipe_tags ON recipe_tags.list_id = recipes.list_id
+			INNER JOIN tags ON tags.id = recipe_tags.tag_id
+	`+where+`
+		GROUP BY tags.id
+		ORDER BY tags.category IS NULL, tags.category, count DESC, tags.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer namedQuery.Close()
+
+	var facets = []TagFacet{}
+	return facets, namedQuery.SelectContext(ctx, &facets, namedArgs)
+}
+
+// loadRecipeTags fills in the tag names of each recipe
+func (r *Repository) loadRecipeTags(ctx context.Context, recipes []Recipe) error {
+	if len(recipes) == 0 {
+		return nil
+	}
+
+	listIDs := []uuid.UUID{}
+	for _, recipe := range recipes {
+		listIDs = append(listIDs, recipe.ListID)
+	}
+
+	var recipeTags []struct {
+		ListID uuid.UUID `db:"list_id"`
+		Name   string    `db:"name"`
+	}
+	if err := r.db.SelectContext(ctx, &recipeTags, `
+		SELECT recipe_tags.list_id, tags.name FROM recipe_tags
+			INNER JOIN tags ON tags.id = recipe_tags.tag_id
+		WHERE recipe_tags.list_id = ANY($1)
+		ORDER BY tags.category IS NULL, tags.category, tags.name
+	`, listIDs); err != nil {
+		return err
+	}
+
+	tagsByListID := map[uuid.UUID][]string{}
+	for _, recipeTag := range recipeTags {
+		tagsByListID[recipeTag.ListID] = append(tagsByListID[recipeTag.ListID], recipeTag.Name)
+	}
+	for i := range recipes {
+		recipes[i].Tags = tagsByListID[recipes[i].ListID]
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+)
+
+// lockFraction of the job's interval is how long its lock is held, so instances that
+// tick slightly apart still only run the job once per interval
+const lockFraction = 0.9
+
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Runner runs registered jobs on their intervals. Each run takes a Redis lock,
+// so when several instances run the same jobs only one of them runs each job.
+type Runner struct {
+	logger   *slog.Logger
+	repo     *data.Repository
+	cache    *data.Cache
+	instance string
+	jobs     []Job
+}
+
+func NewRunner(logger *slog.Logger, repo *data.Repository, cache *data.Cache) *Runner {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return &Runner{
+		logger:   logger,
+		repo:     repo,
+		cache:    cache,
+		instance: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+func (r *Runner) Register(job Job) {
+	r.jobs = append(r.jobs, job)
+}
+
+// Run runs the jobs until the context is done
+func (r *Runner) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, job := range r.jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.schedule(ctx, job)
+		}()
+	}
+	wg.Wait()
+}
+
+func (r *Runner) schedule(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+	for {
+		r.runOnce(ctx, job)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Runner) runOnce(ctx context.Context, job Job) {
+	logger := r.logger.With(slog.String("job", job.Name), slog.String("instance", r.instance))
+
+	lockTTL := time.Duration(float64(job.Interval) * lockFraction)
+	acquired, err := r.cache.AcquireLock(ctx, "job:"+job.Name, r.instance, lockTTL)
+	if err != nil {
+		logger.Error("acquiring job lock", slog.String("error", err.Error()))
+		return
+	} else if !acquired {
+		logger.Debug("job locked by another instance")
+		return
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, lockTTL)
+	defer cancel()
+
+	run := data.JobRun{
+		Job:       job.Name,
+		Instance:  r.instance,
+		StartedAt: time.Now(),
+	}
+	runErr := runJob(runCtx, job)
+	run.FinishedAt = time.Now()
+
+	duration := slog.Duration("duration", run.FinishedAt.Sub(run.StartedAt))
+	if runErr != nil {
+		errMsg := runErr.Error()
+		run.Error = &errMsg
+		logger.Error("job run failed", duration, slog.String("error", errMsg))
+	} else {
+		logger.Info("job run", duration)
+	}
+
+	if err := r.repo.CreateJobRun(ctx, run); err != nil {
+		logger.Error("recording job run", slog.String("error", err.Error()))
+	}
+}
+
+// runJob turns a panicking job into a failed run
+func runJob(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+	return job.Run(ctx)
+}
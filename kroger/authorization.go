@@ -63,12 +63,17 @@ type Credentials interface {
 type AuthorizationCode struct {
 	Code        string
 	RedirectURI string
+	// CodeVerifier is the PKCE verifier for the code challenge sent to the authorize endpoint
+	CodeVerifier string
 }
 
 func (c AuthorizationCode) credentials(values url.Values) {
 	values.Add("grant_type", GrantTypeAuthorizationCode)
 	values.Add("code", c.Code)
 	values.Add("redirect_uri", c.RedirectURI)
+	if c.CodeVerifier != "" {
+		values.Add("code_verifier", c.CodeVerifier)
+	}
 }
 
 type ClientCredentials struct {
@@ -197,6 +197,13 @@ type Item struct {
 	SoldBy        string      `json:"soldBy"`
 }
 
+// Stock levels of an item at the requested location
+const (
+	StockLevelHigh                  = "HIGH"
+	StockLevelLow                   = "LOW"
+	StockLevelTemporarilyOutOfStock = "TEMPORARILY_OUT_OF_STOCK"
+)
+
 type Inventory struct {
 	StockLevel string `json:"stockLevel"`
 }
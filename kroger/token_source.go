@@ -0,0 +1,82 @@
+package kroger
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// TokenExpiryDelta refreshes tokens a little early so they don't expire mid request
+const TokenExpiryDelta = time.Minute
+
+// Token is a user's OAuth2 credentials
+type Token struct {
+	AccessToken  string
+	RefreshToken string
+	Expiry       time.Time
+}
+
+func NewToken(resp *PostTokenResponse) Token {
+	return Token{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		Expiry:       time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second),
+	}
+}
+
+func (t Token) Valid() bool {
+	return t.AccessToken != "" && time.Now().Add(TokenExpiryDelta).Before(t.Expiry)
+}
+
+type TokenSource interface {
+	Token(ctx context.Context) (Token, error)
+}
+
+// RefreshTokenSource returns its token until it expires, then refreshes it with the refresh token
+type RefreshTokenSource struct {
+	mu         sync.Mutex
+	authClient *AuthorizationClient
+	token      Token
+	onRefresh  func(context.Context, Token) error
+}
+
+// NewRefreshTokenSource calls onRefresh, if set, with each refreshed token so it can be persisted
+func NewRefreshTokenSource(authClient *AuthorizationClient, token Token, onRefresh func(context.Context, Token) error) *RefreshTokenSource {
+	return &RefreshTokenSource{
+		authClient: authClient,
+		token:      token,
+		onRefresh:  onRefresh,
+	}
+}
+
+func (s *RefreshTokenSource) Token(ctx context.Context) (Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token.Valid() {
+		return s.token, nil
+	}
+	if s.token.RefreshToken == "" {
+		return Token{}, errors.New("token expired and no refresh token is available")
+	}
+
+	resp, err := s.authClient.PostToken(ctx, RefreshToken{
+		RefreshToken: s.token.RefreshToken,
+	})
+	if err != nil {
+		return Token{}, err
+	}
+	token := NewToken(resp)
+	if token.RefreshToken == "" {
+		token.RefreshToken = s.token.RefreshToken
+	}
+
+	if s.onRefresh != nil {
+		if err := s.onRefresh(ctx, token); err != nil {
+			return Token{}, err
+		}
+	}
+	s.token = token
+	return token, nil
+}
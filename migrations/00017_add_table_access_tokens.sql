@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE access_token_scope AS ENUM (
+    'read',
+    'write'
+);
+
+CREATE TABLE IF NOT EXISTS access_tokens (
+    id UUID PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+    account_id UUID NOT NULL REFERENCES accounts(id),
+    name VARCHAR(128) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    scope access_token_scope NOT NULL DEFAULT 'read',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE access_tokens;
+
+DROP TYPE access_token_scope;
+-- +goose StatementEnd
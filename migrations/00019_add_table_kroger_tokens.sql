@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS kroger_tokens (
+    account_id UUID PRIMARY KEY NOT NULL REFERENCES accounts(id),
+    access_token BYTEA NOT NULL,
+    refresh_token BYTEA NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE kroger_tokens;
+-- +goose StatementEnd
@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE list_schedule_frequency AS ENUM (
+    'weekly',
+    'days',
+    'monthly'
+);
+
+CREATE TABLE IF NOT EXISTS list_schedules (
+    list_id UUID PRIMARY KEY NOT NULL REFERENCES lists (id),
+    frequency list_schedule_frequency NOT NULL,
+    weekday SMALLINT CHECK (weekday BETWEEN 0 AND 6),
+    interval_days INTEGER CHECK (interval_days > 0),
+    day_of_month SMALLINT CHECK (day_of_month BETWEEN 1 AND 28),
+    push_to_kroger BOOLEAN NOT NULL DEFAULT FALSE,
+    paused BOOLEAN NOT NULL DEFAULT FALSE,
+    skip_next BOOLEAN NOT NULL DEFAULT FALSE,
+    next_run_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX list_schedules_next_run_at_idx ON list_schedules (next_run_at) WHERE NOT paused;
+
+CREATE TYPE list_schedule_run_status AS ENUM (
+    'added',
+    'pushed',
+    'skipped',
+    'failed'
+);
+
+CREATE TABLE IF NOT EXISTS list_schedule_runs (
+    id UUID PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+    list_id UUID NOT NULL REFERENCES lists (id),
+    ran_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    status list_schedule_run_status NOT NULL,
+    error TEXT
+);
+
+CREATE INDEX list_schedule_runs_list_id_idx ON list_schedule_runs (list_id, ran_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE list_schedule_runs;
+
+DROP TYPE list_schedule_run_status;
+
+DROP TABLE list_schedules;
+
+DROP TYPE list_schedule_frequency;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS job_runs (
+    id UUID PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+    job VARCHAR(128) NOT NULL,
+    instance VARCHAR(256) NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL,
+    error TEXT
+);
+
+CREATE INDEX job_runs_started_at_idx ON job_runs (started_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE job_runs;
+-- +goose StatementEnd
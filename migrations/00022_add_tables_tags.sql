@@ -0,0 +1,63 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE tag_category AS ENUM (
+    'cuisine',
+    'meal',
+    'diet'
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id UUID PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+    name VARCHAR(64) NOT NULL UNIQUE,
+    category tag_category
+);
+
+CREATE TABLE IF NOT EXISTS recipe_tags (
+    list_id UUID NOT NULL REFERENCES lists (id),
+    tag_id UUID NOT NULL REFERENCES tags (id),
+    PRIMARY KEY (list_id, tag_id)
+);
+
+CREATE INDEX recipe_tags_tag_id_idx ON recipe_tags (tag_id);
+
+-- curated tags, free-form tags are created with no category as recipes use them
+INSERT INTO tags (name, category) VALUES
+    ('american', 'cuisine'),
+    ('chinese', 'cuisine'),
+    ('french', 'cuisine'),
+    ('greek', 'cuisine'),
+    ('indian', 'cuisine'),
+    ('italian', 'cuisine'),
+    ('japanese', 'cuisine'),
+    ('korean', 'cuisine'),
+    ('mediterranean', 'cuisine'),
+    ('mexican', 'cuisine'),
+    ('middle eastern', 'cuisine'),
+    ('thai', 'cuisine'),
+    ('vietnamese', 'cuisine'),
+    ('breakfast', 'meal'),
+    ('lunch', 'meal'),
+    ('dinner', 'meal'),
+    ('appetizer', 'meal'),
+    ('side', 'meal'),
+    ('snack', 'meal'),
+    ('dessert', 'meal'),
+    ('drink', 'meal'),
+    ('dairy free', 'diet'),
+    ('gluten free', 'diet'),
+    ('keto', 'diet'),
+    ('low carb', 'diet'),
+    ('nut free', 'diet'),
+    ('paleo', 'diet'),
+    ('vegan', 'diet'),
+    ('vegetarian', 'diet');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE recipe_tags;
+
+DROP TABLE tags;
+
+DROP TYPE tag_category;
+-- +goose StatementEnd
@@ -0,0 +1,64 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- generated columns can't reference other tables, so the list and recipe halves of the search are kept separately
+ALTER TABLE lists
+    ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'B')
+    ) STORED;
+
+CREATE INDEX lists_search_vector_idx ON lists USING GIN (search_vector);
+
+ALTER TABLE recipes
+    ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', CASE WHEN instruction_type = 'text' THEN instructions ELSE '' END), 'C')
+    ) STORED;
+
+CREATE INDEX recipes_search_vector_idx ON recipes USING GIN (search_vector);
+
+CREATE OR REPLACE VIEW recipe_list_view AS
+(
+    SELECT
+        list_id,
+        lists.account_id AS account_id,
+        lists.name,
+        lists.description,
+        instruction_type,
+        instructions,
+        visibility,
+        lists.search_vector || recipes.search_vector AS search_vector
+    FROM recipes
+        INNER JOIN lists ON lists.id = recipes.list_id
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP VIEW recipe_list_view;
+
+CREATE VIEW recipe_list_view AS
+(
+    SELECT
+        list_id,
+        lists.account_id AS account_id,
+        lists.name,
+        lists.description,
+        instruction_type,
+        instructions,
+        visibility
+    FROM recipes
+        INNER JOIN lists ON lists.id = recipes.list_id
+);
+
+DROP INDEX recipes_search_vector_idx;
+
+ALTER TABLE recipes DROP COLUMN search_vector;
+
+DROP INDEX lists_search_vector_idx;
+
+ALTER TABLE lists DROP COLUMN search_vector;
+
+-- +goose StatementEnd
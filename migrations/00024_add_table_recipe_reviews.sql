@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS recipe_reviews (
+    list_id UUID NOT NULL REFERENCES lists (id),
+    account_id UUID NOT NULL REFERENCES accounts (id),
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    review VARCHAR(2048) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (list_id, account_id)
+);
+
+CREATE INDEX recipe_reviews_account_id_idx ON recipe_reviews (account_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE recipe_reviews;
+-- +goose StatementEnd
@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS recipe_revisions (
+    id UUID PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+    list_id UUID NOT NULL REFERENCES lists (id),
+    number INTEGER NOT NULL,
+    snapshot JSONB NOT NULL,
+    restored_from INTEGER,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (list_id, number)
+);
+
+-- record the current state of existing recipes as their first revision
+INSERT INTO recipe_revisions (list_id, number, snapshot)
+SELECT
+    recipes.list_id,
+    1,
+    jsonb_build_object(
+        'name', recipes.name,
+        'description', coalesce(recipes.description, ''),
+        'instructionType', recipes.instruction_type,
+        'instructions', recipes.instructions,
+        'visibility', recipes.visibility,
+        'ingredients', coalesce((
+            SELECT jsonb_agg(jsonb_build_object(
+                'productID', ingredients.product_id,
+                'quantity', ingredients.quantity,
+                'staple', ingredients.staple
+            ) ORDER BY ingredients.product_id)
+            FROM ingredients
+            WHERE ingredients.list_id = recipes.list_id
+        ), '[]'::jsonb)
+    )
+FROM recipe_list_view AS recipes;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE recipe_revisions;
+-- +goose StatementEnd
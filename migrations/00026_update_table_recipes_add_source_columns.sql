@@ -0,0 +1,51 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE recipes
+    ADD COLUMN source_list_id UUID REFERENCES lists (id) ON DELETE SET NULL,
+    ADD COLUMN source_revision INTEGER;
+
+CREATE INDEX recipes_source_list_id_idx ON recipes (source_list_id);
+
+CREATE OR REPLACE VIEW recipe_list_view AS
+(
+    SELECT
+        list_id,
+        lists.account_id AS account_id,
+        lists.name,
+        lists.description,
+        instruction_type,
+        instructions,
+        visibility,
+        lists.search_vector || recipes.search_vector AS search_vector,
+        source_list_id,
+        source_revision
+    FROM recipes
+        INNER JOIN lists ON lists.id = recipes.list_id
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW recipe_list_view;
+
+CREATE VIEW recipe_list_view AS
+(
+    SELECT
+        list_id,
+        lists.account_id AS account_id,
+        lists.name,
+        lists.description,
+        instruction_type,
+        instructions,
+        visibility,
+        lists.search_vector || recipes.search_vector AS search_vector
+    FROM recipes
+        INNER JOIN lists ON lists.id = recipes.list_id
+);
+
+DROP INDEX recipes_source_list_id_idx;
+
+ALTER TABLE recipes
+    DROP COLUMN source_list_id,
+    DROP COLUMN source_revision;
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS recipe_steps (
+    list_id UUID NOT NULL REFERENCES lists (id),
+    number INTEGER NOT NULL CHECK (number > 0),
+    text VARCHAR(2048) NOT NULL,
+    timer_seconds INTEGER NOT NULL DEFAULT 0 CHECK (timer_seconds >= 0),
+    PRIMARY KEY (list_id, number)
+);
+
+CREATE TABLE IF NOT EXISTS recipe_step_ingredients (
+    list_id UUID NOT NULL,
+    number INTEGER NOT NULL,
+    product_id VARCHAR(13) NOT NULL,
+    PRIMARY KEY (list_id, number, product_id),
+    FOREIGN KEY (list_id, number) REFERENCES recipe_steps (list_id, number) ON DELETE CASCADE
+);
+
+-- split existing text instructions into a step per non-blank line
+INSERT INTO recipe_steps (list_id, number, text)
+SELECT
+    recipes.list_id,
+    row_number() OVER (PARTITION BY recipes.list_id ORDER BY lines.ordinality),
+    left(trim(lines.line), 2048)
+FROM recipes
+    CROSS JOIN LATERAL regexp_split_to_table(recipes.instructions, E'\r?\n') WITH ORDINALITY AS lines (line, ordinality)
+WHERE recipes.instruction_type = 'text' AND trim(lines.line) <> '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE recipe_step_ingredients;
+DROP TABLE recipe_steps;
+-- +goose StatementEnd
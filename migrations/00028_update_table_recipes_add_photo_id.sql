@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE recipes ADD COLUMN photo_id UUID;
+
+CREATE OR REPLACE VIEW recipe_list_view AS
+(
+    SELECT
+        list_id,
+        lists.account_id AS account_id,
+        lists.name,
+        lists.description,
+        instruction_type,
+        instructions,
+        visibility,
+        lists.search_vector || recipes.search_vector AS search_vector,
+        source_list_id,
+        source_revision,
+        photo_id
+    FROM recipes
+        INNER JOIN lists ON lists.id = recipes.list_id
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW recipe_list_view;
+
+CREATE VIEW recipe_list_view AS
+(
+    SELECT
+        list_id,
+        lists.account_id AS account_id,
+        lists.name,
+        lists.description,
+        instruction_type,
+        instructions,
+        visibility,
+        lists.search_vector || recipes.search_vector AS search_vector,
+        source_list_id,
+        source_revision
+    FROM recipes
+        INNER JOIN lists ON lists.id = recipes.list_id
+);
+
+ALTER TABLE recipes DROP COLUMN photo_id;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS recipe_import_ingredients (
+    list_id UUID NOT NULL REFERENCES lists (id),
+    number INTEGER NOT NULL CHECK (number > 0),
+    text VARCHAR(512) NOT NULL,
+    PRIMARY KEY (list_id, number)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE recipe_import_ingredients;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE ingredients
+ADD COLUMN IF NOT EXISTS note VARCHAR(256) NOT NULL DEFAULT '',
+ADD COLUMN IF NOT EXISTS display_name VARCHAR(256) NOT NULL DEFAULT '',
+ADD COLUMN IF NOT EXISTS section VARCHAR(64) NOT NULL DEFAULT '',
+ADD COLUMN IF NOT EXISTS position INTEGER NOT NULL DEFAULT 0;
+
+-- Keep the order ingredients were listed in before they had positions
+UPDATE ingredients SET position = ordered.position
+FROM (
+    SELECT list_id, product_id, ROW_NUMBER() OVER (PARTITION BY list_id ORDER BY staple, product_id) - 1 AS position
+    FROM ingredients
+) AS ordered
+WHERE ingredients.list_id = ordered.list_id AND ingredients.product_id = ordered.product_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE ingredients
+DROP COLUMN IF EXISTS note,
+DROP COLUMN IF EXISTS display_name,
+DROP COLUMN IF EXISTS section,
+DROP COLUMN IF EXISTS position;
+-- +goose StatementEnd
@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+-- products that can be bought in place of an ingredient's product, in order of preference after it
+CREATE TABLE IF NOT EXISTS ingredient_alternatives (
+    list_id UUID NOT NULL REFERENCES lists (id),
+    product_id VARCHAR(13) NOT NULL,
+    alternative_product_id VARCHAR(13) NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (list_id, product_id, alternative_product_id)
+);
+
+-- the ingredient's products a cart product was picked from, in order of preference
+CREATE TABLE IF NOT EXISTS cart_product_alternatives (
+    account_id UUID NOT NULL,
+    product_id VARCHAR(13) NOT NULL,
+    alternative_product_id VARCHAR(13) NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (account_id, product_id, alternative_product_id),
+    FOREIGN KEY (account_id, product_id) REFERENCES cart_products (account_id, product_id) ON DELETE CASCADE
+);
+
+CREATE TYPE alternative_choice AS ENUM (
+    'in-stock',
+    'cheapest'
+);
+
+ALTER TABLE accounts
+    ADD COLUMN alternative_choice alternative_choice NOT NULL DEFAULT 'in-stock';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE accounts
+    DROP COLUMN alternative_choice;
+
+DROP TYPE alternative_choice;
+
+DROP TABLE cart_product_alternatives;
+DROP TABLE ingredient_alternatives;
+-- +goose StatementEnd
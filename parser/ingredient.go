@@ -0,0 +1,246 @@
+// Package parser reads free text, such as pasted recipe ingredient lines, into structured values
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Ingredient is an ingredient line split into its amount, item name and notes
+type Ingredient struct {
+	Text        string  // the line as given
+	Quantity    float64 // 0 when the line has no amount, like "salt to taste"
+	MaxQuantity float64 // the upper end of a range like "2-3 cloves", otherwise 0
+	Unit        string  // one of the Unit constants, empty for counted items like "3 eggs"
+	Size        string  // the package size of a line like "2 (14 oz) cans tomatoes"
+	Name        string
+	Notes       string // preparation and other notes, like "chopped" or "to taste"
+}
+
+// amountPattern matches a mixed number, fraction, decimal or whole number
+const amountPattern = `\d+ \d+/\d+|\d+/\d+|\d*\.\d+|\d+`
+
+var (
+	whitespacePattern = regexp.MustCompile(`\s+`)
+	quantityPattern   = regexp.MustCompile(`^(` + amountPattern + `)(?:(?:\s*-\s*|\s+(?:to|or)\s+)(` + amountPattern + `))?`)
+	parenPattern      = regexp.MustCompile(`\s*\(([^)]*)\)`)
+)
+
+var vulgarFractions = map[rune]string{
+	'½': "1/2", '⅓': "1/3", '⅔': "2/3", '¼': "1/4", '¾': "3/4",
+	'⅕': "1/5", '⅖': "2/5", '⅗': "3/5", '⅘': "4/5", '⅙': "1/6",
+	'⅚': "5/6", '⅐': "1/7", '⅛': "1/8", '⅜': "3/8", '⅝': "5/8",
+	'⅞': "7/8", '⅑': "1/9", '⅒': "1/10",
+}
+
+var numberWords = map[string]float64{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12, "half": 0.5,
+}
+
+// preparationWords lead an item name with how it is prepared, like "chopped onion".
+// Words that also name what is bought, like "ground beef", "crushed tomatoes" or "shredded cheese", are left in the name.
+var preparationWords = map[string]bool{
+	"chopped": true, "cubed": true, "halved": true, "quartered": true, "julienned": true,
+	"melted": true, "softened": true, "beaten": true, "drained": true, "rinsed": true,
+	"trimmed": true, "packed": true, "sifted": true, "zested": true, "seeded": true,
+	"cored": true, "torn": true, "room-temperature": true,
+}
+
+// preparationAdverbs describe a preparation word, like "finely chopped"
+var preparationAdverbs = map[string]bool{
+	"finely": true, "roughly": true, "coarsely": true, "thinly": true, "thickly": true,
+	"freshly": true, "lightly": true, "firmly": true, "well": true, "and": true,
+}
+
+// trailingNotes end an item name without a comma, like "salt to taste"
+var trailingNotes = []string{
+	"to taste", "or to taste", "for garnish", "for garnishing", "for serving",
+	"for the pan", "as needed", "if needed", "optional", "divided",
+}
+
+// ParseIngredient splits an ingredient line like "1 1/2 cups chopped onion" into its quantity, unit, name and notes.
+// Unicode fractions, mixed numbers, ranges and package sizes in parentheses are understood.
+// Anything it doesn't recognize is left in the name, so a line never fails to parse.
+func ParseIngredient(line string) Ingredient {
+	ingredient := Ingredient{Text: line}
+	rest := normalize(line)
+
+	rest = ingredient.parseQuantity(rest)
+	rest = ingredient.parseSize(rest)
+	rest = ingredient.parseUnit(rest)
+	rest = ingredient.parseSize(rest)
+
+	var notes []string
+
+	// Leading preparation, like "finely chopped"
+	words := strings.Fields(rest)
+	leading := 0
+	for i, word := range words[:max(len(words)-1, 0)] {
+		word = strings.ToLower(strings.TrimRight(word, ","))
+		if preparationWords[word] {
+			leading = i + 1
+		} else if !preparationAdverbs[word] {
+			break
+		}
+	}
+	if leading > 0 {
+		notes = append(notes, strings.TrimRight(strings.Join(words[:leading], " "), ","))
+		rest = strings.Join(words[leading:], " ")
+	}
+
+	// Parenthesized notes, like "butter (softened)"
+	for _, match := range parenPattern.FindAllStringSubmatch(rest, -1) {
+		if note := strings.TrimSpace(match[1]); note != "" {
+			notes = append(notes, note)
+		}
+	}
+	rest = strings.TrimSpace(parenPattern.ReplaceAllString(rest, ""))
+
+	// Everything after the first comma, like "onion, diced"
+	if name, note, ok := strings.Cut(rest, ","); ok {
+		rest = strings.TrimSpace(name)
+		if note = strings.TrimSpace(note); note != "" {
+			notes = append(notes, note)
+		}
+	}
+
+	// Trailing notes, like "salt to taste"
+	for _, note := range trailingNotes {
+		lower := strings.ToLower(rest)
+		if len(lower) > len(note) && strings.HasSuffix(lower, " "+note) {
+			notes = append(notes, rest[len(rest)-len(note):])
+			rest = strings.TrimSpace(rest[:len(rest)-len(note)])
+			break
+		}
+	}
+
+	ingredient.Name = strings.TrimSpace(strings.TrimRight(rest, ",;:"))
+	ingredient.Notes = strings.Join(notes, ", ")
+	return ingredient
+}
+
+// normalize replaces unicode fractions and dashes with ASCII, collapses whitespace and drops list bullets
+func normalize(line string) string {
+	var builder strings.Builder
+	for _, r := range line {
+		if fraction, ok := vulgarFractions[r]; ok {
+			builder.WriteString(" " + fraction + " ")
+			continue
+		}
+		switch r {
+		case '⁄':
+			builder.WriteRune('/')
+		case '–', '—':
+			builder.WriteRune('-')
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	normalized := strings.TrimSpace(whitespacePattern.ReplaceAllString(builder.String(), " "))
+	return strings.TrimSpace(strings.TrimLeft(normalized, "-*•·"))
+}
+
+// parseQuantity reads a leading amount or range and returns the rest of the line
+func (i *Ingredient) parseQuantity(line string) string {
+	if match := quantityPattern.FindStringSubmatch(line); match != nil {
+		i.Quantity = parseAmount(match[1])
+		if match[2] != "" {
+			i.MaxQuantity = parseAmount(match[2])
+		}
+		return strings.TrimSpace(line[len(match[0]):])
+	}
+
+	// Written numbers, with "a" and "an" only counting when a unit or size follows, like "a pinch of salt"
+	word, rest, _ := strings.Cut(line, " ")
+	quantity, ok := numberWords[strings.ToLower(word)]
+	if !ok {
+		return line
+	}
+	if lower := strings.ToLower(word); lower == "a" || lower == "an" {
+		next, _, _ := strings.Cut(rest, " ")
+		if _, isUnit := lookupUnit(next); !isUnit && !strings.HasPrefix(next, "(") {
+			return line
+		}
+	}
+	i.Quantity = quantity
+	return rest
+}
+
+// parseSize reads a leading parenthesized package size and returns the rest of the line
+func (i *Ingredient) parseSize(line string) string {
+	if i.Size != "" || !strings.HasPrefix(line, "(") {
+		return line
+	}
+	size, rest, ok := strings.Cut(line[1:], ")")
+	if !ok {
+		return line
+	}
+	i.Size = strings.TrimSpace(size)
+	return strings.TrimSpace(rest)
+}
+
+// parseUnit reads a leading unit, and the "of" after it, and returns the rest of the line
+func (i *Ingredient) parseUnit(line string) string {
+	words := strings.Fields(line)
+	if len(words) < 2 {
+		return line
+	}
+
+	unit, used := leadingUnit(words)
+	// Without an amount short words are more likely part of the name than a unit
+	if unit == "" || (i.Quantity == 0 && i.Size == "" && len(words[0]) <= 2) {
+		return line
+	}
+
+	i.Unit = unit
+	words = words[used:]
+	if len(words) > 1 && strings.EqualFold(words[0], "of") {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// leadingUnit returns the unit the words start with and how many words spell it
+func leadingUnit(words []string) (string, int) {
+	if len(words) == 0 {
+		return "", 0
+	}
+	if fluidWords[strings.ToLower(strings.TrimSuffix(words[0], "."))] {
+		if len(words) > 1 {
+			if unit, ok := lookupUnit(words[1]); ok && unit == UnitOunce {
+				return UnitFluidOunce, 2
+			}
+		}
+		return "", 0
+	}
+	if unit, ok := lookupUnit(words[0]); ok {
+		return unit, 1
+	}
+	return "", 0
+}
+
+// parseAmount parses a whole number, decimal, fraction or mixed number like "1 1/2"
+func parseAmount(amount string) float64 {
+	whole, fraction, mixed := strings.Cut(amount, " ")
+	if !mixed {
+		whole, fraction = "", amount
+	}
+
+	var value float64
+	if whole != "" {
+		value, _ = strconv.ParseFloat(whole, 64)
+	}
+	if numerator, denominator, ok := strings.Cut(fraction, "/"); ok {
+		n, _ := strconv.ParseFloat(numerator, 64)
+		d, _ := strconv.ParseFloat(denominator, 64)
+		if d != 0 {
+			value += n / d
+		}
+	} else {
+		f, _ := strconv.ParseFloat(fraction, 64)
+		value += f
+	}
+	return value
+}
@@ -0,0 +1,34 @@
+package parser
+
+import "testing"
+
+func TestParseIngredient(t *testing.T) {
+	tests := []struct {
+		line string
+		want Ingredient
+	}{
+		{"2 eggs", Ingredient{Quantity: 2, Name: "eggs"}},
+		{"1 1/2 cups chopped onion", Ingredient{Quantity: 1.5, Unit: UnitCup, Name: "onion", Notes: "chopped"}},
+		{"1½ cups milk", Ingredient{Quantity: 1.5, Unit: UnitCup, Name: "milk"}},
+		{"¾ tsp salt", Ingredient{Quantity: 0.75, Unit: UnitTeaspoon, Name: "salt"}},
+		{"1/2 lb bacon", Ingredient{Quantity: 0.5, Unit: UnitPound, Name: "bacon"}},
+		{"2-3 cloves garlic, minced", Ingredient{Quantity: 2, MaxQuantity: 3, Unit: UnitClove, Name: "garlic", Notes: "minced"}},
+		{"2 to 3 tbsp olive oil", Ingredient{Quantity: 2, MaxQuantity: 3, Unit: UnitTablespoon, Name: "olive oil"}},
+		{"2 (14 oz) cans diced tomatoes", Ingredient{Quantity: 2, Unit: UnitCan, Size: "14 oz", Name: "diced tomatoes"}},
+		{"1 (28 oz) can crushed tomatoes", Ingredient{Quantity: 1, Unit: UnitCan, Size: "28 oz", Name: "crushed tomatoes"}},
+		{"1 lb ground beef", Ingredient{Quantity: 1, Unit: UnitPound, Name: "ground beef"}},
+		{"2 cups shredded cheddar cheese", Ingredient{Quantity: 2, Unit: UnitCup, Name: "shredded cheddar cheese"}},
+		{"4 tbsp butter (softened)", Ingredient{Quantity: 4, Unit: UnitTablespoon, Name: "butter", Notes: "softened"}},
+		{"8 fl oz cream", Ingredient{Quantity: 8, Unit: UnitFluidOunce, Name: "cream"}},
+		{"a pinch of salt", Ingredient{Quantity: 1, Unit: UnitPinch, Name: "salt"}},
+		{"salt to taste", Ingredient{Name: "salt", Notes: "to taste"}},
+		{"black pepper, to taste", Ingredient{Name: "black pepper", Notes: "to taste"}},
+		{"- 1 cup sugar", Ingredient{Quantity: 1, Unit: UnitCup, Name: "sugar"}},
+	}
+	for _, test := range tests {
+		test.want.Text = test.line
+		if got := ParseIngredient(test.line); got != test.want {
+			t.Errorf("ParseIngredient(%q) = %+v, want %+v", test.line, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package parser
+
+import "strings"
+
+// Measure is an amount of one of the Unit constants
+type Measure struct {
+	Amount float64
+	Unit   string
+}
+
+type dimension int
+
+const (
+	dimensionVolume dimension = iota + 1
+	dimensionWeight
+	dimensionCount
+)
+
+// unitConversion is how many of its dimension's base unit, milliliters, grams or items, a unit is
+type unitConversion struct {
+	dimension dimension
+	base      float64
+}
+
+// unitConversions are the units that can be converted, others like cloves and sprigs can't be compared to a package size
+var unitConversions = map[string]unitConversion{
+	UnitPinch:      {dimensionVolume, 0.308},
+	UnitDash:       {dimensionVolume, 0.616},
+	UnitTeaspoon:   {dimensionVolume, 4.92892},
+	UnitTablespoon: {dimensionVolume, 14.7868},
+	UnitFluidOunce: {dimensionVolume, 29.5735},
+	UnitCup:        {dimensionVolume, 236.588},
+	UnitPint:       {dimensionVolume, 473.176},
+	UnitQuart:      {dimensionVolume, 946.353},
+	UnitGallon:     {dimensionVolume, 3785.41},
+	UnitMilliliter: {dimensionVolume, 1},
+	UnitLiter:      {dimensionVolume, 1000},
+	UnitMilligram:  {dimensionWeight, 0.001},
+	UnitGram:       {dimensionWeight, 1},
+	UnitKilogram:   {dimensionWeight, 1000},
+	UnitOunce:      {dimensionWeight, 28.3495},
+	UnitPound:      {dimensionWeight, 453.592},
+	UnitEach:       {dimensionCount, 1},
+	UnitCan:        {dimensionCount, 1},
+	UnitJar:        {dimensionCount, 1},
+	UnitPackage:    {dimensionCount, 1},
+	UnitBottle:     {dimensionCount, 1},
+	UnitBox:        {dimensionCount, 1},
+	UnitBag:        {dimensionCount, 1},
+	UnitStick:      {dimensionCount, 1},
+	UnitBunch:      {dimensionCount, 1},
+	UnitHead:       {dimensionCount, 1},
+	UnitPiece:      {dimensionCount, 1},
+}
+
+// Units lists the units ingredient amounts can be entered in, the ones that can be converted to a package size
+var Units = []string{
+	UnitEach,
+	UnitTeaspoon, UnitTablespoon, UnitFluidOunce, UnitCup, UnitPint, UnitQuart, UnitGallon, UnitMilliliter, UnitLiter,
+	UnitOunce, UnitPound, UnitGram, UnitKilogram, UnitMilligram,
+	UnitCan, UnitJar, UnitPackage, UnitBottle, UnitBox, UnitBag, UnitStick, UnitBunch, UnitHead, UnitPiece,
+	UnitPinch, UnitDash,
+}
+
+// ConvertibleUnit reports whether amounts of the unit can be converted to a package size
+func ConvertibleUnit(unit string) bool {
+	_, ok := unitConversions[unit]
+	return ok
+}
+
+// Convert converts the measure to the unit, if both measure the same dimension
+func (m Measure) Convert(unit string) (float64, bool) {
+	from, fromOK := unitConversions[m.Unit]
+	to, toOK := unitConversions[unit]
+	if !fromOK || !toOK || from.dimension != to.dimension {
+		return 0, false
+	}
+	return m.Amount * from.base / to.base, true
+}
+
+// ParseSize reads a product package size like "16 oz", "1/2 gal" or "12 ct".
+// A count followed by another size, like "6 pk / 12 fl oz", is that many items of that size, so both the count and the total are returned.
+// Sizes it doesn't understand return no measures.
+func ParseSize(size string) []Measure {
+	var measures []Measure
+	for part := range strings.SplitSeq(normalize(size), " / ") {
+		measure, ok := parseSizeMeasure(part)
+		if !ok {
+			return nil
+		}
+		// The size of each of the counted items
+		if len(measures) == 1 && measures[0].Unit == UnitEach && measure.Unit != UnitEach {
+			measure.Amount *= measures[0].Amount
+		}
+		measures = append(measures, measure)
+	}
+	return measures
+}
+
+func parseSizeMeasure(size string) (Measure, bool) {
+	if strings.EqualFold(size, UnitEach) {
+		return Measure{Amount: 1, Unit: UnitEach}, true
+	}
+
+	var ingredient Ingredient
+	rest := ingredient.parseQuantity(size)
+	if ingredient.Quantity == 0 || ingredient.MaxQuantity != 0 {
+		return Measure{}, false
+	}
+	// Units can be attached to the amount, like "16oz", and be followed by the packaging, like "3 lb bag"
+	unit, _ := leadingUnit(strings.Fields(rest))
+	if !ConvertibleUnit(unit) {
+		return Measure{}, false
+	}
+	return Measure{Amount: ingredient.Quantity, Unit: unit}, true
+}
+
+// PackageFraction is the fraction of a package of the size the amount uses, like 0.125 for 2 cups of a 1 gal package.
+// Package sizes and recipes often leave the "fl" off fluid ounces, so an amount in fluid ounces uses a package's ounces as fluid ounces,
+// and an amount in ounces is fluid ounces of a package sized by volume. Other volumes can't be compared to a weight.
+func PackageFraction(amount Measure, size []Measure) (float64, bool) {
+	for _, packageMeasure := range size {
+		if packageMeasure.Amount <= 0 {
+			continue
+		}
+		packageUnit, amountUnit := packageMeasure.Unit, amount.Unit
+		if packageUnit == UnitOunce && amountUnit == UnitFluidOunce {
+			packageUnit = UnitFluidOunce
+		} else if amountUnit == UnitOunce && unitConversions[packageUnit].dimension == dimensionVolume {
+			amountUnit = UnitFluidOunce
+		}
+		if converted, ok := (Measure{Amount: amount.Amount, Unit: amountUnit}).Convert(packageUnit); ok {
+			return converted / packageMeasure.Amount, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		size string
+		want []Measure
+	}{
+		{"16 oz", []Measure{{16, UnitOunce}}},
+		{"16oz", []Measure{{16, UnitOunce}}},
+		{"1 gal", []Measure{{1, UnitGallon}}},
+		{"1/2 gal", []Measure{{0.5, UnitGallon}}},
+		{"2 lb", []Measure{{2, UnitPound}}},
+		{"3 lb bag", []Measure{{3, UnitPound}}},
+		{"12 ct", []Measure{{12, UnitEach}}},
+		{"each", []Measure{{1, UnitEach}}},
+		{"64 fl oz", []Measure{{64, UnitFluidOunce}}},
+		{"6 pk / 12 fl oz", []Measure{{6, UnitEach}, {72, UnitFluidOunce}}},
+		{"", nil},
+		{"family size", nil},
+		{"1-2 lb", nil},
+	}
+	for _, test := range tests {
+		if got := ParseSize(test.size); !slices.Equal(got, test.want) {
+			t.Errorf("ParseSize(%q) = %v, want %v", test.size, got, test.want)
+		}
+	}
+}
+
+func TestPackageFraction(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount Measure
+		size   string
+		want   float64
+		wantOK bool
+	}{
+		{"volume", Measure{2, UnitCup}, "1 gal", 0.125, true},
+		{"weight", Measure{8, UnitOunce}, "2 lb", 0.25, true},
+		{"metric weight", Measure{500, UnitGram}, "1 kg", 0.5, true},
+		{"count", Measure{3, UnitEach}, "12 ct", 0.25, true},
+		{"fluid ounces of an ounce package", Measure{8, UnitFluidOunce}, "32 oz", 0.25, true},
+		{"ounces of a volume package", Measure{8, UnitOunce}, "1 qt", 0.25, true},
+		{"total of a multipack", Measure{36, UnitFluidOunce}, "6 pk / 12 fl oz", 0.5, true},
+		{"volume of a weight", Measure{2, UnitCup}, "32 oz", 0, false},
+		{"weight of a volume", Measure{100, UnitGram}, "1 gal", 0, false},
+		{"count of a weight", Measure{2, UnitCan}, "14.5 oz", 0, false},
+		{"unknown size", Measure{1, UnitCup}, "family size", 0, false},
+	}
+	for _, test := range tests {
+		got, ok := PackageFraction(test.amount, ParseSize(test.size))
+		if ok != test.wantOK || math.Abs(got-test.want) > 0.001 {
+			t.Errorf("%s: PackageFraction(%v, %q) = %v, %v, want %v, %v", test.name, test.amount, test.size, got, ok, test.want, test.wantOK)
+		}
+	}
+}
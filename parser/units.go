@@ -0,0 +1,89 @@
+package parser
+
+import "strings"
+
+// Units as ParseIngredient names them
+const (
+	UnitTeaspoon   = "tsp"
+	UnitTablespoon = "tbsp"
+	UnitCup        = "cup"
+	UnitFluidOunce = "fl oz"
+	UnitPint       = "pt"
+	UnitQuart      = "qt"
+	UnitGallon     = "gal"
+	UnitMilliliter = "ml"
+	UnitLiter      = "l"
+	UnitOunce      = "oz"
+	UnitPound      = "lb"
+	UnitMilligram  = "mg"
+	UnitGram       = "g"
+	UnitKilogram   = "kg"
+	UnitCan        = "can"
+	UnitJar        = "jar"
+	UnitPackage    = "package"
+	UnitBottle     = "bottle"
+	UnitBox        = "box"
+	UnitBag        = "bag"
+	UnitClove      = "clove"
+	UnitStick      = "stick"
+	UnitSlice      = "slice"
+	UnitBunch      = "bunch"
+	UnitHead       = "head"
+	UnitSprig      = "sprig"
+	UnitPiece      = "piece"
+	UnitPinch      = "pinch"
+	UnitDash       = "dash"
+	UnitHandful    = "handful"
+	UnitEach       = "each" // whole items, like a "12 ct" package
+)
+
+// unitAliases maps the lower case spellings of units to their names, periods already removed
+var unitAliases = map[string]string{
+	"tsp": UnitTeaspoon, "tsps": UnitTeaspoon, "teaspoon": UnitTeaspoon, "teaspoons": UnitTeaspoon,
+	"tbsp": UnitTablespoon, "tbsps": UnitTablespoon, "tbs": UnitTablespoon, "tbl": UnitTablespoon, "tablespoon": UnitTablespoon, "tablespoons": UnitTablespoon,
+	"c": UnitCup, "cup": UnitCup, "cups": UnitCup,
+	"floz": UnitFluidOunce, "fl.oz": UnitFluidOunce,
+	"pt": UnitPint, "pts": UnitPint, "pint": UnitPint, "pints": UnitPint,
+	"qt": UnitQuart, "qts": UnitQuart, "quart": UnitQuart, "quarts": UnitQuart,
+	"gal": UnitGallon, "gals": UnitGallon, "gallon": UnitGallon, "gallons": UnitGallon,
+	"ml": UnitMilliliter, "milliliter": UnitMilliliter, "milliliters": UnitMilliliter, "millilitre": UnitMilliliter, "millilitres": UnitMilliliter,
+	"l": UnitLiter, "liter": UnitLiter, "liters": UnitLiter, "litre": UnitLiter, "litres": UnitLiter,
+	"oz": UnitOunce, "ozs": UnitOunce, "ounce": UnitOunce, "ounces": UnitOunce,
+	"lb": UnitPound, "lbs": UnitPound, "pound": UnitPound, "pounds": UnitPound,
+	"mg": UnitMilligram, "milligram": UnitMilligram, "milligrams": UnitMilligram,
+	"g": UnitGram, "gr": UnitGram, "gram": UnitGram, "grams": UnitGram,
+	"kg": UnitKilogram, "kgs": UnitKilogram, "kilogram": UnitKilogram, "kilograms": UnitKilogram,
+	"can": UnitCan, "cans": UnitCan,
+	"jar": UnitJar, "jars": UnitJar,
+	"package": UnitPackage, "packages": UnitPackage, "pkg": UnitPackage, "pkgs": UnitPackage, "packet": UnitPackage, "packets": UnitPackage,
+	"bottle": UnitBottle, "bottles": UnitBottle,
+	"box": UnitBox, "boxes": UnitBox,
+	"bag": UnitBag, "bags": UnitBag,
+	"clove": UnitClove, "cloves": UnitClove,
+	"stick": UnitStick, "sticks": UnitStick,
+	"slice": UnitSlice, "slices": UnitSlice,
+	"bunch": UnitBunch, "bunches": UnitBunch,
+	"head": UnitHead, "heads": UnitHead,
+	"sprig": UnitSprig, "sprigs": UnitSprig,
+	"piece": UnitPiece, "pieces": UnitPiece,
+	"pinch": UnitPinch, "pinches": UnitPinch,
+	"dash": UnitDash, "dashes": UnitDash,
+	"handful": UnitHandful, "handfuls": UnitHandful,
+	"each": UnitEach, "ea": UnitEach, "ct": UnitEach, "count": UnitEach, "pk": UnitEach, "pack": UnitEach, "packs": UnitEach,
+}
+
+// fluidWords start the two word spellings of fluid ounces, like "fl oz" and "fluid ounces"
+var fluidWords = map[string]bool{"fl": true, "fluid": true}
+
+// lookupUnit returns the name of the unit spelled by the word.
+// "T" and "t" are the only case sensitive spellings, for tablespoons and teaspoons.
+func lookupUnit(word string) (string, bool) {
+	switch word {
+	case "T":
+		return UnitTablespoon, true
+	case "t":
+		return UnitTeaspoon, true
+	}
+	unit, ok := unitAliases[strings.ToLower(strings.TrimSuffix(word, "."))]
+	return unit, ok
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/templates"
+)
+
+const (
+	AccessTokenPrefix        = "krs_"
+	AccessTokenNameMaxLength = 128
+)
+
+var accessTokenScopes = []string{
+	data.AccessTokenScopeRead,
+	data.AccessTokenScopeWrite,
+}
+
+// GenerateAccessToken returns a new random token; only its hash is ever stored
+func GenerateAccessToken() (string, error) {
+	secret, err := randomURLString()
+	if err != nil {
+		return "", err
+	}
+	return AccessTokenPrefix + secret, nil
+}
+
+func HashAccessToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// bearerToken returns the token from the Authorization header, if one was sent
+func bearerToken(r *http.Request) (string, bool) {
+	authorization := r.Header.Get("Authorization")
+	if authorization == "" {
+		return "", false
+	}
+	scheme, token, _ := strings.Cut(authorization, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+func NewAccessTokensMux(repo *data.Repository) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			requestedAccountID := uuid.MustParse(chi.URLParam(r, "accountID"))
+			if authCookies.AccountID != requestedAccountID {
+				http.Error(w, "listing access tokens", http.StatusUnauthorized)
+				return
+			}
+
+			tokens, err := repo.ListAccessTokens(r.Context(), requestedAccountID)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("listing access tokens: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			if err := templates.AccessTokensTable(requestedAccountID, tokens).Render(w); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+
+		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			requestedAccountID := uuid.MustParse(chi.URLParam(r, "accountID"))
+			if authCookies.AccountID != requestedAccountID {
+				http.Error(w, "creating access token", http.StatusUnauthorized)
+				return
+			}
+
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			name := strings.TrimSpace(r.FormValue("name"))
+			if name == "" || len(name) > AccessTokenNameMaxLength {
+				http.Error(w, fmt.Sprintf("name must be between 1 and %d characters", AccessTokenNameMaxLength), http.StatusBadRequest)
+				return
+			}
+			scope := r.FormValue("scope")
+			if !validOption(accessTokenScopes, scope) {
+				http.Error(w, fmt.Sprintf("invalid scope %q", scope), http.StatusBadRequest)
+				return
+			}
+
+			token, err := GenerateAccessToken()
+			if err != nil {
+				http.Error(w, fmt.Sprintf("generating access token: %v", err), http.StatusInternalServerError)
+				return
+			}
+			if _, err := repo.CreateAccessToken(r.Context(), requestedAccountID, name, HashAccessToken(token), scope); err != nil {
+				http.Error(w, fmt.Sprintf("creating access token: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			if err := templates.AccessTokenCreated(name, token).Render(w); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Add("HX-Trigger", "access-token-update")
+			w.WriteHeader(http.StatusOK)
+		})
+
+		r.Delete("/{tokenID}", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			requestedAccountID := uuid.MustParse(chi.URLParam(r, "accountID"))
+			if authCookies.AccountID != requestedAccountID {
+				http.Error(w, "revoking access token", http.StatusUnauthorized)
+				return
+			}
+
+			tokenID, err := uuid.Parse(chi.URLParam(r, "tokenID"))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid token id: %v", err), http.StatusBadRequest)
+				return
+			}
+
+			if err := repo.DeleteAccessToken(r.Context(), requestedAccountID, tokenID); err != nil {
+				http.Error(w, fmt.Sprintf("revoking access token: %v", err), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Add("HX-Trigger", "access-token-update")
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+}
@@ -57,10 +57,11 @@ func NewAccountMux(config Config, repo *data.Repository, cache *data.Cache) func
 			}
 
 			if err := templates.AccountPage(templates.Account{
-				ID:        account.ID,
-				ImageSize: account.ImageSize,
-				Location:  location,
-				Homepage:  account.Homepage,
+				ID:                account.ID,
+				ImageSize:         account.ImageSize,
+				Location:          location,
+				Homepage:          account.Homepage,
+				AlternativeChoice: account.AlternativeChoice,
 			}, profile).Render(w); err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
@@ -113,10 +114,14 @@ func NewAccountMux(config Config, repo *data.Repository, cache *data.Cache) func
 					http.Error(w, "deleting account", http.StatusUnauthorized)
 					return
 				}
-				if err := repo.DeleteAccount(r.Context(), requestedAccountID); err != nil {
+				photoIDs, err := repo.DeleteAccount(r.Context(), requestedAccountID)
+				if err != nil {
 					http.Error(w, fmt.Sprintf("deleting account: %v", err), http.StatusInternalServerError)
 					return
 				}
+				for _, photoID := range photoIDs {
+					deleteRecipePhoto(r.Context(), config.Photos, photoID)
+				}
 				ClearAuthCookies(w)
 			})
 
@@ -154,6 +159,14 @@ func NewAccountMux(config Config, repo *data.Repository, cache *data.Cache) func
 					}
 				}
 
+				if r.Form.Has("alternativeChoice") {
+					alternativeChoice := r.FormValue("alternativeChoice")
+					if err := repo.UpdateAccountAlternativeChoice(r.Context(), accountID, alternativeChoice); err != nil {
+						http.Error(w, fmt.Sprintf("updating account alternative choice: %v", err), http.StatusInternalServerError)
+						return
+					}
+				}
+
 				if r.Form.Has("locationID") {
 					locationID := r.FormValue("locationID")
 					var locationIDStr *string
@@ -203,6 +216,9 @@ func NewAccountMux(config Config, repo *data.Repository, cache *data.Cache) func
 				w.WriteHeader(http.StatusOK)
 			})
 
+			r.Route("/tokens", NewAccessTokensMux(repo))
+			r.Route("/sessions", NewSessionsMux(config, repo))
+
 			r.Route("/profile", func(r chi.Router) {
 				r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 					authCookies, err := GetAuthCookies(r)
@@ -254,10 +270,11 @@ func NewAccountMux(config Config, repo *data.Repository, cache *data.Cache) func
 					}
 
 					if err := templates.Profile(templates.Account{
-						ID:        account.ID,
-						ImageSize: account.ImageSize,
-						Location:  location,
-						Homepage:  account.Homepage,
+						ID:                account.ID,
+						ImageSize:         account.ImageSize,
+						Location:          location,
+						Homepage:          account.Homepage,
+						AlternativeChoice: account.AlternativeChoice,
 					}, profile).Render(w); err != nil {
 						http.Error(w, err.Error(), http.StatusInternalServerError)
 						return
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/densestvoid/krogerrecipeshopper/app"
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/kroger"
+)
+
+const (
+	APIContentType    = "application/json"
+	APIMaxRequestSize = 1 << 20 // 1 MiB
+)
+
+type APIError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+type apiErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+func WriteAPIJSON(w http.ResponseWriter, statusCode int, value any) {
+	w.Header().Set("Content-Type", APIContentType)
+	w.WriteHeader(statusCode)
+	if value == nil {
+		return
+	}
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		slog.Error("writing api response", slog.String("error", err.Error()))
+	}
+}
+
+// WriteAPIError writes an error response; server errors are logged and only their status text is returned, to keep database and upstream errors private
+func WriteAPIError(w http.ResponseWriter, statusCode int, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	if statusCode >= http.StatusInternalServerError {
+		slog.Error("api request failed", slog.Int("status", statusCode), slog.String("error", message))
+		message = http.StatusText(statusCode)
+	}
+	WriteAPIJSON(w, statusCode, apiErrorResponse{
+		Error: APIError{
+			Status:  statusCode,
+			Message: message,
+		},
+	})
+}
+
+// writeAPIRepoError maps missing rows to a not found response, everything else is an internal error
+func writeAPIRepoError(w http.ResponseWriter, err error, format string, args ...any) {
+	if errors.Is(err, sql.ErrNoRows) {
+		WriteAPIError(w, http.StatusNotFound, "not found")
+		return
+	}
+	WriteAPIError(w, http.StatusInternalServerError, "%s: %v", fmt.Sprintf(format, args...), err)
+}
+
+func decodeAPIRequest(r *http.Request, value any) error {
+	decoder := json.NewDecoder(io.LimitReader(r.Body, APIMaxRequestSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+func apiURLParamUUID(r *http.Request, key string) (uuid.UUID, error) {
+	id, err := uuid.Parse(chi.URLParam(r, key))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return id, nil
+}
+
+func newKrogerManager(ctx context.Context, config Config, cache *data.Cache) (*app.KrogerManager, error) {
+	authClient := kroger.NewAuthorizationClient(http.DefaultClient, kroger.PublicEnvironment, config.ClientID, config.ClientSecret)
+	return app.NewClientKrogerManager(ctx, authClient, cache)
+}
+
+// RecipeVisible reports whether an account may view a recipe, matching the rules of ListRecipes
+func RecipeVisible(recipe data.Recipe, accountID uuid.UUID) bool {
+	return recipe.AccountID == accountID || recipe.Visibility == data.VisibilityPublic
+}
+
+var (
+	visibilities       = []string{data.VisibilityPublic, data.VisibilityFriends, data.VisibilityPrivate}
+	instructionTypes   = []string{data.InstructionTypeNone, data.InstructionTypeText, data.InstructionTypeLink}
+	imageSizes         = []string{data.ImageSizeThumbnail, data.ImageSizeSmall, data.ImageSizeMedium, data.ImageSizeLarge, data.ImageSizeExtraLarge}
+	homepageOptions    = []string{data.HomepageOptionWelcome, data.HomepageOptionRecipes, data.HomepageOptionFavorites, data.HomepageOptionExplore}
+	alternativeChoices = []string{data.AlternativeChoiceInStock, data.AlternativeChoiceCheapest}
+)
+
+func validOption(options []string, value string) bool {
+	return slices.Contains(options, value)
+}
+
+type APIProduct struct {
+	ProductID   string `json:"productID"`
+	Brand       string `json:"brand"`
+	Description string `json:"description"`
+	Size        string `json:"size"`
+	Location    string `json:"location,omitempty"`
+	ImageURL    string `json:"imageURL"`
+	ProductURL  string `json:"productURL"`
+}
+
+func newAPIProduct(product data.CacheProduct, imageSize string) (APIProduct, error) {
+	productURL, err := url.JoinPath(KrogerURL, product.URL)
+	if err != nil {
+		return APIProduct{}, err
+	}
+
+	productURL, err = url.QueryUnescape(productURL)
+	if err != nil {
+		return APIProduct{}, err
+	}
+
+	return APIProduct{
+		ProductID:   product.ProductID,
+		Brand:       product.Brand,
+		Description: product.Description,
+		Size:        product.Size,
+		Location:    product.Location,
+		ImageURL:    ProductImageLink(product.ProductID, imageSize),
+		ProductURL:  productURL,
+	}, nil
+}
+
+// hydrateAPIProducts looks up product details for the account's location and image size
+func hydrateAPIProducts(ctx context.Context, config Config, repo *data.Repository, cache *data.Cache, accountID uuid.UUID, productIDs []string) (map[string]APIProduct, error) {
+	products := map[string]APIProduct{}
+	if len(productIDs) == 0 {
+		return products, nil
+	}
+
+	account, err := repo.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("getting account: %w", err)
+	}
+
+	krogerManager, err := newKrogerManager(ctx, config, cache)
+	if err != nil {
+		return nil, fmt.Errorf("creating kroger manager: %w", err)
+	}
+
+	productsByID, err := krogerManager.GetProducts(ctx, account.LocationID, productIDs...)
+	if err != nil {
+		return nil, fmt.Errorf("getting products: %w", err)
+	}
+
+	for productID, product := range productsByID {
+		apiProduct, err := newAPIProduct(product, account.ImageSize)
+		if err != nil {
+			return nil, fmt.Errorf("building product %s: %w", productID, err)
+		}
+		products[productID] = apiProduct
+	}
+	return products, nil
+}
+
+func NewAPIMux(config Config, repo *data.Repository, cache *data.Cache) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Use(APIAuthenticationMiddleware(config, repo))
+		r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+			WriteAPIError(w, http.StatusNotFound, "not found")
+		})
+		r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+			WriteAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+		})
+
+		r.Route("/account", NewAPIAccountMux(config, repo, cache))
+		r.Route("/profiles", NewAPIProfilesMux(repo))
+		r.Route("/recipes", NewAPIRecipesMux(config, repo, cache))
+		r.Route("/favorites", NewAPIFavoritesMux(repo))
+		r.Route("/lists", NewAPIListsMux(config, repo, cache))
+		r.Route("/cart", NewAPICartMux(config, repo, cache))
+		r.Route("/locations", NewAPILocationsMux(config, cache))
+	}
+}
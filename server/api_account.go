@@ -0,0 +1,276 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+)
+
+const ProfileDisplayNameMinLength = 6
+
+type APIAccount struct {
+	ID                uuid.UUID `json:"id"`
+	ImageSize         string    `json:"imageSize"`
+	LocationID        *string   `json:"locationID"`
+	Homepage          string    `json:"homepage"`
+	AlternativeChoice string    `json:"alternativeChoice"`
+}
+
+func newAPIAccount(account data.Account) APIAccount {
+	return APIAccount{
+		ID:                account.ID,
+		ImageSize:         account.ImageSize,
+		LocationID:        account.LocationID,
+		Homepage:          account.Homepage,
+		AlternativeChoice: account.AlternativeChoice,
+	}
+}
+
+// APIAccountSettingsRequest only updates the settings that are present; an empty locationID clears the location
+type APIAccountSettingsRequest struct {
+	ImageSize         *string `json:"imageSize"`
+	LocationID        *string `json:"locationID"`
+	Homepage          *string `json:"homepage"`
+	AlternativeChoice *string `json:"alternativeChoice"`
+}
+
+type APIProfile struct {
+	AccountID   uuid.UUID `json:"accountID"`
+	DisplayName string    `json:"displayName"`
+}
+
+func newAPIProfile(profile data.Profile) APIProfile {
+	return APIProfile{
+		AccountID:   profile.AccountID,
+		DisplayName: profile.DisplayName,
+	}
+}
+
+type APIProfileRequest struct {
+	DisplayName string `json:"displayName"`
+}
+
+func (req APIProfileRequest) validate() error {
+	if len(req.DisplayName) < ProfileDisplayNameMinLength {
+		return fmt.Errorf("display name must be at least %d characters", ProfileDisplayNameMinLength)
+	}
+	return nil
+}
+
+func NewAPIAccountMux(config Config, repo *data.Repository, cache *data.Cache) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+				return
+			}
+
+			account, err := repo.GetAccountByID(r.Context(), authCookies.AccountID)
+			if err != nil {
+				writeAPIRepoError(w, err, "getting account")
+				return
+			}
+
+			WriteAPIJSON(w, http.StatusOK, newAPIAccount(account))
+		})
+
+		r.Patch("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+				return
+			}
+
+			var req APIAccountSettingsRequest
+			if err := decodeAPIRequest(r, &req); err != nil {
+				WriteAPIError(w, http.StatusBadRequest, "%v", err)
+				return
+			}
+
+			if req.ImageSize != nil && !validOption(imageSizes, *req.ImageSize) {
+				WriteAPIError(w, http.StatusBadRequest, "invalid image size %q", *req.ImageSize)
+				return
+			}
+			if req.Homepage != nil && !validOption(homepageOptions, *req.Homepage) {
+				WriteAPIError(w, http.StatusBadRequest, "invalid homepage %q", *req.Homepage)
+				return
+			}
+			if req.AlternativeChoice != nil && !validOption(alternativeChoices, *req.AlternativeChoice) {
+				WriteAPIError(w, http.StatusBadRequest, "invalid alternative choice %q", *req.AlternativeChoice)
+				return
+			}
+
+			if req.ImageSize != nil {
+				if err := repo.UpdateAccountImageSize(r.Context(), authCookies.AccountID, *req.ImageSize); err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "updating account image size: %v", err)
+					return
+				}
+			}
+			if req.Homepage != nil {
+				if err := repo.UpdateAccountHomepage(r.Context(), authCookies.AccountID, *req.Homepage); err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "updating account homepage: %v", err)
+					return
+				}
+			}
+			if req.AlternativeChoice != nil {
+				if err := repo.UpdateAccountAlternativeChoice(r.Context(), authCookies.AccountID, *req.AlternativeChoice); err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "updating account alternative choice: %v", err)
+					return
+				}
+			}
+			if req.LocationID != nil {
+				var locationID *string
+				if *req.LocationID != "" {
+					krogerManager, err := newKrogerManager(r.Context(), config, cache)
+					if err != nil {
+						WriteAPIError(w, http.StatusInternalServerError, "creating kroger manager: %v", err)
+						return
+					}
+					if _, err := krogerManager.GetLocation(r.Context(), *req.LocationID); err != nil {
+						WriteAPIError(w, http.StatusBadRequest, "invalid location %q: %v", *req.LocationID, err)
+						return
+					}
+					locationID = req.LocationID
+				}
+				if err := repo.UpdateAccountLocationID(r.Context(), authCookies.AccountID, locationID); err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "updating account location ID: %v", err)
+					return
+				}
+			}
+
+			account, err := repo.GetAccountByID(r.Context(), authCookies.AccountID)
+			if err != nil {
+				writeAPIRepoError(w, err, "getting account")
+				return
+			}
+
+			WriteAPIJSON(w, http.StatusOK, newAPIAccount(account))
+		})
+
+		r.Route("/profile", func(r chi.Router) {
+			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				authCookies, err := GetAuthCookies(r)
+				if err != nil {
+					WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+					return
+				}
+
+				profile, err := repo.GetProfileByAccountID(r.Context(), authCookies.AccountID)
+				if err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "getting profile: %v", err)
+					return
+				} else if profile == nil {
+					WriteAPIError(w, http.StatusNotFound, "no profile for this account")
+					return
+				}
+
+				WriteAPIJSON(w, http.StatusOK, newAPIProfile(*profile))
+			})
+
+			// Create or update the profile
+			r.Put("/", func(w http.ResponseWriter, r *http.Request) {
+				authCookies, err := GetAuthCookies(r)
+				if err != nil {
+					WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+					return
+				}
+
+				var req APIProfileRequest
+				if err := decodeAPIRequest(r, &req); err != nil {
+					WriteAPIError(w, http.StatusBadRequest, "%v", err)
+					return
+				}
+				if err := req.validate(); err != nil {
+					WriteAPIError(w, http.StatusBadRequest, "%v", err)
+					return
+				}
+
+				profile, err := repo.GetProfileByAccountID(r.Context(), authCookies.AccountID)
+				if err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "getting profile: %v", err)
+					return
+				}
+
+				statusCode := http.StatusOK
+				if profile == nil {
+					if _, err := repo.CreateProfile(r.Context(), authCookies.AccountID, req.DisplayName); err != nil {
+						WriteAPIError(w, http.StatusInternalServerError, "creating profile: %v", err)
+						return
+					}
+					statusCode = http.StatusCreated
+				} else if err := repo.UpdateProfileDisplayName(r.Context(), authCookies.AccountID, req.DisplayName); err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "updating profile: %v", err)
+					return
+				}
+
+				WriteAPIJSON(w, statusCode, APIProfile{
+					AccountID:   authCookies.AccountID,
+					DisplayName: req.DisplayName,
+				})
+			})
+
+			r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
+				authCookies, err := GetAuthCookies(r)
+				if err != nil {
+					WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+					return
+				}
+
+				if err := repo.DeleteProfile(r.Context(), authCookies.AccountID); err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "deleting profile: %v", err)
+					return
+				}
+
+				WriteAPIJSON(w, http.StatusNoContent, nil)
+			})
+		})
+	}
+}
+
+func NewAPIProfilesMux(repo *data.Repository) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			name := r.URL.Query().Get("name")
+			if name == "" {
+				WriteAPIError(w, http.StatusBadRequest, "name missing")
+				return
+			}
+
+			profiles, err := repo.ListProfiles(r.Context(), name)
+			if err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "listing profiles: %v", err)
+				return
+			}
+
+			apiProfiles := []APIProfile{}
+			for _, profile := range profiles {
+				apiProfiles = append(apiProfiles, newAPIProfile(profile))
+			}
+			WriteAPIJSON(w, http.StatusOK, apiProfiles)
+		})
+
+		r.Get("/{accountID}", func(w http.ResponseWriter, r *http.Request) {
+			accountID, err := apiURLParamUUID(r, "accountID")
+			if err != nil {
+				WriteAPIError(w, http.StatusBadRequest, "%v", err)
+				return
+			}
+
+			profile, err := repo.GetProfileByAccountID(r.Context(), accountID)
+			if err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "getting profile: %v", err)
+				return
+			} else if profile == nil {
+				WriteAPIError(w, http.StatusNotFound, "no profile for this account")
+				return
+			}
+
+			WriteAPIJSON(w, http.StatusOK, newAPIProfile(*profile))
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"math"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/kroger"
+)
+
+type APICartProduct struct {
+	ProductID string  `json:"productID"`
+	Quantity  float64 `json:"quantity"` // fraction of the product's package
+	Staple    bool    `json:"staple"`
+	// The products of the ingredient the product was picked from, in order of preference
+	Alternatives []string    `json:"alternatives,omitempty"`
+	Product      *APIProduct `json:"product,omitempty"`
+}
+
+type APICartProductAddRequest struct {
+	ProductID string  `json:"productID"`
+	Quantity  float64 `json:"quantity"`
+}
+
+type APICartProductSetRequest struct {
+	Quantity *float64 `json:"quantity"`
+	Staple   *bool    `json:"staple"`
+}
+
+type APICheckoutResponse struct {
+	KrogerCartURL string `json:"krogerCartURL"`
+}
+
+func NewAPICartMux(config Config, repo *data.Repository, cache *data.Cache) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+				return
+			}
+
+			cartProducts, err := repo.ListCartProducts(r.Context(), authCookies.AccountID)
+			if err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "listing cart products: %v", err)
+				return
+			}
+
+			productIDs := []string{}
+			for _, cartProduct := range cartProducts {
+				productIDs = append(productIDs, cartProduct.ProductID)
+			}
+			products, err := hydrateAPIProducts(r.Context(), config, repo, cache, authCookies.AccountID, productIDs)
+			if err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "%v", err)
+				return
+			}
+
+			apiCartProducts := []APICartProduct{}
+			for _, cartProduct := range cartProducts {
+				apiCartProduct := APICartProduct{
+					ProductID:    cartProduct.ProductID,
+					Quantity:     float64(cartProduct.Quantity) / 100,
+					Staple:       cartProduct.Staple,
+					Alternatives: cartProduct.Alternatives,
+				}
+				if product, ok := products[cartProduct.ProductID]; ok {
+					apiCartProduct.Product = &product
+				}
+				apiCartProducts = append(apiCartProducts, apiCartProduct)
+			}
+			WriteAPIJSON(w, http.StatusOK, apiCartProducts)
+		})
+
+		r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+				return
+			}
+
+			if err := repo.ClearCartProducts(r.Context(), authCookies.AccountID); err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "clearing cart products: %v", err)
+				return
+			}
+
+			WriteAPIJSON(w, http.StatusNoContent, nil)
+		})
+
+		// Add the ingredients of a list or recipe to the cart
+		r.Post("/lists/{id}", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+				return
+			}
+
+			list, ok := getAPIList(w, r, repo, authCookies.AccountID, false)
+			if !ok {
+				return
+			}
+
+			if err := addListToCart(r.Context(), config, repo, cache, authCookies.AccountID, list.ID); err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "%v", err)
+				return
+			}
+
+			WriteAPIJSON(w, http.StatusNoContent, nil)
+		})
+
+		r.Route("/products", func(r chi.Router) {
+			// Add a quantity of a product to the cart
+			r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+				authCookies, err := GetAuthCookies(r)
+				if err != nil {
+					WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+					return
+				}
+
+				var req APICartProductAddRequest
+				if err := decodeAPIRequest(r, &req); err != nil {
+					WriteAPIError(w, http.StatusBadRequest, "%v", err)
+					return
+				}
+				if req.ProductID == "" {
+					WriteAPIError(w, http.StatusBadRequest, "productID missing")
+					return
+				}
+				if req.Quantity <= 0 {
+					WriteAPIError(w, http.StatusBadRequest, "invalid quantity: %v", req.Quantity)
+					return
+				}
+
+				if err := repo.AddCartProduct(r.Context(), authCookies.AccountID, req.ProductID, int(req.Quantity*100), false, nil); err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "adding cart product: %v", err)
+					return
+				}
+
+				WriteAPIJSON(w, http.StatusNoContent, nil)
+			})
+
+			r.Route("/{productID}", func(r chi.Router) {
+				// Set the quantity or staple status of a product in the cart
+				r.Patch("/", func(w http.ResponseWriter, r *http.Request) {
+					authCookies, err := GetAuthCookies(r)
+					if err != nil {
+						WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+						return
+					}
+					productID := chi.URLParam(r, "productID")
+
+					var req APICartProductSetRequest
+					if err := decodeAPIRequest(r, &req); err != nil {
+						WriteAPIError(w, http.StatusBadRequest, "%v", err)
+						return
+					}
+
+					var quantityPercent *int
+					if req.Quantity != nil {
+						if *req.Quantity <= 0 {
+							WriteAPIError(w, http.StatusBadRequest, "invalid quantity: %v", *req.Quantity)
+							return
+						}
+						quantityPercent = NewT(int(*req.Quantity * 100))
+					}
+
+					if _, err := repo.GetCartProduct(r.Context(), authCookies.AccountID, productID); err != nil {
+						writeAPIRepoError(w, err, "getting cart product")
+						return
+					}
+
+					if err := repo.SetCartProduct(r.Context(), authCookies.AccountID, productID, quantityPercent, req.Staple); err != nil {
+						WriteAPIError(w, http.StatusInternalServerError, "updating cart product: %v", err)
+						return
+					}
+
+					cartProduct, err := repo.GetCartProduct(r.Context(), authCookies.AccountID, productID)
+					if err != nil {
+						WriteAPIError(w, http.StatusInternalServerError, "getting cart product: %v", err)
+						return
+					}
+
+					WriteAPIJSON(w, http.StatusOK, APICartProduct{
+						ProductID:    cartProduct.ProductID,
+						Quantity:     float64(cartProduct.Quantity) / 100,
+						Staple:       cartProduct.Staple,
+						Alternatives: cartProduct.Alternatives,
+					})
+				})
+
+				r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
+					authCookies, err := GetAuthCookies(r)
+					if err != nil {
+						WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+						return
+					}
+
+					if err := repo.RemoveCartProduct(r.Context(), authCookies.AccountID, chi.URLParam(r, "productID")); err != nil {
+						WriteAPIError(w, http.StatusInternalServerError, "removing cart product: %v", err)
+						return
+					}
+
+					WriteAPIJSON(w, http.StatusNoContent, nil)
+				})
+			})
+		})
+
+		// Send the non-staple products to the Kroger cart
+		r.Post("/checkout", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+				return
+			}
+			if !authCookies.HasKrogerSession() {
+				WriteAPIError(w, http.StatusForbidden, "checkout requires a linked Kroger browser session, sign in through the website instead of using an access token")
+				return
+			}
+			cartClient := kroger.NewCartClient(http.DefaultClient, kroger.PublicEnvironment, authCookies.AccessToken)
+
+			cartProducts, err := repo.ListCartProducts(r.Context(), authCookies.AccountID, &data.ListCartProductsIncludeStaples{Include: false})
+			if err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "listing cart products: %v", err)
+				return
+			}
+
+			var addProducts []kroger.PutAddProduct
+			for _, cartProduct := range cartProducts {
+				addProducts = append(addProducts, kroger.PutAddProduct{
+					ProductID: cartProduct.ProductID,
+					Quantity:  int(math.Ceil(float64(cartProduct.Quantity) / 100)),
+					Modality:  kroger.ModalityPickup,
+				})
+			}
+
+			if err := cartClient.PutAdd(r.Context(), kroger.PutAddRequest{
+				Items: addProducts,
+			}); err != nil {
+				WriteAPIError(w, http.StatusBadGateway, "adding products to kroger cart: %v", err)
+				return
+			}
+
+			if err := repo.ClearCartProducts(r.Context(), authCookies.AccountID); err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "removing cart products: %v", err)
+				return
+			}
+
+			WriteAPIJSON(w, http.StatusOK, APICheckoutResponse{KrogerCartURL: KrogerCartURL})
+		})
+	}
+}
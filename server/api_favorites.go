@@ -0,0 +1,80 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+)
+
+func NewAPIFavoritesMux(repo *data.Repository) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+				return
+			}
+
+			recipes, err := repo.ListRecipes(r.Context(), authCookies.AccountID, []data.ListRecipesFilter{
+				data.ListRecipesFilterByFavorites{},
+				data.ListRecipesFilterByVisibilities{Visibilities: visibilities},
+			}, []data.ListRecipesOrderBy{
+				{Field: "name", Direction: "asc"},
+			})
+			if err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "listing favorite recipes: %v", err)
+				return
+			}
+
+			WriteAPIJSON(w, http.StatusOK, newAPIRecipes(recipes))
+		})
+
+		r.Route("/{id}", func(r chi.Router) {
+			r.Put("/", func(w http.ResponseWriter, r *http.Request) {
+				authCookies, err := GetAuthCookies(r)
+				if err != nil {
+					WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+					return
+				}
+
+				recipe, ok := getAPIRecipe(w, r, repo, authCookies.AccountID)
+				if !ok {
+					return
+				}
+
+				if !recipe.Favorite {
+					if err := repo.FavoriteRecipe(r.Context(), recipe.ListID, authCookies.AccountID); err != nil {
+						WriteAPIError(w, http.StatusInternalServerError, "adding favorite recipe: %v", err)
+						return
+					}
+					recipe.Favorite = true
+				}
+
+				WriteAPIJSON(w, http.StatusOK, newAPIRecipe(recipe))
+			})
+
+			r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
+				authCookies, err := GetAuthCookies(r)
+				if err != nil {
+					WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+					return
+				}
+
+				listID, err := apiURLParamUUID(r, "id")
+				if err != nil {
+					WriteAPIError(w, http.StatusBadRequest, "%v", err)
+					return
+				}
+
+				if err := repo.UnfavoriteRecipe(r.Context(), listID, authCookies.AccountID); err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "removing favorite recipe: %v", err)
+					return
+				}
+
+				WriteAPIJSON(w, http.StatusNoContent, nil)
+			})
+		})
+	}
+}
@@ -0,0 +1,436 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/parser"
+)
+
+type APIList struct {
+	ID          uuid.UUID `json:"id"`
+	AccountID   uuid.UUID `json:"accountID"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+}
+
+func newAPIList(list data.List) APIList {
+	return APIList{
+		ID:          list.ID,
+		AccountID:   list.AccountID,
+		Name:        list.Name,
+		Description: list.Description,
+	}
+}
+
+type APIListRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (req APIListRequest) validate() error {
+	if req.Name == "" {
+		return fmt.Errorf("name missing")
+	}
+	return nil
+}
+
+type APIIngredient struct {
+	ProductID   string  `json:"productID"`
+	Quantity    float64 `json:"quantity"`         // fraction of the product's package
+	Amount      float64 `json:"amount,omitempty"` // the recipe amount the quantity was computed from
+	Unit        string  `json:"unit,omitempty"`
+	Staple      bool    `json:"staple"`
+	Note        string  `json:"note,omitempty"`
+	DisplayName string  `json:"displayName,omitempty"`
+	Section     string  `json:"section,omitempty"`
+	// Product IDs that can be bought in place of the product, in order of preference
+	Alternatives []string    `json:"alternatives,omitempty"`
+	Product      *APIProduct `json:"product,omitempty"`
+}
+
+func newAPIIngredient(ingredient data.Ingredient) APIIngredient {
+	return APIIngredient{
+		ProductID:    ingredient.ProductID,
+		Quantity:     float64(ingredient.Quantity) / 100,
+		Amount:       ingredient.Amount,
+		Unit:         ingredient.Unit,
+		Staple:       ingredient.Staple,
+		Note:         ingredient.Note,
+		DisplayName:  ingredient.DisplayName,
+		Section:      ingredient.Section,
+		Alternatives: ingredient.Alternatives,
+	}
+}
+
+// APIIngredientRequest sets either the quantity as a fraction of the product's package,
+// or an amount in a unit that is converted to that fraction using the product's size
+type APIIngredientRequest struct {
+	Quantity    float64 `json:"quantity"`
+	Amount      float64 `json:"amount"`
+	Unit        string  `json:"unit"`
+	Staple      bool    `json:"staple"`
+	Note        string  `json:"note"`
+	DisplayName string  `json:"displayName"`
+	Section     string  `json:"section"`
+	// Replaces the ingredient's alternatives when set
+	Alternatives *[]string `json:"alternatives"`
+}
+
+func (req APIIngredientRequest) validate() error {
+	if utf8.RuneCountInString(req.Note) > data.IngredientNoteMaxLength {
+		return fmt.Errorf("note is longer than %d characters", data.IngredientNoteMaxLength)
+	} else if utf8.RuneCountInString(req.DisplayName) > data.IngredientDisplayNameMaxLength {
+		return fmt.Errorf("display name is longer than %d characters", data.IngredientDisplayNameMaxLength)
+	} else if utf8.RuneCountInString(req.Section) > data.IngredientSectionMaxLength {
+		return fmt.Errorf("section is longer than %d characters", data.IngredientSectionMaxLength)
+	} else if req.Alternatives != nil {
+		return validateIngredientAlternatives(*req.Alternatives)
+	}
+	return nil
+}
+
+// quantityPercent converts the request quantity to the stored percentage, staples are always a whole product
+func (req APIIngredientRequest) quantityPercent() (int, error) {
+	if req.Staple {
+		return 100, nil
+	}
+	if req.Quantity <= 0 {
+		return 0, fmt.Errorf("invalid quantity: %v", req.Quantity)
+	}
+	return int(req.Quantity * 100), nil
+}
+
+// getAPIList writes an error response and returns false if the list can't be accessed by the account.
+// Lists can only be modified by their owner; recipe lists can be viewed by anyone the recipe is visible to.
+func getAPIList(w http.ResponseWriter, r *http.Request, repo *data.Repository, accountID uuid.UUID, modify bool) (data.List, bool) {
+	listID, err := apiURLParamUUID(r, "id")
+	if err != nil {
+		WriteAPIError(w, http.StatusBadRequest, "%v", err)
+		return data.List{}, false
+	}
+
+	list, err := repo.GetList(r.Context(), listID)
+	if err != nil {
+		writeAPIRepoError(w, err, "getting list")
+		return data.List{}, false
+	}
+
+	if list.AccountID == accountID {
+		return list, true
+	}
+
+	if !modify {
+		recipe, err := repo.GetRecipe(r.Context(), listID, accountID)
+		if err == nil && RecipeVisible(recipe, accountID) {
+			return list, true
+		} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			WriteAPIError(w, http.StatusInternalServerError, "getting recipe: %v", err)
+			return data.List{}, false
+		}
+		WriteAPIError(w, http.StatusNotFound, "not found")
+		return data.List{}, false
+	}
+
+	WriteAPIError(w, http.StatusForbidden, "can't modify lists you didn't create")
+	return data.List{}, false
+}
+
+func NewAPIListsMux(config Config, repo *data.Repository, cache *data.Cache) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+				return
+			}
+
+			filters := []data.ListListsFilter{}
+			if name := r.URL.Query().Get("name"); name != "" {
+				filters = append(filters, data.ListListsFilterByName{Name: name})
+			}
+
+			lists, err := repo.ListLists(r.Context(), authCookies.AccountID, filters, []data.ListListsOrderBy{
+				{Field: "name", Direction: "asc"},
+			})
+			if err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "listing lists: %v", err)
+				return
+			}
+
+			apiLists := []APIList{}
+			for _, list := range lists {
+				apiLists = append(apiLists, newAPIList(list))
+			}
+			WriteAPIJSON(w, http.StatusOK, apiLists)
+		})
+
+		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+				return
+			}
+
+			var req APIListRequest
+			if err := decodeAPIRequest(r, &req); err != nil {
+				WriteAPIError(w, http.StatusBadRequest, "%v", err)
+				return
+			}
+			if err := req.validate(); err != nil {
+				WriteAPIError(w, http.StatusBadRequest, "%v", err)
+				return
+			}
+
+			listID, err := repo.CreateList(r.Context(), authCookies.AccountID, req.Name, req.Description)
+			if err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "creating list: %v", err)
+				return
+			}
+
+			WriteAPIJSON(w, http.StatusCreated, APIList{
+				ID:          listID,
+				AccountID:   authCookies.AccountID,
+				Name:        req.Name,
+				Description: req.Description,
+			})
+		})
+
+		r.Route("/{id}", func(r chi.Router) {
+			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				authCookies, err := GetAuthCookies(r)
+				if err != nil {
+					WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+					return
+				}
+
+				list, ok := getAPIList(w, r, repo, authCookies.AccountID, false)
+				if !ok {
+					return
+				}
+
+				WriteAPIJSON(w, http.StatusOK, newAPIList(list))
+			})
+
+			r.Put("/", func(w http.ResponseWriter, r *http.Request) {
+				authCookies, err := GetAuthCookies(r)
+				if err != nil {
+					WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+					return
+				}
+
+				list, ok := getAPIList(w, r, repo, authCookies.AccountID, true)
+				if !ok {
+					return
+				}
+
+				var req APIListRequest
+				if err := decodeAPIRequest(r, &req); err != nil {
+					WriteAPIError(w, http.StatusBadRequest, "%v", err)
+					return
+				}
+				if err := req.validate(); err != nil {
+					WriteAPIError(w, http.StatusBadRequest, "%v", err)
+					return
+				}
+
+				list.Name = req.Name
+				list.Description = req.Description
+				if err := repo.UpdateList(r.Context(), list); err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "updating list: %v", err)
+					return
+				}
+
+				WriteAPIJSON(w, http.StatusOK, newAPIList(list))
+			})
+
+			r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
+				authCookies, err := GetAuthCookies(r)
+				if err != nil {
+					WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+					return
+				}
+
+				list, ok := getAPIList(w, r, repo, authCookies.AccountID, true)
+				if !ok {
+					return
+				}
+
+				// Recipes must be deleted through the recipes endpoint so their details are removed too
+				if _, err := repo.GetRecipe(r.Context(), list.ID, authCookies.AccountID); err == nil {
+					WriteAPIError(w, http.StatusConflict, "list is a recipe, delete it through /recipes")
+					return
+				} else if !errors.Is(err, sql.ErrNoRows) {
+					WriteAPIError(w, http.StatusInternalServerError, "getting recipe: %v", err)
+					return
+				}
+
+				if err := repo.DeleteList(r.Context(), list.ID); err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "deleting list: %v", err)
+					return
+				}
+
+				WriteAPIJSON(w, http.StatusNoContent, nil)
+			})
+
+			r.Route("/ingredients", NewAPIIngredientsMux(config, repo, cache))
+		})
+	}
+}
+
+func NewAPIIngredientsMux(config Config, repo *data.Repository, cache *data.Cache) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+				return
+			}
+
+			list, ok := getAPIList(w, r, repo, authCookies.AccountID, false)
+			if !ok {
+				return
+			}
+
+			ingredients, err := repo.ListIngredients(r.Context(), list.ID)
+			if err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "listing ingredients: %v", err)
+				return
+			}
+
+			productIDs := []string{}
+			for _, ingredient := range ingredients {
+				productIDs = append(productIDs, ingredient.ProductID)
+			}
+			products, err := hydrateAPIProducts(r.Context(), config, repo, cache, authCookies.AccountID, productIDs)
+			if err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "%v", err)
+				return
+			}
+
+			apiIngredients := []APIIngredient{}
+			for _, ingredient := range ingredients {
+				apiIngredient := newAPIIngredient(ingredient)
+				if product, ok := products[ingredient.ProductID]; ok {
+					apiIngredient.Product = &product
+				}
+				apiIngredients = append(apiIngredients, apiIngredient)
+			}
+			WriteAPIJSON(w, http.StatusOK, apiIngredients)
+		})
+
+		r.Route("/{productID}", func(r chi.Router) {
+			// Create or update the ingredient
+			r.Put("/", func(w http.ResponseWriter, r *http.Request) {
+				authCookies, err := GetAuthCookies(r)
+				if err != nil {
+					WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+					return
+				}
+
+				list, ok := getAPIList(w, r, repo, authCookies.AccountID, true)
+				if !ok {
+					return
+				}
+				productID := chi.URLParam(r, "productID")
+
+				var req APIIngredientRequest
+				if err := decodeAPIRequest(r, &req); err != nil {
+					WriteAPIError(w, http.StatusBadRequest, "%v", err)
+					return
+				}
+				if err := req.validate(); err != nil {
+					WriteAPIError(w, http.StatusBadRequest, "%v", err)
+					return
+				}
+				ingredient := data.Ingredient{
+					ProductID:   productID,
+					ListID:      list.ID,
+					Staple:      req.Staple,
+					Note:        strings.TrimSpace(req.Note),
+					DisplayName: strings.TrimSpace(req.DisplayName),
+					Section:     strings.TrimSpace(req.Section),
+				}
+				if req.Alternatives != nil {
+					ingredient.Alternatives = *req.Alternatives
+				}
+				if req.Unit != "" && !req.Staple {
+					if req.Amount <= 0 {
+						WriteAPIError(w, http.StatusBadRequest, "invalid amount: %v", req.Amount)
+						return
+					}
+					ingredient.Amount, ingredient.Unit = req.Amount, req.Unit
+					ingredient.Quantity, err = ingredientQuantityPercent(r.Context(), config, repo, cache, authCookies.AccountID, productID, parser.Measure{Amount: req.Amount, Unit: req.Unit})
+					if errors.Is(err, errUnconvertibleAmount) {
+						WriteAPIError(w, http.StatusBadRequest, "%v", err)
+						return
+					} else if err != nil {
+						WriteAPIError(w, http.StatusInternalServerError, "converting amount: %v", err)
+						return
+					}
+				} else if ingredient.Quantity, err = req.quantityPercent(); err != nil {
+					WriteAPIError(w, http.StatusBadRequest, "%v", err)
+					return
+				}
+
+				statusCode := http.StatusOK
+				if _, err := repo.GetIngredient(r.Context(), list.ID, productID); errors.Is(err, sql.ErrNoRows) {
+					if err := repo.CreateIngredient(r.Context(), ingredient); err != nil {
+						WriteAPIError(w, http.StatusInternalServerError, "creating ingredient: %v", err)
+						return
+					}
+					statusCode = http.StatusCreated
+				} else if err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "getting ingredient: %v", err)
+					return
+				} else if err := repo.UpdateIngredient(r.Context(), ingredient); err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "updating ingredient: %v", err)
+					return
+				}
+				if statusCode == http.StatusOK && req.Alternatives != nil {
+					if err := repo.SetIngredientAlternatives(r.Context(), list.ID, productID, ingredient.Alternatives); err != nil {
+						WriteAPIError(w, http.StatusInternalServerError, "setting ingredient alternatives: %v", err)
+						return
+					}
+				}
+
+				// Alternatives the request left unchanged are still the ingredient's
+				ingredient, err = repo.GetIngredient(r.Context(), list.ID, productID)
+				if err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "getting ingredient: %v", err)
+					return
+				}
+
+				WriteAPIJSON(w, statusCode, newAPIIngredient(ingredient))
+			})
+
+			r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
+				authCookies, err := GetAuthCookies(r)
+				if err != nil {
+					WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+					return
+				}
+
+				list, ok := getAPIList(w, r, repo, authCookies.AccountID, true)
+				if !ok {
+					return
+				}
+
+				if err := repo.DeleteIngredient(r.Context(), chi.URLParam(r, "productID"), list.ID); err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "deleting ingredient: %v", err)
+					return
+				}
+
+				WriteAPIJSON(w, http.StatusNoContent, nil)
+			})
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/kroger"
+)
+
+type APILocation struct {
+	LocationID string `json:"locationID"`
+	Name       string `json:"name"`
+	Address    string `json:"address"`
+}
+
+func NewAPILocationsMux(config Config, cache *data.Cache) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			zipCode, err := strconv.Atoi(r.URL.Query().Get("zipCode"))
+			if err != nil || zipCode <= 0 {
+				WriteAPIError(w, http.StatusBadRequest, "invalid zip code %q", r.URL.Query().Get("zipCode"))
+				return
+			}
+
+			authClient := kroger.NewAuthorizationClient(http.DefaultClient, kroger.PublicEnvironment, config.ClientID, config.ClientSecret)
+			authResp, err := authClient.PostToken(r.Context(), kroger.ClientCredentials{})
+			if err != nil {
+				WriteAPIError(w, http.StatusBadGateway, "authorizing with kroger: %v", err)
+				return
+			}
+
+			locationsClient := kroger.NewLocationsClient(http.DefaultClient, kroger.PublicEnvironment, authResp.AccessToken)
+			locationsResp, err := locationsClient.GetLocations(r.Context(), &kroger.GetLocationsWithFiltersRequest{
+				Chain: "KROGER",
+				GeographicArea: &kroger.GetLocationsWithZipCodeRequest{
+					ZipCode:       zipCode,
+					RadiusInMiles: RadiusMiles,
+				},
+			})
+			if err != nil {
+				WriteAPIError(w, http.StatusBadGateway, "getting locations: %v", err)
+				return
+			}
+
+			locations := []APILocation{}
+			for _, krogerLocation := range locationsResp.Locations {
+				locations = append(locations, APILocation{
+					LocationID: krogerLocation.LocationID,
+					Name:       krogerLocation.Name,
+					Address: fmt.Sprintf("%s %s %s %s %s",
+						krogerLocation.Address.Line1,
+						krogerLocation.Address.Line2,
+						krogerLocation.Address.City,
+						krogerLocation.Address.State,
+						krogerLocation.Address.ZipCode,
+					),
+				})
+			}
+			WriteAPIJSON(w, http.StatusOK, locations)
+		})
+
+		r.Get("/{locationID}", func(w http.ResponseWriter, r *http.Request) {
+			krogerManager, err := newKrogerManager(r.Context(), config, cache)
+			if err != nil {
+				WriteAPIError(w, http.StatusBadGateway, "creating kroger manager: %v", err)
+				return
+			}
+
+			location, err := krogerManager.GetLocation(r.Context(), chi.URLParam(r, "locationID"))
+			if err != nil {
+				WriteAPIError(w, http.StatusBadGateway, "getting location: %v", err)
+				return
+			}
+
+			WriteAPIJSON(w, http.StatusOK, APILocation{
+				LocationID: location.LocationID,
+				Name:       location.Name,
+				Address:    location.Address,
+			})
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+)
+
+type APIRecipeReview struct {
+	AccountID   uuid.UUID `json:"accountID"`
+	DisplayName *string   `json:"displayName"`
+	Rating      int       `json:"rating"`
+	Review      string    `json:"review"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+func newAPIRecipeReview(review data.RecipeReview) APIRecipeReview {
+	return APIRecipeReview{
+		AccountID:   review.AccountID,
+		DisplayName: review.DisplayName,
+		Rating:      review.Rating,
+		Review:      review.Review,
+		CreatedAt:   review.CreatedAt,
+		UpdatedAt:   review.UpdatedAt,
+	}
+}
+
+type APIRecipeReviewRequest struct {
+	Rating int    `json:"rating"`
+	Review string `json:"review"`
+}
+
+func NewAPIRecipeReviewsMux(repo *data.Repository) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+				return
+			}
+
+			recipe, ok := getAPIRecipe(w, r, repo, authCookies.AccountID)
+			if !ok {
+				return
+			}
+
+			reviews, err := repo.ListRecipeReviews(r.Context(), recipe.ListID)
+			if err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "listing reviews: %v", err)
+				return
+			}
+
+			apiReviews := []APIRecipeReview{}
+			for _, review := range reviews {
+				apiReviews = append(apiReviews, newAPIRecipeReview(review))
+			}
+			WriteAPIJSON(w, http.StatusOK, apiReviews)
+		})
+
+		// Create or update the account's review
+		r.Put("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+				return
+			}
+
+			recipe, ok := getAPIRecipe(w, r, repo, authCookies.AccountID)
+			if !ok {
+				return
+			}
+			if !RecipeReviewable(recipe, authCookies.AccountID) {
+				WriteAPIError(w, http.StatusForbidden, "can't review your own recipes")
+				return
+			}
+
+			var req APIRecipeReviewRequest
+			if err := decodeAPIRequest(r, &req); err != nil {
+				WriteAPIError(w, http.StatusBadRequest, "%v", err)
+				return
+			}
+			req.Review = strings.TrimSpace(req.Review)
+			if err := validateRecipeReview(req.Rating, req.Review); err != nil {
+				WriteAPIError(w, http.StatusBadRequest, "%v", err)
+				return
+			}
+
+			if err := repo.SetRecipeReview(r.Context(), recipe.ListID, authCookies.AccountID, req.Rating, req.Review); err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "saving review: %v", err)
+				return
+			}
+
+			reviews, err := repo.ListRecipeReviews(r.Context(), recipe.ListID)
+			if err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "listing reviews: %v", err)
+				return
+			}
+			for _, review := range reviews {
+				if review.AccountID == authCookies.AccountID {
+					WriteAPIJSON(w, http.StatusOK, newAPIRecipeReview(review))
+					return
+				}
+			}
+			WriteAPIError(w, http.StatusInternalServerError, "review missing after saving")
+		})
+
+		r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+				return
+			}
+
+			listID, err := apiURLParamUUID(r, "id")
+			if err != nil {
+				WriteAPIError(w, http.StatusBadRequest, "%v", err)
+				return
+			}
+
+			if err := repo.DeleteRecipeReview(r.Context(), listID, authCookies.AccountID); err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "deleting review: %v", err)
+				return
+			}
+
+			WriteAPIJSON(w, http.StatusNoContent, nil)
+		})
+	}
+}
@@ -0,0 +1,374 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+)
+
+type APIRecipe struct {
+	ID              uuid.UUID `json:"id"`
+	AccountID       uuid.UUID `json:"accountID"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description"`
+	InstructionType string    `json:"instructionType"`
+	Instructions    string    `json:"instructions"`
+	Visibility      string    `json:"visibility"`
+	Favorite        bool      `json:"favorite"`
+	Tags            []string  `json:"tags"`
+	Rating          float64   `json:"rating"`
+	ReviewCount     int       `json:"reviewCount"`
+	// SourceID is the recipe this was forked from, omitted when the source can't be viewed
+	SourceID  *uuid.UUID `json:"sourceID,omitempty"`
+	ForkCount int        `json:"forkCount"`
+	// Steps of text instructions, only included for a single recipe
+	Steps []APIRecipeStep `json:"steps,omitempty"`
+}
+
+type APIRecipeStep struct {
+	Number       int      `json:"number"`
+	Text         string   `json:"text"`
+	TimerSeconds int      `json:"timerSeconds"`
+	ProductIDs   []string `json:"productIDs"`
+}
+
+func newAPIRecipe(recipe data.Recipe) APIRecipe {
+	apiRecipe := APIRecipe{
+		ID:              recipe.ListID,
+		AccountID:       recipe.AccountID,
+		Name:            recipe.Name,
+		Description:     recipe.Description,
+		InstructionType: recipe.InstructionType,
+		Instructions:    recipe.Instructions,
+		Visibility:      recipe.Visibility,
+		Favorite:        recipe.Favorite,
+		Tags:            recipe.Tags,
+		Rating:          recipe.Rating,
+		ReviewCount:     recipe.ReviewCount,
+		ForkCount:       recipe.ForkCount,
+	}
+	if recipe.SourceName != nil {
+		apiRecipe.SourceID = recipe.SourceListID
+	}
+	for _, step := range recipe.Steps {
+		apiStep := APIRecipeStep{
+			Number:       step.Number,
+			Text:         step.Text,
+			TimerSeconds: step.TimerSeconds,
+			ProductIDs:   step.ProductIDs,
+		}
+		if apiStep.ProductIDs == nil {
+			apiStep.ProductIDs = []string{}
+		}
+		apiRecipe.Steps = append(apiRecipe.Steps, apiStep)
+	}
+	if apiRecipe.Tags == nil {
+		apiRecipe.Tags = []string{}
+	}
+	return apiRecipe
+}
+
+func newAPIRecipes(recipes []data.Recipe) []APIRecipe {
+	apiRecipes := []APIRecipe{}
+	for _, recipe := range recipes {
+		apiRecipes = append(apiRecipes, newAPIRecipe(recipe))
+	}
+	return apiRecipes
+}
+
+type APIRecipeRequest struct {
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	InstructionType string `json:"instructionType"`
+	Instructions    string `json:"instructions"`
+	Visibility      string `json:"visibility"`
+	// Tags replace the recipe's tags when present
+	Tags *[]string `json:"tags"`
+	// Steps replace the text instructions when present, otherwise they're split from the instructions' lines
+	Steps *[]APIRecipeStep `json:"steps"`
+}
+
+// steps is nil when the request has no steps
+func (req *APIRecipeRequest) steps() []data.RecipeStep {
+	if req.Steps == nil {
+		return nil
+	}
+	steps := []data.RecipeStep{}
+	for i, step := range *req.Steps {
+		steps = append(steps, data.RecipeStep{
+			Number:       i + 1,
+			Text:         strings.TrimSpace(step.Text),
+			TimerSeconds: step.TimerSeconds,
+			ProductIDs:   step.ProductIDs,
+		})
+	}
+	return steps
+}
+
+// validate checks the request, whose steps can only use the recipe's ingredients
+func (req *APIRecipeRequest) validate(ingredients []data.Ingredient) error {
+	if req.Name == "" {
+		return fmt.Errorf("name missing")
+	} else if utf8.RuneCountInString(req.Name) > data.ListNameMaxLength {
+		return fmt.Errorf("name is longer than %d characters", data.ListNameMaxLength)
+	} else if utf8.RuneCountInString(req.Description) > data.ListDescriptionMaxLength {
+		return fmt.Errorf("description is longer than %d characters", data.ListDescriptionMaxLength)
+	}
+	if req.InstructionType == "" {
+		req.InstructionType = data.InstructionTypeNone
+	}
+	if !validOption(instructionTypes, req.InstructionType) {
+		return fmt.Errorf("invalid instruction type %q", req.InstructionType)
+	}
+	if steps := req.steps(); steps != nil && req.InstructionType == data.InstructionTypeText {
+		if err := validateRecipeSteps(steps, ingredients); err != nil {
+			return err
+		}
+		req.Instructions = data.JoinRecipeSteps(steps)
+	}
+	if req.InstructionType == data.InstructionTypeNone {
+		req.Instructions = ""
+	} else if req.Instructions == "" {
+		return fmt.Errorf("instructions missing")
+	}
+	if err := validateRecipeInstructionsLink(req.InstructionType, req.Instructions); err != nil {
+		return err
+	}
+	if !validOption(visibilities, req.Visibility) {
+		return fmt.Errorf("invalid visibility %q", req.Visibility)
+	}
+	if req.Tags != nil && len(*req.Tags) > data.RecipeTagsMax {
+		return fmt.Errorf("a recipe can have at most %d tags", data.RecipeTagsMax)
+	}
+	return nil
+}
+
+// getAPIRecipe writes an error response and returns false if the recipe can't be viewed by the account
+func getAPIRecipe(w http.ResponseWriter, r *http.Request, repo *data.Repository, accountID uuid.UUID) (data.Recipe, bool) {
+	listID, err := apiURLParamUUID(r, "id")
+	if err != nil {
+		WriteAPIError(w, http.StatusBadRequest, "%v", err)
+		return data.Recipe{}, false
+	}
+
+	recipe, err := repo.GetRecipe(r.Context(), listID, accountID)
+	if err != nil {
+		writeAPIRepoError(w, err, "getting recipe")
+		return data.Recipe{}, false
+	}
+
+	if !RecipeVisible(recipe, accountID) {
+		WriteAPIError(w, http.StatusNotFound, "not found")
+		return data.Recipe{}, false
+	}
+	return recipe, true
+}
+
+func NewAPIRecipesMux(config Config, repo *data.Repository, cache *data.Cache) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+				return
+			}
+
+			query := r.URL.Query()
+			filters := []data.ListRecipesFilter{}
+			if query.Has("accountID") {
+				accountID, err := uuid.Parse(query.Get("accountID"))
+				if err != nil {
+					WriteAPIError(w, http.StatusBadRequest, "invalid accountID: %v", err)
+					return
+				}
+				filters = append(filters, data.ListRecipesFilterByAccountID{AccountID: accountID})
+			}
+			if name := query.Get("name"); name != "" {
+				filters = append(filters, data.ListRecipesFilterByName{Name: name})
+			}
+			orderBys := []data.ListRecipesOrderBy{}
+			if query.Get("sort") == "rating" {
+				orderBys = append(orderBys, recipeRatingOrderBys...)
+			}
+			if search := query.Get("search"); search != "" {
+				filters = append(filters, data.ListRecipesFilterBySearch{Search: search})
+				orderBys = append(orderBys, data.ListRecipesOrderBy{Field: "search_rank", Direction: "desc"})
+			}
+			if query.Get("favorites") == "true" {
+				filters = append(filters, data.ListRecipesFilterByFavorites{})
+			}
+			recipeVisibilities := visibilities
+			if query.Has("visibility") {
+				recipeVisibilities = query["visibility"]
+			}
+			filters = append(filters, data.ListRecipesFilterByVisibilities{Visibilities: recipeVisibilities})
+			if query.Has("productID") {
+				filters = append(filters, data.ListRecipesFilterByProducts{ProductIDs: query["productID"], All: query.Get("productMatch") == "all"})
+			}
+			if query.Has("tag") {
+				filters = append(filters, data.ListRecipesFilterByTags{Tags: query["tag"], All: query.Get("tagMatch") == "all"})
+			}
+
+			recipes, err := repo.ListRecipes(r.Context(), authCookies.AccountID, filters, append(orderBys, data.ListRecipesOrderBy{
+				Field: "name", Direction: "asc",
+			}))
+			if err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "listing recipes: %v", err)
+				return
+			}
+
+			WriteAPIJSON(w, http.StatusOK, newAPIRecipes(recipes))
+		})
+
+		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+				return
+			}
+
+			var req APIRecipeRequest
+			if err := decodeAPIRequest(r, &req); err != nil {
+				WriteAPIError(w, http.StatusBadRequest, "%v", err)
+				return
+			}
+			// A new recipe has no ingredients for its steps to use yet
+			if err := req.validate(nil); err != nil {
+				WriteAPIError(w, http.StatusBadRequest, "%v", err)
+				return
+			}
+
+			listID, err := repo.CreateRecipe(r.Context(), authCookies.AccountID, req.Name, req.Description, req.InstructionType, req.Instructions, req.Visibility, req.steps())
+			if err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "creating recipe: %v", err)
+				return
+			}
+			if req.Tags != nil {
+				if err := repo.SetRecipeTags(r.Context(), listID, *req.Tags); err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "setting recipe tags: %v", err)
+					return
+				}
+			}
+
+			recipe, err := repo.GetRecipe(r.Context(), listID, authCookies.AccountID)
+			if err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "getting recipe: %v", err)
+				return
+			}
+
+			WriteAPIJSON(w, http.StatusCreated, newAPIRecipe(recipe))
+		})
+
+		r.Route("/{id}", func(r chi.Router) {
+			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				authCookies, err := GetAuthCookies(r)
+				if err != nil {
+					WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+					return
+				}
+
+				recipe, ok := getAPIRecipe(w, r, repo, authCookies.AccountID)
+				if !ok {
+					return
+				}
+
+				WriteAPIJSON(w, http.StatusOK, newAPIRecipe(recipe))
+			})
+
+			r.Put("/", func(w http.ResponseWriter, r *http.Request) {
+				authCookies, err := GetAuthCookies(r)
+				if err != nil {
+					WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+					return
+				}
+
+				recipe, ok := getAPIRecipe(w, r, repo, authCookies.AccountID)
+				if !ok {
+					return
+				}
+				if recipe.AccountID != authCookies.AccountID {
+					WriteAPIError(w, http.StatusForbidden, "can't update recipes you didn't create")
+					return
+				}
+
+				var req APIRecipeRequest
+				if err := decodeAPIRequest(r, &req); err != nil {
+					WriteAPIError(w, http.StatusBadRequest, "%v", err)
+					return
+				}
+				ingredients, err := repo.ListIngredients(r.Context(), recipe.ListID)
+				if err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "listing recipe ingredients: %v", err)
+					return
+				}
+				if err := req.validate(ingredients); err != nil {
+					WriteAPIError(w, http.StatusBadRequest, "%v", err)
+					return
+				}
+
+				// Steps are kept when the instructions don't change, so their timers and ingredients aren't lost
+				if steps := req.steps(); steps != nil || req.Instructions != recipe.Instructions {
+					recipe.Steps = steps
+				}
+				recipe.Name = req.Name
+				recipe.Description = req.Description
+				recipe.InstructionType = req.InstructionType
+				recipe.Instructions = req.Instructions
+				recipe.Visibility = req.Visibility
+				if err := repo.UpdateRecipe(r.Context(), recipe); err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "updating recipe: %v", err)
+					return
+				}
+				if req.Tags != nil {
+					if err := repo.SetRecipeTags(r.Context(), recipe.ListID, *req.Tags); err != nil {
+						WriteAPIError(w, http.StatusInternalServerError, "setting recipe tags: %v", err)
+						return
+					}
+				}
+
+				recipe, err = repo.GetRecipe(r.Context(), recipe.ListID, authCookies.AccountID)
+				if err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "getting recipe: %v", err)
+					return
+				}
+
+				WriteAPIJSON(w, http.StatusOK, newAPIRecipe(recipe))
+			})
+
+			r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
+				authCookies, err := GetAuthCookies(r)
+				if err != nil {
+					WriteAPIError(w, http.StatusUnauthorized, "%v", err)
+					return
+				}
+
+				recipe, ok := getAPIRecipe(w, r, repo, authCookies.AccountID)
+				if !ok {
+					return
+				}
+				if recipe.AccountID != authCookies.AccountID {
+					WriteAPIError(w, http.StatusForbidden, "can't delete recipes you didn't create")
+					return
+				}
+
+				if err := repo.DeleteRecipe(r.Context(), recipe.ListID); err != nil {
+					WriteAPIError(w, http.StatusInternalServerError, "deleting recipe: %v", err)
+					return
+				}
+
+				WriteAPIJSON(w, http.StatusNoContent, nil)
+			})
+
+			r.Route("/reviews", NewAPIRecipeReviewsMux(repo))
+
+			// Recipes are lists, so their ingredients are managed the same way
+			r.Route("/ingredients", NewAPIIngredientsMux(config, repo, cache))
+		})
+	}
+}
@@ -2,7 +2,11 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"database/sql"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -17,13 +21,84 @@ import (
 	"github.com/google/uuid"
 )
 
-func NewAuthMux(config Config, repo *data.Repository) func(r chi.Router) {
+const (
+	LoginStateCookie     = "loginState"
+	LoginStateMaxAge     = 10 * time.Minute
+	LoginReturnToDefault = "/"
+)
+
+func NewAuthMux(config Config, repo *data.Repository, cache *data.Cache) func(r chi.Router) {
 	return func(r chi.Router) {
+		// Start a login, the state and PKCE verifier are checked when Kroger redirects back to the callback
+		r.Get("/login", func(w http.ResponseWriter, r *http.Request) {
+			state, err := randomURLString()
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Unable to generate login state: %v", err), http.StatusInternalServerError)
+				return
+			}
+			codeVerifier, err := randomURLString()
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Unable to generate code verifier: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			if err := cache.StoreLoginState(r.Context(), state, data.LoginState{
+				CodeVerifier: codeVerifier,
+				ReturnTo:     safeReturnTo(r.FormValue("return_to")),
+			}, LoginStateMaxAge); err != nil {
+				http.Error(w, fmt.Sprintf("Unable to store login state: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			// Binds the login to this browser, a callback carrying someone else's state is rejected
+			http.SetCookie(w, &http.Cookie{
+				Path:     "/auth",
+				Name:     LoginStateCookie,
+				Value:    state,
+				MaxAge:   int(LoginStateMaxAge.Seconds()),
+				Secure:   true,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+			http.Redirect(w, r, LoginRedirectURL(config, state, codeChallenge(codeVerifier), kroger.ScopeCartBasicWrite, kroger.ScopeProfileCompact), http.StatusFound)
+		})
+
 		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			state := r.FormValue("state")
+			stateCookie, err := r.Cookie(LoginStateCookie)
+			if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(stateCookie.Value), []byte(state)) != 1 {
+				http.Error(w, "Login state mismatch, please sign in again", http.StatusBadRequest)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{
+				Path:     "/auth",
+				Name:     LoginStateCookie,
+				Value:    "",
+				Expires:  time.Now(),
+				Secure:   true,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+
+			loginState, err := cache.TakeLoginState(r.Context(), state)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Unable to get login state: %v", err), http.StatusInternalServerError)
+				return
+			} else if loginState == nil {
+				http.Error(w, "Login expired, please sign in again", http.StatusBadRequest)
+				return
+			}
+
+			if errMsg := r.FormValue("error"); errMsg != "" {
+				http.Error(w, fmt.Sprintf("Kroger login failed: %s", errMsg), http.StatusUnauthorized)
+				return
+			}
+
 			authClient := kroger.NewAuthorizationClient(http.DefaultClient, kroger.PublicEnvironment, config.ClientID, config.ClientSecret)
 			authResp, err := authClient.PostToken(r.Context(), kroger.AuthorizationCode{
-				Code:        r.FormValue("code"),
-				RedirectURI: config.RedirectUrl(),
+				Code:         r.FormValue("code"),
+				RedirectURI:  config.RedirectUrl(),
+				CodeVerifier: loginState.CodeVerifier,
 			})
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -46,7 +121,14 @@ func NewAuthMux(config Config, repo *data.Repository) func(r chi.Router) {
 				http.Error(w, fmt.Sprintf("Unable to get account: %v", err), http.StatusInternalServerError)
 				return
 			}
-			session, err := repo.CreateSession(r.Context(), account.ID)
+			// Signing in again replaces the browser's previous session
+			if sessionID, ok, err := sessionIDFromCookie(r); err == nil && ok {
+				if err := repo.DeleteSession(r.Context(), sessionID); err != nil {
+					http.Error(w, fmt.Sprintf("Unable to delete previous session: %v", err), http.StatusInternalServerError)
+					return
+				}
+			}
+			session, err := repo.CreateSession(r.Context(), account.ID, r.UserAgent(), SessionLifetime)
 			if err != nil {
 				http.Error(w, fmt.Sprintf("Unable to create session: %v", err), http.StatusInternalServerError)
 				return
@@ -55,10 +137,17 @@ func NewAuthMux(config Config, repo *data.Repository) func(r chi.Router) {
 				http.Error(w, fmt.Sprintf("Unable to set auth cookies: %v", err), http.StatusInternalServerError)
 				return
 			}
-			http.Redirect(w, r, "/", http.StatusFound)
+			storeKrogerTokens(r.Context(), config, account.ID, authResp)
+			http.Redirect(w, r, safeReturnTo(loginState.ReturnTo), http.StatusFound)
 		})
 
 		r.Post("/logout", func(w http.ResponseWriter, r *http.Request) {
+			if sessionID, ok, err := sessionIDFromCookie(r); err == nil && ok {
+				if err := repo.DeleteSession(r.Context(), sessionID); err != nil {
+					http.Error(w, fmt.Sprintf("Unable to delete session: %v", err), http.StatusInternalServerError)
+					return
+				}
+			}
 			ClearAuthCookies(w)
 		})
 	}
@@ -68,14 +157,53 @@ func (c Config) RedirectUrl() string {
 	return fmt.Sprintf("%s/auth", c.Domain)
 }
 
-func LoginRedirectURL(config Config, scopes ...string) string {
-	scopesURIEncoded := url.QueryEscape(strings.Join(scopes, " "))
-	return fmt.Sprintf("%s/authorize?client_id=%s&redirect_uri=%s&response_type=code&scope=%s",
-		kroger.OAuth2BaseURL,
-		config.ClientID,
-		config.RedirectUrl(),
-		scopesURIEncoded,
-	)
+func LoginRedirectURL(config Config, state, codeChallenge string, scopes ...string) string {
+	values := url.Values{}
+	values.Set("client_id", config.ClientID)
+	values.Set("redirect_uri", config.RedirectUrl())
+	values.Set("response_type", "code")
+	values.Set("scope", strings.Join(scopes, " "))
+	values.Set("state", state)
+	values.Set("code_challenge", codeChallenge)
+	values.Set("code_challenge_method", "S256")
+	return fmt.Sprintf("%s/authorize?%s", kroger.OAuth2BaseURL, values.Encode())
+}
+
+func randomURLString() (string, error) {
+	value := make([]byte, 32)
+	if _, err := rand.Read(value); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(value), nil
+}
+
+func codeChallenge(codeVerifier string) string {
+	hash := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// safeReturnTo only allows paths on this site, so the login can't be used as an open redirect
+func safeReturnTo(returnTo string) string {
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") || strings.HasPrefix(returnTo, "/\\") {
+		return LoginReturnToDefault
+	}
+	if returnTo == "/auth" || strings.HasPrefix(returnTo, "/auth/") || strings.HasPrefix(returnTo, "/auth?") {
+		return LoginReturnToDefault
+	}
+	return returnTo
+}
+
+// loginURL starts a login that returns to the page the request came from
+func loginURL(r *http.Request) string {
+	returnTo := r.URL.RequestURI()
+	if r.Header.Get("HX-Request") != "" || r.Method != http.MethodGet {
+		// Partial and modifying requests return to the page they were sent from
+		returnTo = LoginReturnToDefault
+		if currentURL, err := url.Parse(r.Header.Get("HX-Current-URL")); err == nil && currentURL.Path != "" {
+			returnTo = currentURL.RequestURI()
+		}
+	}
+	return "/auth/login?" + url.Values{"return_to": {safeReturnTo(returnTo)}}.Encode()
 }
 
 type ContextAuthCookies struct{}
@@ -85,10 +213,14 @@ type AuthCookies struct {
 	RefreshToken string
 	SessionID    uuid.UUID
 	AccountID    uuid.UUID
+	// TokenScope is set when the request was authenticated with a personal access token,
+	// such requests have no Kroger session
+	TokenScope string
 }
 
-func RedirectToLogin(w http.ResponseWriter, r *http.Request, url string, err error) {
+func RedirectToLogin(w http.ResponseWriter, r *http.Request, err error) {
 	slog.Error("failed to authenticate response", "error", err)
+	url := loginURL(r)
 	if r.Header.Get("HX-Request") != "" {
 		w.Header().Add("HX-Redirect", url)
 	} else {
@@ -97,20 +229,76 @@ func RedirectToLogin(w http.ResponseWriter, r *http.Request, url string, err err
 }
 
 func AuthenticationMiddleware(config Config, repo *data.Repository) func(next http.Handler) http.Handler {
-	loginRedirectURL := LoginRedirectURL(config, kroger.ScopeCartBasicWrite, kroger.ScopeProfileCompact)
+	return authenticationMiddleware(config, repo, RedirectToLogin)
+}
+
+// APIAuthenticationMiddleware accepts a personal access token as a bearer token,
+// otherwise it authenticates the same way as AuthenticationMiddleware,
+// but responds with a JSON error instead of redirecting to the login page
+func APIAuthenticationMiddleware(config Config, repo *data.Repository) func(next http.Handler) http.Handler {
+	sessionAuthentication := authenticationMiddleware(config, repo, func(w http.ResponseWriter, r *http.Request, err error) {
+		slog.Error("failed to authenticate api request", "error", err)
+		WriteAPIError(w, http.StatusUnauthorized, "unauthenticated")
+	})
+	return func(next http.Handler) http.Handler {
+		sessionNext := sessionAuthentication(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				sessionNext.ServeHTTP(w, r)
+				return
+			}
+
+			accessToken, err := repo.GetAccessTokenByHash(r.Context(), HashAccessToken(token))
+			if errors.Is(err, sql.ErrNoRows) {
+				WriteAPIError(w, http.StatusUnauthorized, "invalid access token")
+				return
+			} else if err != nil {
+				WriteAPIError(w, http.StatusInternalServerError, "getting access token: %v", err)
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+			default:
+				if accessToken.Scope != data.AccessTokenScopeWrite {
+					WriteAPIError(w, http.StatusForbidden, "access token is read-only")
+					return
+				}
+			}
+
+			if err := repo.TouchAccessToken(r.Context(), accessToken.ID); err != nil {
+				slog.Error("updating access token last used", "error", err)
+			}
+
+			ctx := context.WithValue(r.Context(), ContextAuthCookies{}, AuthCookies{
+				AccountID:  accessToken.AccountID,
+				TokenScope: accessToken.Scope,
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// HasKrogerSession reports whether the request carries a linked Kroger browser session,
+// which is needed for actions taken on the user's Kroger account
+func (a AuthCookies) HasKrogerSession() bool {
+	return a.AccessToken != ""
+}
+
+func authenticationMiddleware(config Config, repo *data.Repository, unauthenticated func(w http.ResponseWriter, r *http.Request, err error)) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			refreshToken := ""
 			refreshTokenCookie, err := r.Cookie("refreshToken")
 			if err != nil || refreshTokenCookie.Value == "" || refreshTokenCookie.Valid() != nil {
-				RedirectToLogin(w, r, loginRedirectURL, errors.New("refreshToken missing"))
+				unauthenticated(w, r, errors.New("refreshToken missing"))
 				return
 			}
 			refreshToken = refreshTokenCookie.Value
 
 			accessToken := ""
-			sessionID := uuid.Nil
-			accountID := uuid.Nil
+			var session data.Session
 			accessTokenCookie, err := r.Cookie("accessToken")
 			if err != nil || accessTokenCookie.Value == "" || accessTokenCookie.Valid() != nil {
 				authClient := kroger.NewAuthorizationClient(http.DefaultClient, kroger.PublicEnvironment, config.ClientID, config.ClientSecret)
@@ -118,99 +306,145 @@ func AuthenticationMiddleware(config Config, repo *data.Repository) func(next ht
 					RefreshToken: refreshToken,
 				})
 				if err != nil {
-					RedirectToLogin(w, r, loginRedirectURL, err)
+					unauthenticated(w, r, err)
 					return
 				}
 				identityClient := kroger.NewIdentityClient(http.DefaultClient, kroger.PublicEnvironment, authResp.AccessToken)
 				profileResp, err := identityClient.GetProfile(r.Context())
 				if err != nil {
-					RedirectToLogin(w, r, loginRedirectURL, fmt.Errorf("unable to get kroger profile id: %w", err))
+					unauthenticated(w, r, fmt.Errorf("unable to get kroger profile id: %w", err))
 					return
 				}
 				account, err := repo.GetAccountByKrogerProfileID(r.Context(), profileResp.Profile.ID)
 				// Refrsh token exists, the user has logged in before and should have an account already
 				if err != nil {
-					RedirectToLogin(w, r, loginRedirectURL, fmt.Errorf("unable to get account: %w", err))
+					unauthenticated(w, r, fmt.Errorf("unable to get account: %w", err))
 					return
 				}
-				session, err := repo.CreateSession(r.Context(), account.ID)
+				// Refreshing keeps the browser's existing session
+				session, err = getOrCreateSession(r, repo, account.ID)
 				if err != nil {
-					RedirectToLogin(w, r, loginRedirectURL, fmt.Errorf("unable to create session: %w", err))
+					unauthenticated(w, r, err)
 					return
 				}
 				if err := SetAuthResponseCookies(r.Context(), w, session, authResp); err != nil {
-					RedirectToLogin(w, r, loginRedirectURL, fmt.Errorf("unable to set auth cookies: %w", err))
+					unauthenticated(w, r, fmt.Errorf("unable to set auth cookies: %w", err))
 					return
 				}
+				storeKrogerTokens(r.Context(), config, account.ID, authResp)
 
 				refreshToken = authResp.RefreshToken
 				accessToken = authResp.AccessToken
-				sessionID = session.ID
-				accountID = account.ID
 			} else {
 				accessToken = accessTokenCookie.Value
-			}
 
-			// Session not created when getting new access token
-			if sessionID == uuid.Nil {
-				// Session ID cookie exists
-				if sessionIDCookie, err := r.Cookie("sessionID"); err == nil && sessionIDCookie.Value != "" && sessionIDCookie.Valid() == nil {
-					sessionID, err = uuid.Parse(sessionIDCookie.Value)
-					if err != nil {
-						RedirectToLogin(w, r, loginRedirectURL, fmt.Errorf("unable to parse session id: %w", err))
-						return
-					}
-					session, err := repo.GetSessionByID(r.Context(), sessionID)
+				sessionID, ok, err := sessionIDFromCookie(r)
+				if err != nil {
+					unauthenticated(w, r, err)
+					return
+				}
+				if ok {
+					session, err = repo.GetSessionByID(r.Context(), sessionID)
 					if err != nil {
-						RedirectToLogin(w, r, loginRedirectURL, fmt.Errorf("unable to create session: %w", err))
+						unauthenticated(w, r, fmt.Errorf("unable to get session: %w", err))
 						return
 					}
-					accountID = session.AccountID
 				} else { // Session ID cookie missing
 					identityClient := kroger.NewIdentityClient(http.DefaultClient, kroger.PublicEnvironment, accessToken)
 					profileResp, err := identityClient.GetProfile(r.Context())
 					if err != nil {
-						RedirectToLogin(w, r, loginRedirectURL, fmt.Errorf("unable to get kroger profile id: %w", err))
+						unauthenticated(w, r, fmt.Errorf("unable to get kroger profile id: %w", err))
 						return
 					}
 					account, err := repo.GetAccountByKrogerProfileID(r.Context(), profileResp.Profile.ID)
 					// Refrsh token exists, the user has logged in before and should have an account already
 					if err != nil {
-						RedirectToLogin(w, r, loginRedirectURL, fmt.Errorf("unable to get account: %w", err))
+						unauthenticated(w, r, fmt.Errorf("unable to get account: %w", err))
 						return
 					}
-					session, err := repo.CreateSession(r.Context(), account.ID)
+					session, err = repo.CreateSession(r.Context(), account.ID, r.UserAgent(), SessionLifetime)
 					if err != nil {
-						RedirectToLogin(w, r, loginRedirectURL, fmt.Errorf("unable to create session: %w", err))
+						unauthenticated(w, r, fmt.Errorf("unable to create session: %w", err))
 						return
 					}
-					sessionID = session.ID
-					accountID = account.ID
-
-					http.SetCookie(w, &http.Cookie{
-						Path:     "/",
-						Name:     "sessionID",
-						Value:    session.ID.String(),
-						Secure:   true,
-						HttpOnly: true,
-						SameSite: http.SameSiteLaxMode,
-					})
+					setSessionCookie(w, session)
 				}
 			}
 
+			if err := repo.TouchSession(r.Context(), session.ID, SessionLifetime); err != nil {
+				slog.Error("updating session last seen", "error", err)
+			}
+
 			ctx := context.WithValue(r.Context(), ContextAuthCookies{}, AuthCookies{
 				AccessToken:  accessToken,
 				RefreshToken: refreshToken,
-				SessionID:    sessionID,
-				AccountID:    accountID,
+				SessionID:    session.ID,
+				AccountID:    session.AccountID,
 			})
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// storeKrogerTokens keeps the vault up to date with the newest tokens,
+// failures are only logged since the browser's cookies still work
+func storeKrogerTokens(ctx context.Context, config Config, accountID uuid.UUID, credentials *kroger.PostTokenResponse) {
+	if config.TokenVault == nil {
+		return
+	}
+	token := kroger.NewToken(credentials)
+	if err := config.TokenVault.Store(ctx, accountID, data.KrogerTokens{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		ExpiresAt:    token.Expiry,
+	}); err != nil {
+		slog.Error("storing kroger tokens", "error", err)
+	}
+}
+
+// sessionIDFromCookie returns false if the browser has no session cookie
+func sessionIDFromCookie(r *http.Request) (uuid.UUID, bool, error) {
+	sessionIDCookie, err := r.Cookie("sessionID")
+	if err != nil || sessionIDCookie.Value == "" || sessionIDCookie.Valid() != nil {
+		return uuid.Nil, false, nil
+	}
+	sessionID, err := uuid.Parse(sessionIDCookie.Value)
+	if err != nil {
+		return uuid.Nil, false, fmt.Errorf("unable to parse session id: %w", err)
+	}
+	return sessionID, true, nil
+}
+
+// getOrCreateSession returns the browser's session for the account, creating one if the browser has none.
+// A session cookie for a revoked or expired session is an error, so revoking a session also ends its refreshes.
+func getOrCreateSession(r *http.Request, repo *data.Repository, accountID uuid.UUID) (data.Session, error) {
+	sessionID, ok, err := sessionIDFromCookie(r)
+	if err != nil {
+		return data.Session{}, err
+	}
+	if !ok {
+		session, err := repo.CreateSession(r.Context(), accountID, r.UserAgent(), SessionLifetime)
+		if err != nil {
+			return data.Session{}, fmt.Errorf("unable to create session: %w", err)
+		}
+		return session, nil
+	}
+
+	session, err := repo.GetSessionByID(r.Context(), sessionID)
+	if err != nil {
+		return data.Session{}, fmt.Errorf("unable to get session: %w", err)
+	}
+	if session.AccountID != accountID {
+		return data.Session{}, errors.New("session belongs to a different account")
+	}
+	return session, nil
+}
+
 const RefreshTokenMaxAgeSeconds = 15_768_000 // 6 months in seconds, taken from an FAQ response
 
+// SessionLifetime matches the refresh token, a session expires once it can no longer be refreshed
+const SessionLifetime = RefreshTokenMaxAgeSeconds * time.Second
+
 func SetAuthResponseCookies(ctx context.Context, w http.ResponseWriter, session data.Session, credentials *kroger.PostTokenResponse) error {
 	http.SetCookie(w, &http.Cookie{
 		Path:     "/",
@@ -230,16 +464,20 @@ func SetAuthResponseCookies(ctx context.Context, w http.ResponseWriter, session
 		HttpOnly: true,
 		SameSite: http.SameSiteLaxMode,
 	})
+	setSessionCookie(w, session)
+	return nil
+}
+
+func setSessionCookie(w http.ResponseWriter, session data.Session) {
 	http.SetCookie(w, &http.Cookie{
 		Path:     "/",
 		Name:     "sessionID",
 		Value:    session.ID.String(),
-		MaxAge:   credentials.ExpiresIn,
+		MaxAge:   RefreshTokenMaxAgeSeconds,
 		Secure:   true,
 		HttpOnly: true,
 		SameSite: http.SameSiteLaxMode,
 	})
-	return nil
 }
 
 func ClearAuthCookies(w http.ResponseWriter) {
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/densestvoid/krogerrecipeshopper/app"
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/templates"
+)
+
+const bundleMaxBytes = 10 << 20
+
+var bundleDuplicates = []string{data.BundleDuplicatesSkip, data.BundleDuplicatesRename}
+
+func NewBundlesMux(repo *data.Repository) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/selection", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			recipes, err := repo.ListRecipes(r.Context(), authCookies.AccountID, []data.ListRecipesFilter{
+				data.ListRecipesFilterByAccountID{AccountID: authCookies.AccountID},
+			}, []data.ListRecipesOrderBy{{Field: "name", Direction: "asc"}})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			lists, err := repo.ListLists(r.Context(), authCookies.AccountID, nil, []data.ListListsOrderBy{{Field: "name", Direction: "asc"}})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			if err := templates.BundleExportSelection(recipes, lists).Render(w); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+
+		// Exports the recipes and lists given by recipeID and listID, or all of the account's when neither is given
+		r.Get("/export", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			var bundle data.Bundle
+			if !r.Form.Has("recipeID") && !r.Form.Has("listID") {
+				if bundle, err = repo.ExportAccountBundle(r.Context(), authCookies.AccountID); err != nil {
+					http.Error(w, fmt.Sprintf("exporting bundle: %v", err), http.StatusInternalServerError)
+					return
+				}
+			} else {
+				recipes := []data.Recipe{}
+				for _, id := range r.Form["recipeID"] {
+					listID, err := uuid.Parse(id)
+					if err != nil {
+						http.Error(w, fmt.Sprintf("parsing recipe id: %v", err), http.StatusBadRequest)
+						return
+					}
+					recipe, err := repo.GetRecipe(r.Context(), listID, authCookies.AccountID)
+					if err != nil {
+						http.Error(w, err.Error(), http.StatusInternalServerError)
+						return
+					} else if !RecipeVisible(recipe, authCookies.AccountID) {
+						http.Error(w, "not found", http.StatusNotFound)
+						return
+					}
+					recipes = append(recipes, recipe)
+				}
+
+				lists := []data.List{}
+				for _, id := range r.Form["listID"] {
+					listID, err := uuid.Parse(id)
+					if err != nil {
+						http.Error(w, fmt.Sprintf("parsing list id: %v", err), http.StatusBadRequest)
+						return
+					}
+					// Recipes are exported with their recipe details, not as plain lists
+					list, err := repo.GetPlainList(r.Context(), listID)
+					if errors.Is(err, sql.ErrNoRows) {
+						http.Error(w, "not found", http.StatusNotFound)
+						return
+					} else if err != nil {
+						http.Error(w, err.Error(), http.StatusInternalServerError)
+						return
+					} else if list.AccountID != authCookies.AccountID {
+						http.Error(w, "not found", http.StatusNotFound)
+						return
+					}
+					lists = append(lists, list)
+				}
+
+				if bundle, err = repo.ExportBundle(r.Context(), recipes, lists); err != nil {
+					http.Error(w, fmt.Sprintf("exporting bundle: %v", err), http.StatusInternalServerError)
+					return
+				}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="kroger-recipes-%s.json"`, bundle.ExportedAt.Format(time.DateOnly)))
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "\t")
+			if err := encoder.Encode(bundle); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+
+		r.Post("/import", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, bundleMaxBytes)
+			file, _, err := r.FormFile("bundle")
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, fmt.Sprintf("bundle is larger than %d MB", bundleMaxBytes>>20), http.StatusRequestEntityTooLarge)
+				return
+			} else if err != nil {
+				http.Error(w, fmt.Sprintf("bundle missing: %v", err), http.StatusBadRequest)
+				return
+			}
+			defer file.Close()
+
+			duplicates := r.PostForm.Get("duplicates")
+			if !validOption(bundleDuplicates, duplicates) {
+				http.Error(w, fmt.Sprintf("invalid duplicates option %q", duplicates), http.StatusBadRequest)
+				return
+			}
+
+			var bundle data.Bundle
+			if err := json.NewDecoder(file).Decode(&bundle); err != nil {
+				http.Error(w, fmt.Sprintf("decoding bundle: %v", err), http.StatusBadRequest)
+				return
+			}
+			if err := app.ValidateBundle(bundle); err != nil {
+				http.Error(w, fmt.Sprintf("invalid bundle: %v", err), http.StatusBadRequest)
+				return
+			}
+
+			result, err := repo.ImportBundle(r.Context(), authCookies.AccountID, bundle, duplicates)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("importing bundle: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Add("HX-Trigger", "recipe-update")
+			if err := templates.BundleImportResult(result).Render(w); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+}
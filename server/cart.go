@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"net/http"
@@ -21,6 +22,31 @@ func NewT[T any](t T) *T {
 
 const KrogerCartURL = "https://www.kroger.com/shopping/cart"
 
+// addListToCart adds the list's ingredients to the account's cart, picking which of an ingredient's alternatives is bought
+func addListToCart(ctx context.Context, config Config, repo *data.Repository, cache *data.Cache, accountID, listID uuid.UUID) error {
+	ingredients, err := repo.ListIngredients(ctx, listID)
+	if err != nil {
+		return fmt.Errorf("listing ingredients: %w", err)
+	}
+	account, err := repo.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("getting account: %w", err)
+	}
+	picks, err := app.PickIngredientProducts(ctx, account, ingredients, func(ctx context.Context) (*app.KrogerManager, error) {
+		return newKrogerManager(ctx, config, cache)
+	})
+	if err != nil {
+		return fmt.Errorf("picking ingredient products: %w", err)
+	}
+
+	for _, pick := range picks {
+		if err := repo.AddCartProduct(ctx, accountID, pick.ProductID, pick.Quantity, pick.Staple, pick.Alternatives); err != nil {
+			return fmt.Errorf("adding cart product: %w", err)
+		}
+	}
+	return nil
+}
+
 func NewCartMux(config Config, repo *data.Repository, cache *data.Cache) func(chi.Router) {
 	return func(r chi.Router) {
 		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
@@ -48,6 +74,7 @@ func NewCartMux(config Config, repo *data.Repository, cache *data.Cache) func(ch
 			productIDs := []string{}
 			for _, cartProduct := range dataCartProducts {
 				productIDs = append(productIDs, cartProduct.ProductID)
+				productIDs = append(productIDs, cartProduct.Alternatives...)
 			}
 
 			cartProducts := []templates.CartProduct{}
@@ -75,10 +102,32 @@ func NewCartMux(config Config, repo *data.Repository, cache *data.Cache) func(ch
 					http.Error(w, err.Error(), http.StatusInternalServerError)
 					return
 				}
+				offersByID := map[string]data.CacheProductOffer{}
+				if account.LocationID != nil {
+					if offersByID, err = krogerManager.GetProductOffers(r.Context(), *account.LocationID, productIDs...); err != nil {
+						http.Error(w, err.Error(), http.StatusInternalServerError)
+						return
+					}
+				}
 
 				for _, dataCartProduct := range dataCartProducts {
 					product := productsByID[dataCartProduct.ProductID]
 
+					alternatives := []templates.CartAlternative{}
+					for _, alternativeID := range dataCartProduct.Alternatives {
+						alternative, offer := productsByID[alternativeID], offersByID[alternativeID]
+						alternatives = append(alternatives, templates.CartAlternative{
+							Product: templates.Product{
+								ProductID:   alternativeID,
+								Brand:       alternative.Brand,
+								Description: alternative.Description,
+								Size:        alternative.Size,
+							},
+							Price:      offer.Price,
+							OutOfStock: account.LocationID != nil && !app.ProductOfferInStock(offer),
+						})
+					}
+
 					productURL, err := url.JoinPath(KrogerURL, product.URL)
 					if err != nil {
 						http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -92,15 +141,16 @@ func NewCartMux(config Config, repo *data.Repository, cache *data.Cache) func(ch
 					}
 
 					cartProducts = append(cartProducts, templates.CartProduct{
-						ProductID:   product.ProductID,
-						Brand:       product.Brand,
-						Description: product.Description,
-						Size:        product.Size,
-						ImageURL:    ProductImageLink(dataCartProduct.ProductID, account.ImageSize),
-						Quantity:    dataCartProduct.Quantity,
-						Staple:      dataCartProduct.Staple,
-						ProductURL:  productURL,
-						Location:    product.Location,
+						ProductID:    product.ProductID,
+						Brand:        product.Brand,
+						Description:  product.Description,
+						Size:         product.Size,
+						ImageURL:     ProductImageLink(dataCartProduct.ProductID, account.ImageSize),
+						Quantity:     dataCartProduct.Quantity,
+						Staple:       dataCartProduct.Staple,
+						ProductURL:   productURL,
+						Location:     product.Location,
+						Alternatives: alternatives,
 					})
 				}
 			}
@@ -120,17 +170,10 @@ func NewCartMux(config Config, repo *data.Repository, cache *data.Cache) func(ch
 			}
 
 			listID := uuid.MustParse(chi.URLParam(r, "listID"))
-			ingredients, err := repo.ListIngredients(r.Context(), listID)
-			if err != nil {
-				http.Error(w, fmt.Sprintf("listing ingredients: %v", err), http.StatusInternalServerError)
+			if err := addListToCart(r.Context(), config, repo, cache, authCookies.AccountID, listID); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
-			for _, ingredient := range ingredients {
-				if err := repo.AddCartProduct(r.Context(), authCookies.AccountID, ingredient.ProductID, ingredient.Quantity, ingredient.Staple); err != nil {
-					http.Error(w, fmt.Sprintf("adding cart product: %v", err), http.StatusInternalServerError)
-					return
-				}
-			}
 			w.WriteHeader(http.StatusOK)
 		})
 
@@ -195,7 +238,7 @@ func NewCartMux(config Config, repo *data.Repository, cache *data.Cache) func(ch
 				}
 				quantityPercent := int(quantityFloat * 100)
 
-				if err := repo.AddCartProduct(r.Context(), authCookies.AccountID, productID, quantityPercent, false); err != nil {
+				if err := repo.AddCartProduct(r.Context(), authCookies.AccountID, productID, quantityPercent, false, nil); err != nil {
 					http.Error(w, fmt.Sprintf("adding cart product: %v", err), http.StatusInternalServerError)
 					return
 				}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/densestvoid/krogerrecipeshopper/templates"
+)
+
+// NewCSRFMiddleware issues a per-browser token cookie and requires modifying requests to echo it
+// in the CSRF header, which templates.BasePage has htmx send on every request. Other sites can't
+// read the cookie, so they can't forge the header. Requests authenticated with a bearer token
+// don't use cookies and are exempt.
+func NewCSRFMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cookieToken := ""
+			if cookie, err := r.Cookie(templates.CSRFCookie); err == nil && cookie.Valid() == nil {
+				cookieToken = cookie.Value
+			}
+			if cookieToken == "" {
+				token, err := randomURLString()
+				if err != nil {
+					http.Error(w, "generating csrf token", http.StatusInternalServerError)
+					return
+				}
+				http.SetCookie(w, &http.Cookie{
+					Path:     "/",
+					Name:     templates.CSRFCookie,
+					Value:    token,
+					MaxAge:   RefreshTokenMaxAgeSeconds,
+					Secure:   true,
+					HttpOnly: false, // Read by the page to set the header
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+				next.ServeHTTP(w, r)
+				return
+			}
+			// Only the API authenticates with bearer tokens, which browsers don't send on their own
+			isAPI := strings.HasPrefix(r.URL.Path, "/api/")
+			if _, ok := bearerToken(r); ok && isAPI {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			headerToken := r.Header.Get(templates.CSRFHeader)
+			if cookieToken == "" || subtle.ConstantTimeCompare([]byte(cookieToken), []byte(headerToken)) != 1 {
+				if isAPI {
+					WriteAPIError(w, http.StatusForbidden, "csrf token missing or invalid")
+				} else {
+					http.Error(w, "csrf token missing or invalid", http.StatusForbidden)
+				}
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/parser"
+)
+
+// errUnconvertibleAmount is returned when a recipe amount can't be converted to a fraction of the product's package
+var errUnconvertibleAmount = errors.New("amount can't be converted to the product's package size")
+
+// ingredientQuantityPercent converts a recipe amount, like 2 cups, to the percentage of the product's package that ingredients store
+func ingredientQuantityPercent(ctx context.Context, config Config, repo *data.Repository, cache *data.Cache, accountID uuid.UUID, productID string, amount parser.Measure) (int, error) {
+	if !parser.ConvertibleUnit(amount.Unit) {
+		return 0, fmt.Errorf("%w: unknown unit %q", errUnconvertibleAmount, amount.Unit)
+	}
+
+	account, err := repo.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return 0, fmt.Errorf("getting account: %w", err)
+	}
+	krogerManager, err := newKrogerManager(ctx, config, cache)
+	if err != nil {
+		return 0, fmt.Errorf("creating kroger manager: %w", err)
+	}
+	products, err := krogerManager.GetProducts(ctx, account.LocationID, productID)
+	if err != nil {
+		return 0, fmt.Errorf("getting product: %w", err)
+	}
+	product, ok := products[productID]
+	if !ok {
+		return 0, fmt.Errorf("%w: product %s not found", errUnconvertibleAmount, productID)
+	}
+
+	fraction, ok := parser.PackageFraction(amount, parser.ParseSize(product.Size))
+	if !ok {
+		return 0, fmt.Errorf("%w: %s can't be measured in %s, enter the quantity as a fraction of the package instead", errUnconvertibleAmount, product.Size, amount.Unit)
+	}
+	// Anything used at all is at least a percent of the package
+	return max(int(math.Round(fraction*100)), 1), nil
+}
+
+// readIngredientForm reads the staple, quantity, unit, note, display name and section fields of an ingredient form.
+// Without a unit the quantity is a fraction of the product's package, with one it is a recipe amount converted to that fraction.
+// It writes an error response and returns false if the fields are invalid.
+func readIngredientForm(w http.ResponseWriter, r *http.Request, config Config, repo *data.Repository, cache *data.Cache, accountID, listID uuid.UUID, productID string) (data.Ingredient, bool) {
+	ingredient := data.Ingredient{
+		ProductID:   productID,
+		ListID:      listID,
+		Quantity:    100,
+		Note:        strings.TrimSpace(r.Form.Get("note")),
+		DisplayName: strings.TrimSpace(r.Form.Get("displayName")),
+		Section:     strings.TrimSpace(r.Form.Get("section")),
+	}
+	for _, field := range []struct {
+		name      string
+		value     string
+		maxLength int
+	}{
+		{"note", ingredient.Note, data.IngredientNoteMaxLength},
+		{"display name", ingredient.DisplayName, data.IngredientDisplayNameMaxLength},
+		{"section", ingredient.Section, data.IngredientSectionMaxLength},
+	} {
+		if utf8.RuneCountInString(field.value) > field.maxLength {
+			http.Error(w, fmt.Sprintf("%s is longer than %d characters", field.name, field.maxLength), http.StatusBadRequest)
+			return data.Ingredient{}, false
+		}
+	}
+
+	if r.Form.Has("staple") {
+		var err error
+		if ingredient.Staple, err = strconv.ParseBool(r.Form.Get("staple")); err != nil {
+			http.Error(w, fmt.Sprintf("invalid staple value: %v", err), http.StatusBadRequest)
+			return data.Ingredient{}, false
+		}
+	}
+	if ingredient.Staple {
+		return ingredient, true
+	}
+
+	quantityFloat, err := strconv.ParseFloat(r.Form.Get("quantity"), 64)
+	if err != nil || quantityFloat <= 0 {
+		http.Error(w, fmt.Sprintf("invalid quantity: %v", err), http.StatusBadRequest)
+		return data.Ingredient{}, false
+	}
+
+	unit := r.Form.Get("unit")
+	if unit == "" {
+		ingredient.Quantity = int(quantityFloat * 100)
+		return ingredient, true
+	}
+
+	ingredient.Amount, ingredient.Unit = quantityFloat, unit
+	ingredient.Quantity, err = ingredientQuantityPercent(r.Context(), config, repo, cache, accountID, productID, parser.Measure{Amount: quantityFloat, Unit: unit})
+	if errors.Is(err, errUnconvertibleAmount) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return data.Ingredient{}, false
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf("converting amount: %v", err), http.StatusInternalServerError)
+		return data.Ingredient{}, false
+	}
+	return ingredient, true
+}
@@ -1,10 +1,13 @@
 package server
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
-	"strconv"
+	"slices"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -12,9 +15,31 @@ import (
 	"github.com/densestvoid/krogerrecipeshopper/app"
 	"github.com/densestvoid/krogerrecipeshopper/data"
 	"github.com/densestvoid/krogerrecipeshopper/kroger"
+	"github.com/densestvoid/krogerrecipeshopper/parser"
 	"github.com/densestvoid/krogerrecipeshopper/templates"
 )
 
+// ingredientPasteMaxLines limits how many lines are read from one paste, each of which is searched separately
+const ingredientPasteMaxLines = 100
+
+// ingredientAlternativesMax limits the products looked up for an ingredient each time it's added to the cart
+const ingredientAlternativesMax = 10
+
+// validateIngredientAlternatives checks there aren't too many alternatives and each is a product ID listed once
+func validateIngredientAlternatives(alternatives []string) error {
+	if len(alternatives) > ingredientAlternativesMax {
+		return fmt.Errorf("an ingredient can have at most %d alternatives", ingredientAlternativesMax)
+	}
+	for i, alternative := range alternatives {
+		if alternative == "" || len(alternative) > data.ProductIDMaxLength {
+			return fmt.Errorf("invalid alternative product ID %q", alternative)
+		} else if slices.Contains(alternatives[:i], alternative) {
+			return fmt.Errorf("alternative %s is listed more than once", alternative)
+		}
+	}
+	return nil
+}
+
 func ProductImageLink(productID, imageSize string) string {
 	return fmt.Sprintf("https://www.kroger.com/product/images/%s/front/%s", imageSize, productID)
 }
@@ -48,6 +73,12 @@ func NewIngredientMux(config Config, repo *data.Repository, cache *data.Cache) f
 		})
 
 		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
 			listID := uuid.MustParse(chi.URLParam(r, "id"))
 
 			if err := r.ParseForm(); err != nil {
@@ -55,36 +86,20 @@ func NewIngredientMux(config Config, repo *data.Repository, cache *data.Cache) f
 				return
 			}
 
-			productID := r.FormValue("productID")
-
-			staple := false
-			if r.Form.Has("staple") {
-				var err error
-				if staple, err = strconv.ParseBool(r.FormValue("staple")); err != nil {
-					http.Error(w, fmt.Sprintf("invalid staple value: %v", err), http.StatusBadRequest)
-					return
-				}
-			}
-
-			quantityPercent := 100
-			if !staple {
-				quantityFloat, err := strconv.ParseFloat(r.FormValue("quantity"), 64)
-				if err != nil || quantityFloat <= 0 {
-					http.Error(w, fmt.Sprintf("invalid quantity: %v", err), http.StatusBadRequest)
-					return
-				}
-				quantityPercent = int(quantityFloat * 100)
+			ingredient, ok := readIngredientForm(w, r, config, repo, cache, authCookies.AccountID, listID, r.FormValue("productID"))
+			if !ok {
+				return
 			}
 
-			if _, err := repo.GetIngredient(r.Context(), listID, productID); err != nil {
+			if _, err := repo.GetIngredient(r.Context(), listID, ingredient.ProductID); err != nil {
 				// Doesn't exist, create it
-				if err := repo.CreateIngredient(r.Context(), productID, listID, quantityPercent, staple); err != nil {
+				if err := repo.CreateIngredient(r.Context(), ingredient); err != nil {
 					http.Error(w, fmt.Sprintf("creating ingredient: %v", err), http.StatusInternalServerError)
 					return
 				}
 			} else {
 				// Exists, update it
-				if err := repo.UpdateIngredient(r.Context(), productID, listID, quantityPercent, staple); err != nil {
+				if err := repo.UpdateIngredient(r.Context(), ingredient); err != nil {
 					http.Error(w, fmt.Sprintf("updating ingredient: %v", err), http.StatusInternalServerError)
 					return
 				}
@@ -123,6 +138,7 @@ func NewIngredientMux(config Config, repo *data.Repository, cache *data.Cache) f
 			productIDs := []string{}
 			for _, ingredient := range ingredients {
 				productIDs = append(productIDs, ingredient.ProductID)
+				productIDs = append(productIDs, ingredient.Alternatives...)
 			}
 
 			ingredientProducts := []templates.Ingredient{}
@@ -154,6 +170,17 @@ func NewIngredientMux(config Config, repo *data.Repository, cache *data.Cache) f
 				for _, ingredient := range ingredients {
 					product := productsByID[ingredient.ProductID]
 
+					alternatives := []templates.Product{}
+					for _, alternativeID := range ingredient.Alternatives {
+						alternative := productsByID[alternativeID]
+						alternatives = append(alternatives, templates.Product{
+							ProductID:   alternativeID,
+							Brand:       alternative.Brand,
+							Description: alternative.Description,
+							Size:        alternative.Size,
+						})
+					}
+
 					productURL, err := url.JoinPath(KrogerURL, product.URL)
 					if err != nil {
 						http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -175,20 +202,143 @@ func NewIngredientMux(config Config, repo *data.Repository, cache *data.Cache) f
 							ImageURL:    ProductImageLink(ingredient.ProductID, account.ImageSize),
 							ProductURL:  productURL,
 						},
-						ListID:   ingredient.ListID,
-						Quantity: ingredient.Quantity,
-						Staple:   ingredient.Staple,
+						ListID:       ingredient.ListID,
+						Quantity:     ingredient.Quantity,
+						Staple:       ingredient.Staple,
+						Amount:       ingredient.Amount,
+						Unit:         ingredient.Unit,
+						Note:         ingredient.Note,
+						DisplayName:  ingredient.DisplayName,
+						Section:      ingredient.Section,
+						Alternatives: alternatives,
 					})
 				}
 			}
 
-			if err := templates.IngredientsTable(authCookies.AccountID, list.AccountID, ingredientProducts).Render(w); err != nil {
+			if err := templates.IngredientsTable(authCookies.AccountID, list.AccountID, listID, ingredientProducts).Render(w); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+
+		r.Post("/order", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			listID := uuid.MustParse(chi.URLParam(r, "id"))
+			list, err := repo.GetList(r.Context(), listID)
+			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
+
+			if list.AccountID != authCookies.AccountID {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			if err := repo.ReorderIngredients(r.Context(), listID, r.PostForm["productID"]); err != nil {
+				http.Error(w, fmt.Sprintf("reordering ingredients: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Add("HX-Trigger", "ingredient-update")
 			w.WriteHeader(http.StatusOK)
 		})
 
+		r.Route("/paste", func(r chi.Router) {
+			r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+				authCookies, err := GetAuthCookies(r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+
+				listID := uuid.MustParse(chi.URLParam(r, "id"))
+				list, err := repo.GetList(r.Context(), listID)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				if list.AccountID != authCookies.AccountID {
+					http.Error(w, "unauthorized", http.StatusUnauthorized)
+					return
+				}
+
+				if err := r.ParseForm(); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				ingredients := []parser.Ingredient{}
+				for line := range strings.Lines(r.PostForm.Get("lines")) {
+					if line = strings.TrimSpace(line); line == "" {
+						continue
+					}
+					if len(ingredients) == ingredientPasteMaxLines {
+						http.Error(w, fmt.Sprintf("paste at most %d ingredient lines at a time", ingredientPasteMaxLines), http.StatusBadRequest)
+						return
+					}
+					ingredients = append(ingredients, parser.ParseIngredient(truncateText(line, data.RecipeImportIngredientMaxLength)))
+				}
+
+				if err := templates.IngredientPasteTable(listID, ingredients).Render(w); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			r.Get("/match", func(w http.ResponseWriter, r *http.Request) {
+				authCookies, err := GetAuthCookies(r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+
+				listID := uuid.MustParse(chi.URLParam(r, "id"))
+				list, err := repo.GetList(r.Context(), listID)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				if list.AccountID != authCookies.AccountID {
+					http.Error(w, "unauthorized", http.StatusUnauthorized)
+					return
+				}
+
+				line := strings.TrimSpace(r.URL.Query().Get("line"))
+				if line == "" {
+					http.Error(w, "ingredient line missing", http.StatusBadRequest)
+					return
+				}
+				line = truncateText(line, data.RecipeImportIngredientMaxLength)
+
+				sections, err := repo.ListIngredientSections(r.Context(), listID)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				if err := templates.IngredientPasteMatchModalContent(listID, parser.ParseIngredient(line), sections).Render(w); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+		})
+
 		r.Route("/{productID}", func(r chi.Router) {
 			r.Get("/details", func(w http.ResponseWriter, r *http.Request) {
 				listID := uuid.MustParse(chi.URLParam(r, "id"))
@@ -203,13 +353,115 @@ func NewIngredientMux(config Config, repo *data.Repository, cache *data.Cache) f
 					}
 				}
 
-				if err := templates.IngredientDetailsModalContent(listID, ingredient).Render(w); err != nil {
+				sections, err := repo.ListIngredientSections(r.Context(), listID)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				if err := templates.IngredientDetailsModalContent(listID, ingredient, sections).Render(w); err != nil {
 					http.Error(w, err.Error(), http.StatusInternalServerError)
 					return
 				}
 				w.WriteHeader(http.StatusOK)
 			})
 
+			r.Route("/alternatives", func(r chi.Router) {
+				// getOwnIngredient writes an error response and returns false if the ingredient isn't on a list the account owns
+				getOwnIngredient := func(w http.ResponseWriter, r *http.Request, accountID uuid.UUID) (data.Ingredient, bool) {
+					listID := uuid.MustParse(chi.URLParam(r, "id"))
+					list, err := repo.GetList(r.Context(), listID)
+					if err != nil {
+						http.Error(w, err.Error(), http.StatusInternalServerError)
+						return data.Ingredient{}, false
+					}
+					if list.AccountID != accountID {
+						http.Error(w, "unauthorized", http.StatusUnauthorized)
+						return data.Ingredient{}, false
+					}
+
+					ingredient, err := repo.GetIngredient(r.Context(), listID, chi.URLParam(r, "productID"))
+					if errors.Is(err, sql.ErrNoRows) {
+						http.Error(w, "ingredient not found", http.StatusNotFound)
+						return data.Ingredient{}, false
+					} else if err != nil {
+						http.Error(w, err.Error(), http.StatusInternalServerError)
+						return data.Ingredient{}, false
+					}
+					return ingredient, true
+				}
+
+				r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+					authCookies, err := GetAuthCookies(r)
+					if err != nil {
+						http.Error(w, err.Error(), http.StatusUnauthorized)
+						return
+					}
+
+					ingredient, ok := getOwnIngredient(w, r, authCookies.AccountID)
+					if !ok {
+						return
+					}
+
+					products, err := hydrateTemplateProducts(r.Context(), config, repo, cache, authCookies.AccountID, ingredient.Alternatives)
+					if err != nil {
+						http.Error(w, err.Error(), http.StatusInternalServerError)
+						return
+					}
+					alternatives := []templates.Product{}
+					for _, productID := range ingredient.Alternatives {
+						product, ok := products[productID]
+						if !ok {
+							product = templates.Product{ProductID: productID}
+						}
+						alternatives = append(alternatives, product)
+					}
+
+					if err := templates.IngredientAlternativesModalContent(ingredient.ListID, ingredient.ProductID, alternatives).Render(w); err != nil {
+						http.Error(w, err.Error(), http.StatusInternalServerError)
+						return
+					}
+					w.WriteHeader(http.StatusOK)
+				})
+
+				// Set the alternatives in the order they were listed, followed by the selected product if there is one
+				r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+					authCookies, err := GetAuthCookies(r)
+					if err != nil {
+						http.Error(w, err.Error(), http.StatusUnauthorized)
+						return
+					}
+
+					ingredient, ok := getOwnIngredient(w, r, authCookies.AccountID)
+					if !ok {
+						return
+					}
+
+					if err := r.ParseForm(); err != nil {
+						http.Error(w, err.Error(), http.StatusBadRequest)
+						return
+					}
+
+					alternatives := r.PostForm["alternative"]
+					// Selecting a product that's already an alternative leaves it where it is
+					if productID := r.PostForm.Get("productID"); productID != "" && !slices.Contains(alternatives, productID) {
+						alternatives = append(alternatives, productID)
+					}
+					if err := validateIngredientAlternatives(alternatives); err != nil {
+						http.Error(w, err.Error(), http.StatusBadRequest)
+						return
+					}
+
+					if err := repo.SetIngredientAlternatives(r.Context(), ingredient.ListID, ingredient.ProductID, alternatives); err != nil {
+						http.Error(w, fmt.Sprintf("setting ingredient alternatives: %v", err), http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Add("HX-Trigger", "ingredient-update")
+					w.WriteHeader(http.StatusOK)
+				})
+			})
+
 			r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
 				listID := uuid.Must(uuid.Parse(chi.URLParam(r, "id")))
 				productID := chi.URLParam(r, "productID")
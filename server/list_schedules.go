@@ -0,0 +1,213 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/densestvoid/krogerrecipeshopper/app"
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/templates"
+)
+
+const ListScheduleRunsShown = 10
+
+var listScheduleFrequencies = []string{
+	data.ListScheduleFrequencyWeekly,
+	data.ListScheduleFrequencyDays,
+	data.ListScheduleFrequencyMonthly,
+}
+
+func NewListScheduleMux(config Config, repo *data.Repository) func(chi.Router) {
+	// getOwnedList writes an error response and returns false if the list isn't the account's
+	getOwnedList := func(w http.ResponseWriter, r *http.Request) (data.List, bool) {
+		authCookies, err := GetAuthCookies(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return data.List{}, false
+		}
+		listID, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("parsing list id: %v", err), http.StatusBadRequest)
+			return data.List{}, false
+		}
+		// Recipes are added to the cart from their own page, only plain lists are scheduled
+		list, err := repo.GetPlainList(r.Context(), listID)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "list not found", http.StatusNotFound)
+			return data.List{}, false
+		} else if err != nil {
+			http.Error(w, fmt.Sprintf("getting list: %v", err), http.StatusInternalServerError)
+			return data.List{}, false
+		} else if list.AccountID != authCookies.AccountID {
+			http.Error(w, "Can't schedule lists you didn't create", http.StatusUnauthorized)
+			return data.List{}, false
+		}
+		return list, true
+	}
+
+	renderSchedule := func(w http.ResponseWriter, r *http.Request, list data.List) {
+		var schedule *data.ListSchedule
+		if listSchedule, err := repo.GetListSchedule(r.Context(), list.ID); err == nil {
+			schedule = &listSchedule
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, fmt.Sprintf("getting list schedule: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		runs, err := repo.ListListScheduleRuns(r.Context(), list.ID, ListScheduleRunsShown)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("listing list schedule runs: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		if err := templates.ListScheduleModalContent(list, schedule, runs, config.TokenVault != nil).Render(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			list, ok := getOwnedList(w, r)
+			if !ok {
+				return
+			}
+			renderSchedule(w, r, list)
+		})
+
+		r.Put("/", func(w http.ResponseWriter, r *http.Request) {
+			list, ok := getOwnedList(w, r)
+			if !ok {
+				return
+			}
+
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			schedule := data.ListSchedule{
+				ListID:       list.ID,
+				Frequency:    r.FormValue("frequency"),
+				PushToKroger: r.FormValue("pushToKroger") == "true",
+			}
+			if !validOption(listScheduleFrequencies, schedule.Frequency) {
+				http.Error(w, fmt.Sprintf("invalid frequency %q", schedule.Frequency), http.StatusBadRequest)
+				return
+			}
+			if schedule.PushToKroger && config.TokenVault == nil {
+				http.Error(w, "sending to the kroger cart requires a token vault", http.StatusBadRequest)
+				return
+			}
+
+			formInt := func(name string) (*int, error) {
+				value, err := strconv.Atoi(r.FormValue(name))
+				if err != nil {
+					return nil, fmt.Errorf("parsing %s: %w", name, err)
+				}
+				return &value, nil
+			}
+			var err error
+			switch schedule.Frequency {
+			case data.ListScheduleFrequencyWeekly:
+				schedule.Weekday, err = formInt("weekday")
+			case data.ListScheduleFrequencyDays:
+				schedule.IntervalDays, err = formInt("intervalDays")
+			case data.ListScheduleFrequencyMonthly:
+				schedule.DayOfMonth, err = formInt("dayOfMonth")
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			// Changing the schedule keeps it paused if it was
+			if existing, err := repo.GetListSchedule(r.Context(), list.ID); err == nil {
+				schedule.Paused = existing.Paused
+			} else if !errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, fmt.Sprintf("getting list schedule: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			if schedule.NextRunAt, err = app.NextListScheduleRun(schedule, time.Now()); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			if err := repo.SetListSchedule(r.Context(), schedule); err != nil {
+				http.Error(w, fmt.Sprintf("setting list schedule: %v", err), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+
+		r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
+			list, ok := getOwnedList(w, r)
+			if !ok {
+				return
+			}
+
+			if err := repo.DeleteListSchedule(r.Context(), list.ID); err != nil {
+				http.Error(w, fmt.Sprintf("deleting list schedule: %v", err), http.StatusInternalServerError)
+				return
+			}
+			renderSchedule(w, r, list)
+		})
+
+		r.Post("/pause", func(w http.ResponseWriter, r *http.Request) {
+			list, ok := getOwnedList(w, r)
+			if !ok {
+				return
+			}
+
+			schedule, err := repo.GetListSchedule(r.Context(), list.ID)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("getting list schedule: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			schedule.Paused = r.FormValue("paused") == "true"
+			// Runs missed while paused aren't caught up
+			if !schedule.Paused && schedule.NextRunAt.Before(time.Now()) {
+				if schedule.NextRunAt, err = app.NextListScheduleRun(schedule, time.Now()); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+
+			if err := repo.SetListSchedule(r.Context(), schedule); err != nil {
+				http.Error(w, fmt.Sprintf("setting list schedule: %v", err), http.StatusInternalServerError)
+				return
+			}
+			renderSchedule(w, r, list)
+		})
+
+		r.Post("/skip-next", func(w http.ResponseWriter, r *http.Request) {
+			list, ok := getOwnedList(w, r)
+			if !ok {
+				return
+			}
+
+			schedule, err := repo.GetListSchedule(r.Context(), list.ID)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("getting list schedule: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			schedule.SkipNext = r.FormValue("skip") == "true"
+			if err := repo.SetListSchedule(r.Context(), schedule); err != nil {
+				http.Error(w, fmt.Sprintf("setting list schedule: %v", err), http.StatusInternalServerError)
+				return
+			}
+			renderSchedule(w, r, list)
+		})
+	}
+}
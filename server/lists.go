@@ -198,7 +198,8 @@ func NewListsMux(config Config, repo *data.Repository, cache *data.Cache) func(c
 				}
 
 				for _, ingredient := range ingredients {
-					if err := repo.CreateIngredient(r.Context(), ingredient.ProductID, newListID, ingredient.Quantity, ingredient.Staple); err != nil {
+					ingredient.ListID = newListID
+					if err := repo.CreateIngredient(r.Context(), ingredient); err != nil {
 						http.Error(w, fmt.Sprintf("adding ingredient to copied list: %v", err), http.StatusInternalServerError)
 						return
 					}
@@ -235,6 +236,7 @@ func NewListsMux(config Config, repo *data.Repository, cache *data.Cache) func(c
 			})
 
 			r.Route("/ingredients", NewIngredientMux(config, repo, cache))
+			r.Route("/schedule", NewListScheduleMux(config, repo))
 		})
 	}
 }
@@ -91,6 +91,9 @@ func NewErrorStatusMiddleware() func(http.Handler) http.Handler {
 			statusCode := writer.statusCode
 
 			switch {
+			// JSON API responses carry their own error bodies
+			case writer.header.Get("Content-Type") == APIContentType:
+				writer.Commit()
 			case 400 <= statusCode && statusCode <= 599:
 				defer writer.Abort()
 				w.WriteHeader(statusCode)
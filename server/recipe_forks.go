@@ -0,0 +1,109 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/densestvoid/krogerrecipeshopper/app"
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/templates"
+)
+
+func NewRecipeSourceMux(config Config, repo *data.Repository, cache *data.Cache) func(chi.Router) {
+	// getFork writes an error response and returns false unless the recipe is a fork owned by the account of a source it can view
+	getFork := func(w http.ResponseWriter, r *http.Request, accountID uuid.UUID) (data.Recipe, bool) {
+		listID, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("parsing recipe id: %v", err), http.StatusBadRequest)
+			return data.Recipe{}, false
+		}
+
+		recipe, err := repo.GetRecipe(r.Context(), listID, accountID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return data.Recipe{}, false
+		} else if recipe.AccountID != accountID {
+			http.Error(w, "Can't sync recipes you didn't create", http.StatusBadRequest)
+			return data.Recipe{}, false
+		} else if recipe.SourceName == nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return data.Recipe{}, false
+		}
+		return recipe, true
+	}
+
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			recipe, ok := getFork(w, r, authCookies.AccountID)
+			if !ok {
+				return
+			}
+
+			// Forks of recipes without revisions compare against an empty recipe
+			from, to := data.RecipeSnapshot{}, data.RecipeSnapshot{}
+			if recipe.SourceRevision != nil {
+				revision, err := repo.GetRecipeRevision(r.Context(), *recipe.SourceListID, *recipe.SourceRevision)
+				if err != nil {
+					http.Error(w, fmt.Sprintf("getting synced revision: %v", err), http.StatusInternalServerError)
+					return
+				}
+				from = revision.Snapshot
+			}
+			if recipe.SourceLatestRevision != nil {
+				revision, err := repo.GetRecipeRevision(r.Context(), *recipe.SourceListID, *recipe.SourceLatestRevision)
+				if err != nil {
+					http.Error(w, fmt.Sprintf("getting latest revision: %v", err), http.StatusInternalServerError)
+					return
+				}
+				to = revision.Snapshot
+			}
+
+			products, err := hydrateTemplateProducts(r.Context(), config, repo, cache, authCookies.AccountID, data.RecipeSnapshotProductIDs(from, to))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			if err := templates.RecipeSourceChanges(recipe, app.DiffRecipeSnapshots(from, to), products).Render(w); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+
+		r.Post("/sync", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			recipe, ok := getFork(w, r, authCookies.AccountID)
+			if !ok {
+				return
+			}
+
+			if err := repo.SyncRecipeSource(r.Context(), recipe.ListID, r.PostForm.Get("pull") == "true"); err != nil {
+				http.Error(w, fmt.Sprintf("syncing recipe source: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Add("HX-Redirect", fmt.Sprintf("/recipes/%s/source", recipe.ListID))
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+}
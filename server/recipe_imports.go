@@ -0,0 +1,283 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/densestvoid/krogerrecipeshopper/app"
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/templates"
+)
+
+const recipeImportMaxBytes = 5 << 20
+
+// recipeImportPage is the uploaded page, or the pasted HTML when no file was uploaded
+func recipeImportPage(w http.ResponseWriter, r *http.Request) (string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, recipeImportMaxBytes)
+	if err := r.ParseMultipartForm(recipeImportMaxBytes); err != nil {
+		return "", err
+	}
+
+	if file, _, err := r.FormFile("file"); err == nil {
+		defer file.Close()
+		page, err := io.ReadAll(file)
+		return string(page), err
+	} else if !errors.Is(err, http.ErrMissingFile) {
+		return "", err
+	}
+
+	page := r.PostForm.Get("html")
+	if strings.TrimSpace(page) == "" {
+		return "", fmt.Errorf("paste a page's HTML or upload an .html file")
+	}
+	return page, nil
+}
+
+// truncateText cuts the text to the max length in characters, like the database's varchar columns count them
+func truncateText(text string, maxLength int) string {
+	if runes := []rune(text); len(runes) > maxLength {
+		return strings.TrimSpace(string(runes[:maxLength]))
+	}
+	return text
+}
+
+func NewRecipeImportMux(repo *data.Repository) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			if err := templates.RecipeImportModalContent().Render(w); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+
+		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			page, err := recipeImportPage(w, r)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("reading page: %v", err), http.StatusBadRequest)
+				return
+			}
+
+			imported, err := app.ParseRecipeJSONLD(strings.NewReader(page))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("importing recipe: %v", err), http.StatusBadRequest)
+				return
+			}
+
+			// Pages don't know the length limits, so imported text is cut short instead of rejected
+			steps := []data.RecipeStep{}
+			for _, instruction := range imported.Instructions {
+				steps = append(steps, data.RecipeStep{Number: len(steps) + 1, Text: truncateText(instruction, data.RecipeStepMaxLength)})
+			}
+			ingredients := []string{}
+			for _, ingredient := range imported.Ingredients {
+				ingredients = append(ingredients, truncateText(ingredient, data.RecipeImportIngredientMaxLength))
+			}
+
+			description := imported.Description
+			if imported.Yield != "" {
+				description = strings.TrimSpace(fmt.Sprintf("%s\n\n**Yield:** %s", description, imported.Yield))
+			}
+			name, description := truncateText(imported.Name, data.ListNameMaxLength), truncateText(description, data.ListDescriptionMaxLength)
+
+			listID, err := repo.CreateImportedRecipe(r.Context(), authCookies.AccountID, name, description, steps, ingredients)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("creating recipe: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Add("HX-Redirect", fmt.Sprintf("/recipes/%s/import", listID))
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+}
+
+func NewRecipeImportQueueMux(config Config, repo *data.Repository, cache *data.Cache) func(chi.Router) {
+	// getOwnRecipe writes an error response and returns false unless the recipe is owned by the account
+	getOwnRecipe := func(w http.ResponseWriter, r *http.Request, accountID uuid.UUID) (data.Recipe, bool) {
+		listID, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("parsing recipe id: %v", err), http.StatusBadRequest)
+			return data.Recipe{}, false
+		}
+
+		recipe, err := repo.GetRecipe(r.Context(), listID, accountID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return data.Recipe{}, false
+		} else if recipe.AccountID != accountID {
+			http.Error(w, "Can't match the ingredients of recipes you didn't create", http.StatusBadRequest)
+			return data.Recipe{}, false
+		}
+		return recipe, true
+	}
+
+	// getLine writes an error response and returns false unless the line is queued for the account's recipe
+	getLine := func(w http.ResponseWriter, r *http.Request, accountID uuid.UUID) (data.RecipeImportIngredient, bool) {
+		recipe, ok := getOwnRecipe(w, r, accountID)
+		if !ok {
+			return data.RecipeImportIngredient{}, false
+		}
+
+		number, err := strconv.Atoi(chi.URLParam(r, "number"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("parsing ingredient line number: %v", err), http.StatusBadRequest)
+			return data.RecipeImportIngredient{}, false
+		}
+
+		line, err := repo.GetRecipeImportIngredient(r.Context(), recipe.ListID, number)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return data.RecipeImportIngredient{}, false
+		} else if err != nil {
+			http.Error(w, fmt.Sprintf("getting ingredient line: %v", err), http.StatusInternalServerError)
+			return data.RecipeImportIngredient{}, false
+		}
+		return line, true
+	}
+
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			recipe, ok := getOwnRecipe(w, r, authCookies.AccountID)
+			if !ok {
+				return
+			}
+
+			if err := templates.RecipeImportQueue(recipe).Render(w); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+
+		r.Get("/table", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			recipe, ok := getOwnRecipe(w, r, authCookies.AccountID)
+			if !ok {
+				return
+			}
+
+			lines, err := repo.ListRecipeImportIngredients(r.Context(), recipe.ListID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			if err := templates.RecipeImportQueueTable(recipe.ListID, lines).Render(w); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+
+		r.Route("/{number}", func(r chi.Router) {
+			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				authCookies, err := GetAuthCookies(r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+
+				line, ok := getLine(w, r, authCookies.AccountID)
+				if !ok {
+					return
+				}
+
+				sections, err := repo.ListIngredientSections(r.Context(), line.ListID)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				if err := templates.RecipeImportMatchModalContent(line, sections).Render(w); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+				authCookies, err := GetAuthCookies(r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+
+				if err := r.ParseForm(); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				line, ok := getLine(w, r, authCookies.AccountID)
+				if !ok {
+					return
+				}
+
+				productID := r.PostForm.Get("productID")
+				if productID == "" {
+					http.Error(w, "product missing", http.StatusBadRequest)
+					return
+				}
+
+				ingredient, ok := readIngredientForm(w, r, config, repo, cache, authCookies.AccountID, line.ListID, productID)
+				if !ok {
+					return
+				}
+
+				if err := repo.MatchRecipeImportIngredient(r.Context(), line.Number, ingredient); err != nil {
+					http.Error(w, fmt.Sprintf("matching ingredient line: %v", err), http.StatusInternalServerError)
+					return
+				}
+
+				w.Header().Add("HX-Trigger", "ingredient-update")
+				w.WriteHeader(http.StatusOK)
+			})
+
+			r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
+				authCookies, err := GetAuthCookies(r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+
+				line, ok := getLine(w, r, authCookies.AccountID)
+				if !ok {
+					return
+				}
+
+				if err := repo.SkipRecipeImportIngredient(r.Context(), line.ListID, line.Number); err != nil {
+					http.Error(w, fmt.Sprintf("skipping ingredient line: %v", err), http.StatusInternalServerError)
+					return
+				}
+
+				w.Header().Add("HX-Trigger", "ingredient-update")
+				w.WriteHeader(http.StatusOK)
+			})
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/densestvoid/krogerrecipeshopper/app"
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/storage"
+	"github.com/densestvoid/krogerrecipeshopper/templates"
+)
+
+// deleteRecipePhoto removes every variant of a replaced or deleted photo, failures only leave unused files behind
+func deleteRecipePhoto(ctx context.Context, photos storage.Storage, photoID uuid.UUID) {
+	if err := app.DeleteRecipePhoto(ctx, photos, photoID); err != nil {
+		slog.Error("deleting recipe photo", "photoID", photoID, "error", err)
+	}
+}
+
+func NewRecipePhotoMux(config Config, repo *data.Repository) func(chi.Router) {
+	// getOwnRecipe writes an error response and returns false unless the recipe is owned by the account
+	getOwnRecipe := func(w http.ResponseWriter, r *http.Request, accountID uuid.UUID) (data.Recipe, bool) {
+		listID, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("parsing recipe id: %v", err), http.StatusBadRequest)
+			return data.Recipe{}, false
+		}
+
+		recipe, err := repo.GetRecipe(r.Context(), listID, accountID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return data.Recipe{}, false
+		} else if recipe.AccountID != accountID {
+			http.Error(w, "Can't change the photo of recipes you didn't create", http.StatusBadRequest)
+			return data.Recipe{}, false
+		}
+		return recipe, true
+	}
+
+	// setPhoto replaces the recipe's photo and renders the photo form for the updated recipe
+	setPhoto := func(w http.ResponseWriter, r *http.Request, accountID uuid.UUID, recipe data.Recipe, photoID *uuid.UUID) {
+		replaced, err := repo.SetRecipePhoto(r.Context(), recipe.ListID, photoID)
+		if err != nil {
+			if photoID != nil {
+				deleteRecipePhoto(r.Context(), config.Photos, *photoID)
+			}
+			http.Error(w, fmt.Sprintf("setting recipe photo: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if replaced != nil {
+			deleteRecipePhoto(r.Context(), config.Photos, *replaced)
+		}
+		recipe.PhotoID = photoID
+
+		account, err := repo.GetAccountByID(r.Context(), accountID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("HX-Trigger", "recipe-update")
+		if err := templates.RecipePhotoForm(recipe, account.ImageSize).Render(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return func(r chi.Router) {
+		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			recipe, ok := getOwnRecipe(w, r, authCookies.AccountID)
+			if !ok {
+				return
+			}
+
+			// Leaves room for the rest of the multipart form
+			r.Body = http.MaxBytesReader(w, r.Body, app.RecipePhotoMaxBytes+1<<20)
+			file, _, err := r.FormFile("photo")
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, fmt.Sprintf("photo is larger than %d MB", app.RecipePhotoMaxBytes>>20), http.StatusRequestEntityTooLarge)
+				return
+			} else if err != nil {
+				http.Error(w, fmt.Sprintf("photo missing: %v", err), http.StatusBadRequest)
+				return
+			}
+			defer file.Close()
+
+			photo, err := io.ReadAll(io.LimitReader(file, app.RecipePhotoMaxBytes+1))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("reading photo: %v", err), http.StatusBadRequest)
+				return
+			}
+
+			variants, err := app.ResizeRecipePhoto(photo)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			photoID := uuid.New()
+			for imageSize, variant := range variants {
+				if err := config.Photos.Put(r.Context(), app.RecipePhotoKey(photoID, imageSize), bytes.NewReader(variant)); err != nil {
+					deleteRecipePhoto(r.Context(), config.Photos, photoID)
+					http.Error(w, fmt.Sprintf("storing recipe photo: %v", err), http.StatusInternalServerError)
+					return
+				}
+			}
+
+			setPhoto(w, r, authCookies.AccountID, recipe, &photoID)
+		})
+
+		r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			recipe, ok := getOwnRecipe(w, r, authCookies.AccountID)
+			if !ok {
+				return
+			}
+
+			setPhoto(w, r, authCookies.AccountID, recipe, nil)
+		})
+
+		r.Get("/{photoID}/{imageSize}", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			listID, err := uuid.Parse(chi.URLParam(r, "id"))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("parsing recipe id: %v", err), http.StatusBadRequest)
+				return
+			}
+			photoID, err := uuid.Parse(chi.URLParam(r, "photoID"))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("parsing photo id: %v", err), http.StatusBadRequest)
+				return
+			}
+			imageSize := chi.URLParam(r, "imageSize")
+			if !slices.Contains(imageSizes, imageSize) {
+				http.Error(w, fmt.Sprintf("invalid image size %q", imageSize), http.StatusBadRequest)
+				return
+			}
+
+			recipe, err := repo.GetRecipe(r.Context(), listID, authCookies.AccountID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			} else if !RecipeVisible(recipe, authCookies.AccountID) || recipe.PhotoID == nil || *recipe.PhotoID != photoID {
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+
+			variant, err := config.Photos.Get(r.Context(), app.RecipePhotoKey(photoID, imageSize))
+			if errors.Is(err, storage.ErrNotExist) {
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			} else if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			defer variant.Close()
+
+			// Photos get a new ID when replaced, so a photo's variants never change
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+			if _, err := io.Copy(w, variant); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+}
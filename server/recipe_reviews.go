@@ -0,0 +1,142 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/templates"
+)
+
+// RecipeReviewable reports whether an account may review a recipe, which excludes the recipe's author
+func RecipeReviewable(recipe data.Recipe, accountID uuid.UUID) bool {
+	return RecipeVisible(recipe, accountID) && recipe.AccountID != accountID
+}
+
+func validateRecipeReview(rating int, review string) error {
+	if rating < data.RecipeRatingMin || rating > data.RecipeRatingMax {
+		return fmt.Errorf("rating must be between %d and %d", data.RecipeRatingMin, data.RecipeRatingMax)
+	}
+	if utf8.RuneCountInString(review) > data.RecipeReviewMaxLength {
+		return fmt.Errorf("review must be at most %d characters", data.RecipeReviewMaxLength)
+	}
+	return nil
+}
+
+func NewRecipeReviewsMux(repo *data.Repository) func(chi.Router) {
+	renderReviews := func(w http.ResponseWriter, r *http.Request, listID, accountID uuid.UUID) {
+		recipe, err := repo.GetRecipe(r.Context(), listID, accountID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		} else if !RecipeVisible(recipe, accountID) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		reviews, err := repo.ListRecipeReviews(r.Context(), listID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := templates.RecipeReviews(accountID, recipe, reviews, RecipeReviewable(recipe, accountID)).Render(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			listID, err := uuid.Parse(chi.URLParam(r, "id"))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("parsing recipe id: %v", err), http.StatusBadRequest)
+				return
+			}
+
+			renderReviews(w, r, listID, authCookies.AccountID)
+		})
+
+		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			listID, err := uuid.Parse(chi.URLParam(r, "id"))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("parsing recipe id: %v", err), http.StatusBadRequest)
+				return
+			}
+
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			rating, err := strconv.Atoi(r.FormValue("rating"))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("parsing rating: %v", err), http.StatusBadRequest)
+				return
+			}
+			review := strings.TrimSpace(r.FormValue("review"))
+			if err := validateRecipeReview(rating, review); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			recipe, err := repo.GetRecipe(r.Context(), listID, authCookies.AccountID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			} else if !RecipeReviewable(recipe, authCookies.AccountID) {
+				http.Error(w, "Can't review your own recipes or recipes that aren't public", http.StatusBadRequest)
+				return
+			}
+
+			if err := repo.SetRecipeReview(r.Context(), listID, authCookies.AccountID, rating, review); err != nil {
+				http.Error(w, fmt.Sprintf("saving review: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Add("HX-Trigger", "recipe-update")
+			renderReviews(w, r, listID, authCookies.AccountID)
+		})
+
+		r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			listID, err := uuid.Parse(chi.URLParam(r, "id"))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("parsing recipe id: %v", err), http.StatusBadRequest)
+				return
+			}
+
+			if err := repo.DeleteRecipeReview(r.Context(), listID, authCookies.AccountID); err != nil {
+				http.Error(w, fmt.Sprintf("deleting review: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Add("HX-Trigger", "recipe-update")
+			renderReviews(w, r, listID, authCookies.AccountID)
+		})
+	}
+}
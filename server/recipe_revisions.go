@@ -0,0 +1,201 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/densestvoid/krogerrecipeshopper/app"
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/templates"
+)
+
+// hydrateTemplateProducts looks up product details for the account's location and image size
+func hydrateTemplateProducts(ctx context.Context, config Config, repo *data.Repository, cache *data.Cache, accountID uuid.UUID, productIDs []string) (map[string]templates.Product, error) {
+	products := map[string]templates.Product{}
+	if len(productIDs) == 0 {
+		return products, nil
+	}
+
+	account, err := repo.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("getting account: %w", err)
+	}
+
+	krogerManager, err := newKrogerManager(ctx, config, cache)
+	if err != nil {
+		return nil, fmt.Errorf("creating kroger manager: %w", err)
+	}
+
+	productsByID, err := krogerManager.GetProducts(ctx, account.LocationID, productIDs...)
+	if err != nil {
+		return nil, fmt.Errorf("getting products: %w", err)
+	}
+
+	for productID, product := range productsByID {
+		productURL, err := url.JoinPath(KrogerURL, product.URL)
+		if err != nil {
+			return nil, err
+		}
+		products[productID] = templates.Product{
+			ProductID:   productID,
+			Brand:       product.Brand,
+			Description: product.Description,
+			Size:        product.Size,
+			ImageURL:    ProductImageLink(productID, account.ImageSize),
+			ProductURL:  productURL,
+		}
+	}
+	return products, nil
+}
+
+func NewRecipeHistoryMux(config Config, repo *data.Repository, cache *data.Cache) func(chi.Router) {
+	// getOwnRecipe writes an error response and returns false if the recipe isn't the account's.
+	// Revisions can hold ingredients and instructions from before the recipe was shared, so only the owner sees them.
+	getOwnRecipe := func(w http.ResponseWriter, r *http.Request, accountID uuid.UUID) (data.Recipe, bool) {
+		listID, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("parsing recipe id: %v", err), http.StatusBadRequest)
+			return data.Recipe{}, false
+		}
+
+		recipe, err := repo.GetRecipe(r.Context(), listID, accountID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return data.Recipe{}, false
+		} else if recipe.AccountID != accountID {
+			http.Error(w, "not found", http.StatusNotFound)
+			return data.Recipe{}, false
+		}
+		return recipe, true
+	}
+
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			recipe, ok := getOwnRecipe(w, r, authCookies.AccountID)
+			if !ok {
+				return
+			}
+
+			revisions, err := repo.ListRecipeRevisions(r.Context(), recipe.ListID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			// Revisions are newest first, so each is compared with the one after it
+			snapshots := []data.RecipeSnapshot{}
+			templateRevisions := []templates.RecipeRevision{}
+			for i, revision := range revisions {
+				previous := data.RecipeSnapshot{}
+				if i+1 < len(revisions) {
+					previous = revisions[i+1].Snapshot
+				}
+				snapshots = append(snapshots, revision.Snapshot)
+				templateRevisions = append(templateRevisions, templates.RecipeRevision{
+					RecipeRevision: revision,
+					Diff:           app.DiffRecipeSnapshots(previous, revision.Snapshot),
+				})
+			}
+
+			products, err := hydrateTemplateProducts(r.Context(), config, repo, cache, authCookies.AccountID, data.RecipeSnapshotProductIDs(snapshots...))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			if err := templates.RecipeHistory(recipe, templateRevisions, products).Render(w); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+
+		r.Get("/diff", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			recipe, ok := getOwnRecipe(w, r, authCookies.AccountID)
+			if !ok {
+				return
+			}
+
+			revisions := []data.RecipeRevision{}
+			for _, key := range []string{"from", "to"} {
+				number, err := strconv.Atoi(r.URL.Query().Get(key))
+				if err != nil {
+					http.Error(w, fmt.Sprintf("parsing %s revision: %v", key, err), http.StatusBadRequest)
+					return
+				}
+				revision, err := repo.GetRecipeRevision(r.Context(), recipe.ListID, number)
+				if errors.Is(err, sql.ErrNoRows) {
+					http.Error(w, fmt.Sprintf("%s revision not found", key), http.StatusNotFound)
+					return
+				} else if err != nil {
+					http.Error(w, fmt.Sprintf("getting %s revision: %v", key, err), http.StatusInternalServerError)
+					return
+				}
+				revisions = append(revisions, revision)
+			}
+			from, to := revisions[0].Snapshot, revisions[1].Snapshot
+
+			products, err := hydrateTemplateProducts(r.Context(), config, repo, cache, authCookies.AccountID, data.RecipeSnapshotProductIDs(from, to))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			if err := templates.RecipeDiffView(app.DiffRecipeSnapshots(from, to), products).Render(w); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+
+		r.Post("/{number}/restore", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			recipe, ok := getOwnRecipe(w, r, authCookies.AccountID)
+			if !ok {
+				return
+			}
+
+			number, err := strconv.Atoi(chi.URLParam(r, "number"))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("parsing revision: %v", err), http.StatusBadRequest)
+				return
+			}
+
+			if err := repo.RestoreRecipeRevision(r.Context(), recipe.ListID, number); errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "revision not found", http.StatusNotFound)
+				return
+			} else if err != nil {
+				http.Error(w, fmt.Sprintf("restoring revision: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Add("HX-Redirect", fmt.Sprintf("/recipes/%s/history", recipe.ListID))
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+}
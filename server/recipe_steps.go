@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/templates"
+)
+
+// hydrateTemplateIngredients lists the ingredients of a list with their product details
+func hydrateTemplateIngredients(ctx context.Context, config Config, repo *data.Repository, cache *data.Cache, accountID, listID uuid.UUID) ([]templates.Ingredient, error) {
+	ingredients, err := repo.ListIngredients(ctx, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	productIDs := []string{}
+	for _, ingredient := range ingredients {
+		productIDs = append(productIDs, ingredient.ProductID)
+	}
+	products, err := hydrateTemplateProducts(ctx, config, repo, cache, accountID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	templateIngredients := []templates.Ingredient{}
+	for _, ingredient := range ingredients {
+		product, ok := products[ingredient.ProductID]
+		if !ok {
+			product = templates.Product{ProductID: ingredient.ProductID}
+		}
+		templateIngredients = append(templateIngredients, templates.Ingredient{
+			Product:     product,
+			ListID:      ingredient.ListID,
+			Quantity:    ingredient.Quantity,
+			Staple:      ingredient.Staple,
+			Amount:      ingredient.Amount,
+			Unit:        ingredient.Unit,
+			Note:        ingredient.Note,
+			DisplayName: ingredient.DisplayName,
+			Section:     ingredient.Section,
+		})
+	}
+	return templateIngredients, nil
+}
@@ -1,16 +1,98 @@
 package server
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 
+	"github.com/densestvoid/krogerrecipeshopper/app"
 	"github.com/densestvoid/krogerrecipeshopper/data"
 	"github.com/densestvoid/krogerrecipeshopper/templates"
 )
 
+const TagOptionsLimit = 10
+
+var recipeRatingOrderBys = []data.ListRecipesOrderBy{
+	{Field: "rating", Direction: "desc"},
+	{Field: "review_count", Direction: "desc"},
+}
+
+// recipeTagsFromForm includes a tag left in the picker's input without being added
+func recipeTagsFromForm(r *http.Request) ([]string, error) {
+	tags := r.PostForm["tags"]
+	if tag := r.PostForm.Get("tag"); tag != "" {
+		tags = append(tags, tag)
+	}
+	if len(tags) > data.RecipeTagsMax {
+		return nil, fmt.Errorf("a recipe can have at most %d tags", data.RecipeTagsMax)
+	}
+	return tags, nil
+}
+
+// recipeStepsFromForm reads the steps editor, whose timers are in minutes, and checks the steps only use the recipe's ingredients
+func recipeStepsFromForm(r *http.Request, ingredients []data.Ingredient) ([]data.RecipeStep, error) {
+	texts, timers := r.PostForm["step-text"], r.PostForm["step-timer"]
+	if len(timers) != len(texts) {
+		return nil, fmt.Errorf("expected a timer for each of the %d steps, got %d", len(texts), len(timers))
+	}
+
+	steps := []data.RecipeStep{}
+	for i, text := range texts {
+		step := data.RecipeStep{
+			Number:     i + 1,
+			Text:       strings.TrimSpace(text),
+			ProductIDs: r.PostForm[fmt.Sprintf("step-ingredients-%d", i)],
+		}
+		if timer := strings.TrimSpace(timers[i]); timer != "" {
+			minutes, err := strconv.ParseFloat(timer, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing step %d timer: %w", step.Number, err)
+			}
+			step.TimerSeconds = int(math.Round(minutes * 60))
+		}
+		steps = append(steps, step)
+	}
+	return steps, validateRecipeSteps(steps, ingredients)
+}
+
+// validateRecipeSteps checks the steps, whose ingredients must be in the recipe's ingredients
+func validateRecipeSteps(steps []data.RecipeStep, ingredients []data.Ingredient) error {
+	for _, step := range steps {
+		if step.Text == "" {
+			return fmt.Errorf("step %d is empty", step.Number)
+		} else if len(step.Text) > data.RecipeStepMaxLength {
+			return fmt.Errorf("step %d is longer than %d characters", step.Number, data.RecipeStepMaxLength)
+		} else if step.TimerSeconds < 0 {
+			return fmt.Errorf("step %d timer can't be negative", step.Number)
+		}
+		for _, productID := range step.ProductIDs {
+			if productID == "" || len(productID) > data.ProductIDMaxLength {
+				return fmt.Errorf("step %d has an invalid product ID %q", step.Number, productID)
+			} else if !slices.ContainsFunc(ingredients, func(ingredient data.Ingredient) bool { return ingredient.ProductID == productID }) {
+				return fmt.Errorf("step %d uses product %s, which isn't an ingredient", step.Number, productID)
+			}
+		}
+	}
+	return nil
+}
+
+// validateRecipeInstructionsLink only allows web links, since the link is opened by everyone viewing the recipe
+func validateRecipeInstructionsLink(instructionType, instructions string) error {
+	if instructionType == data.InstructionTypeLink && !app.WebURL(instructions) {
+		return fmt.Errorf("instructions link must be an http or https URL")
+	}
+	return nil
+}
+
 func NewRecipesMux(config Config, repo *data.Repository, cache *data.Cache) func(chi.Router) {
 	return func(r chi.Router) {
 		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
@@ -56,9 +138,16 @@ func NewRecipesMux(config Config, repo *data.Repository, cache *data.Cache) func
 			if name == "" {
 				http.Error(w, fmt.Sprintf("name missing: %v", err), http.StatusBadRequest)
 				return
+			} else if utf8.RuneCountInString(name) > data.ListNameMaxLength {
+				http.Error(w, fmt.Sprintf("name is longer than %d characters", data.ListNameMaxLength), http.StatusBadRequest)
+				return
 			}
 
 			description := r.FormValue("description")
+			if utf8.RuneCountInString(description) > data.ListDescriptionMaxLength {
+				http.Error(w, fmt.Sprintf("description is longer than %d characters", data.ListDescriptionMaxLength), http.StatusBadRequest)
+				return
+			}
 
 			instructionType := r.FormValue("instruction-type")
 			if instructionType == "" {
@@ -66,11 +155,50 @@ func NewRecipesMux(config Config, repo *data.Repository, cache *data.Cache) func
 				return
 			}
 
+			// Steps can use the ingredients of the recipe being updated, a new recipe has none yet
+			var listID uuid.UUID
+			var ingredients []data.Ingredient
+			if r.PostForm.Has("id") {
+				if listID, err = uuid.Parse(r.PostForm.Get("id")); err != nil {
+					http.Error(w, fmt.Sprintf("parsing recipe id: %v", err), http.StatusBadRequest)
+					return
+				}
+
+				recipe, err := repo.GetRecipe(r.Context(), listID, authCookies.AccountID)
+				if errors.Is(err, sql.ErrNoRows) {
+					http.Error(w, "not found", http.StatusNotFound)
+					return
+				} else if err != nil {
+					http.Error(w, fmt.Sprintf("getting recipe: %v", err), http.StatusInternalServerError)
+					return
+				} else if recipe.AccountID != authCookies.AccountID {
+					http.Error(w, "Can't update recipes you didn't create", http.StatusBadRequest)
+					return
+				}
+
+				if ingredients, err = repo.ListIngredients(r.Context(), listID); err != nil {
+					http.Error(w, fmt.Sprintf("listing recipe ingredients: %v", err), http.StatusInternalServerError)
+					return
+				}
+			}
+			steps, err := recipeStepsFromForm(r, ingredients)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
 			instructions := r.FormValue("instructions")
+			if instructionType == data.InstructionTypeText {
+				instructions = data.JoinRecipeSteps(steps)
+			}
 			if instructionType != data.InstructionTypeNone && instructions == "" {
 				http.Error(w, fmt.Sprintf("instructions missing: %v", err), http.StatusBadRequest)
 				return
 			}
+			if err := validateRecipeInstructionsLink(instructionType, instructions); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 
 			visibility := r.FormValue("visibility")
 			if visibility == "" {
@@ -78,13 +206,13 @@ func NewRecipesMux(config Config, repo *data.Repository, cache *data.Cache) func
 				return
 			}
 
-			if r.PostForm.Has("id") {
-				listID, err := uuid.Parse(r.PostForm.Get("id"))
-				if err != nil {
-					http.Error(w, fmt.Sprintf("parsing recipe id: %v", err), http.StatusBadRequest)
-					return
-				}
+			tags, err := recipeTagsFromForm(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 
+			if r.PostForm.Has("id") {
 				if err := repo.UpdateRecipe(r.Context(), data.Recipe{
 					ListID:          listID,
 					AccountID:       authCookies.AccountID,
@@ -93,20 +221,114 @@ func NewRecipesMux(config Config, repo *data.Repository, cache *data.Cache) func
 					InstructionType: instructionType,
 					Instructions:    instructions,
 					Visibility:      visibility,
+					Steps:           steps,
 				}); err != nil {
 					http.Error(w, fmt.Sprintf("updating recipe: %v", err), http.StatusInternalServerError)
 					return
 				}
+				if err := repo.SetRecipeTags(r.Context(), listID, tags); err != nil {
+					http.Error(w, fmt.Sprintf("setting recipe tags: %v", err), http.StatusInternalServerError)
+					return
+				}
 			} else {
-				_, err := repo.CreateRecipe(r.Context(), authCookies.AccountID, name, description, instructionType, instructions, visibility)
+				listID, err := repo.CreateRecipe(r.Context(), authCookies.AccountID, name, description, instructionType, instructions, visibility, steps)
 				if err != nil {
 					http.Error(w, fmt.Sprintf("creating recipe: %v", err), http.StatusInternalServerError)
 					return
 				}
+				if err := repo.SetRecipeTags(r.Context(), listID, tags); err != nil {
+					http.Error(w, fmt.Sprintf("setting recipe tags: %v", err), http.StatusInternalServerError)
+					return
+				}
 			}
 			w.Header().Add("HX-Trigger", "recipe-update")
 			w.WriteHeader(http.StatusOK)
 		})
+		r.Get("/products", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			productIDs := r.URL.Query()["productID"]
+			if len(productIDs) == 0 {
+				http.Error(w, "product ID missing", http.StatusBadRequest)
+				return
+			}
+
+			account, err := repo.GetAccountByID(r.Context(), authCookies.AccountID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			krogerManager, err := newKrogerManager(r.Context(), config, cache)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			productsByID, err := krogerManager.GetProducts(r.Context(), account.LocationID, productIDs...)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			products := []templates.Product{}
+			for _, productID := range productIDs {
+				product := productsByID[productID]
+				products = append(products, templates.Product{
+					ProductID:   productID,
+					Brand:       product.Brand,
+					Description: product.Description,
+					Size:        product.Size,
+				})
+			}
+
+			productMatch := r.URL.Query().Get("match")
+			if productMatch != templates.MatchAll {
+				productMatch = templates.MatchAny
+			}
+
+			if err := templates.ProductRecipes(products, productMatch).Render(w); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+		r.Post("/preview", func(w http.ResponseWriter, r *http.Request) {
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			recipe := data.Recipe{
+				Description:     r.PostForm.Get("description"),
+				InstructionType: r.PostForm.Get("instruction-type"),
+				Instructions:    r.PostForm.Get("instructions"),
+			}
+			// Unfinished steps are still previewed, they're validated when the recipe is submitted
+			recipe.Steps, _ = recipeStepsFromForm(r, nil)
+
+			if err := templates.RecipePreview(recipe).Render(w); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+		r.Get("/tags", func(w http.ResponseWriter, r *http.Request) {
+			tags, err := repo.ListTags(r.Context(), r.URL.Query().Get("tag"), TagOptionsLimit)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			if err := templates.TagOptions(tags).Render(w); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
 		r.Post("/search", func(w http.ResponseWriter, r *http.Request) {
 			authCookies, err := GetAuthCookies(r)
 			if err != nil {
@@ -123,35 +345,63 @@ func NewRecipesMux(config Config, repo *data.Repository, cache *data.Cache) func
 			if r.Form.Has("accountID") {
 				filters = append(filters, data.ListRecipesFilterByAccountID{AccountID: uuid.MustParse(r.Form.Get("accountID"))})
 			}
+			orderBys := []data.ListRecipesOrderBy{}
+			if r.Form.Get("sort") == templates.RecipeSortRating {
+				orderBys = append(orderBys, recipeRatingOrderBys...)
+			}
 			if r.Form.Has("name") && r.Form.Get("name") != "" {
-				filters = append(filters, data.ListRecipesFilterByName{Name: r.FormValue("name")})
+				filters = append(filters, data.ListRecipesFilterBySearch{Search: r.FormValue("name")})
+				orderBys = append(orderBys, data.ListRecipesOrderBy{Field: "search_rank", Direction: "desc"})
 			}
 			if r.Form.Has("favorites") {
 				filters = append(filters, data.ListRecipesFilterByFavorites{})
 			}
+			if r.Form.Has("productID") {
+				filters = append(filters, data.ListRecipesFilterByProducts{
+					ProductIDs: r.Form["productID"],
+					All:        r.Form.Get("product-match") == templates.MatchAll,
+				})
+			}
 			filters = append(filters, data.ListRecipesFilterByVisibilities{Visibilities: r.Form["visibility"]})
-			if len(filters) == 0 {
-				if err := templates.RecipeTable(authCookies.AccountID, nil).Render(w); err != nil {
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				}
+
+			// Facets ignore the tags when matching any of them so the other tags can still be added
+			tagMatch := r.Form.Get("tag-match")
+			if tagMatch != templates.MatchAll {
+				tagMatch = templates.MatchAny
+			}
+			facetFilters := filters
+			filters = append(filters, data.ListRecipesFilterByTags{Tags: r.Form["tags"], All: tagMatch == templates.MatchAll})
+			if tagMatch == templates.MatchAll {
+				facetFilters = filters
+			}
+
+			recipes, err := repo.ListRecipes(r.Context(), authCookies.AccountID, filters, append(orderBys, data.ListRecipesOrderBy{
+				Field: "name", Direction: "asc",
+			}))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
 
-			recipes, err := repo.ListRecipes(r.Context(), authCookies.AccountID, filters, []data.ListRecipesOrderBy{
-				{Field: "name", Direction: "asc"},
-			})
+			facets, err := repo.ListRecipeTagFacets(r.Context(), authCookies.AccountID, facetFilters)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
 
-			if err := templates.RecipeTable(authCookies.AccountID, recipes).Render(w); err != nil {
+			account, err := repo.GetAccountByID(r.Context(), authCookies.AccountID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			if err := templates.RecipeSearchResults(authCookies.AccountID, account.ImageSize, recipes, facets, r.Form["tags"], tagMatch).Render(w); err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
 			w.WriteHeader(http.StatusOK)
 		})
+		r.Route("/import", NewRecipeImportMux(repo))
 		r.Route("/{id}", func(r chi.Router) {
 			r.Get("/details", func(w http.ResponseWriter, r *http.Request) {
 				authCookies, err := GetAuthCookies(r)
@@ -169,7 +419,64 @@ func NewRecipesMux(config Config, repo *data.Repository, cache *data.Cache) func
 					}
 				}
 
-				if err := templates.RecipeDetailsModalContent(authCookies.AccountID, recipe, false).Render(w); err != nil {
+				curatedTags, err := repo.ListCuratedTags(r.Context())
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				// Editing links the recipe's ingredients to its steps
+				var ingredients []templates.Ingredient
+				if recipe.ListID != uuid.Nil && recipe.AccountID == authCookies.AccountID {
+					ingredients, err = hydrateTemplateIngredients(r.Context(), config, repo, cache, authCookies.AccountID, recipe.ListID)
+					if err != nil {
+						http.Error(w, err.Error(), http.StatusInternalServerError)
+						return
+					}
+				}
+
+				account, err := repo.GetAccountByID(r.Context(), authCookies.AccountID)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				if err := templates.RecipeDetailsModalContent(authCookies.AccountID, account.ImageSize, recipe, curatedTags, ingredients, false).Render(w); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			r.Get("/cook", func(w http.ResponseWriter, r *http.Request) {
+				authCookies, err := GetAuthCookies(r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+
+				listID, err := uuid.Parse(chi.URLParam(r, "id"))
+				if err != nil {
+					http.Error(w, fmt.Sprintf("parsing recipe id: %v", err), http.StatusBadRequest)
+					return
+				}
+
+				recipe, err := repo.GetRecipe(r.Context(), listID, authCookies.AccountID)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				} else if !RecipeVisible(recipe, authCookies.AccountID) {
+					http.Error(w, "not found", http.StatusNotFound)
+					return
+				}
+
+				ingredients, err := hydrateTemplateIngredients(r.Context(), config, repo, cache, authCookies.AccountID, recipe.ListID)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				if err := templates.RecipeCookMode(recipe, ingredients).Render(w); err != nil {
 					http.Error(w, err.Error(), http.StatusInternalServerError)
 					return
 				}
@@ -192,7 +499,29 @@ func NewRecipesMux(config Config, repo *data.Repository, cache *data.Cache) func
 					}
 				}
 
-				if err := templates.RecipeDetailsModalContent(authCookies.AccountID, recipe, true).Render(w); err != nil {
+				curatedTags, err := repo.ListCuratedTags(r.Context())
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				// Editing links the recipe's ingredients to its steps
+				var ingredients []templates.Ingredient
+				if recipe.ListID != uuid.Nil && RecipeVisible(recipe, authCookies.AccountID) {
+					ingredients, err = hydrateTemplateIngredients(r.Context(), config, repo, cache, authCookies.AccountID, recipe.ListID)
+					if err != nil {
+						http.Error(w, err.Error(), http.StatusInternalServerError)
+						return
+					}
+				}
+
+				account, err := repo.GetAccountByID(r.Context(), authCookies.AccountID)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				if err := templates.RecipeDetailsModalContent(authCookies.AccountID, account.ImageSize, recipe, curatedTags, ingredients, true).Render(w); err != nil {
 					http.Error(w, err.Error(), http.StatusInternalServerError)
 					return
 				}
@@ -215,9 +544,16 @@ func NewRecipesMux(config Config, repo *data.Repository, cache *data.Cache) func
 				if name == "" {
 					http.Error(w, fmt.Sprintf("name missing: %v", err), http.StatusBadRequest)
 					return
+				} else if utf8.RuneCountInString(name) > data.ListNameMaxLength {
+					http.Error(w, fmt.Sprintf("name is longer than %d characters", data.ListNameMaxLength), http.StatusBadRequest)
+					return
 				}
 
 				description := r.FormValue("description")
+				if utf8.RuneCountInString(description) > data.ListDescriptionMaxLength {
+					http.Error(w, fmt.Sprintf("description is longer than %d characters", data.ListDescriptionMaxLength), http.StatusBadRequest)
+					return
+				}
 
 				instructionType := r.FormValue("instruction-type")
 				if instructionType == "" {
@@ -225,11 +561,48 @@ func NewRecipesMux(config Config, repo *data.Repository, cache *data.Cache) func
 					return
 				}
 
+				recipeToCopyID, err := uuid.Parse(r.PostForm.Get("id"))
+				if err != nil {
+					http.Error(w, fmt.Sprintf("parsing recipe id: %v", err), http.StatusBadRequest)
+					return
+				}
+
+				recipeToCopy, err := repo.GetRecipe(r.Context(), recipeToCopyID, authCookies.AccountID)
+				if errors.Is(err, sql.ErrNoRows) {
+					http.Error(w, "not found", http.StatusNotFound)
+					return
+				} else if err != nil {
+					http.Error(w, fmt.Sprintf("getting recipe to copy: %v", err), http.StatusInternalServerError)
+					return
+				} else if !RecipeVisible(recipeToCopy, authCookies.AccountID) {
+					http.Error(w, "not found", http.StatusNotFound)
+					return
+				}
+
+				// Steps can use the ingredients of the recipe being copied
+				ingredients, err := repo.ListIngredients(r.Context(), recipeToCopy.ListID)
+				if err != nil {
+					http.Error(w, fmt.Sprintf("listing recipe ingredients: %v", err), http.StatusInternalServerError)
+					return
+				}
+				steps, err := recipeStepsFromForm(r, ingredients)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
 				instructions := r.FormValue("instructions")
+				if instructionType == data.InstructionTypeText {
+					instructions = data.JoinRecipeSteps(steps)
+				}
 				if instructionType != data.InstructionTypeNone && instructions == "" {
 					http.Error(w, fmt.Sprintf("instructions missing: %v", err), http.StatusBadRequest)
 					return
 				}
+				if err := validateRecipeInstructionsLink(instructionType, instructions); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
 
 				visibility := r.FormValue("visibility")
 				if visibility == "" {
@@ -237,31 +610,23 @@ func NewRecipesMux(config Config, repo *data.Repository, cache *data.Cache) func
 					return
 				}
 
-				recipeToCopyID, err := uuid.Parse(r.PostForm.Get("id"))
+				tags, err := recipeTagsFromForm(r)
 				if err != nil {
-					http.Error(w, fmt.Sprintf("parsing recipe id: %v", err), http.StatusBadRequest)
+					http.Error(w, err.Error(), http.StatusBadRequest)
 					return
 				}
 
-				newListID, err := repo.CreateRecipe(r.Context(), authCookies.AccountID, name, description, instructionType, instructions, visibility)
+				newListID, err := repo.ForkRecipe(r.Context(), recipeToCopy.ListID, authCookies.AccountID, name, description, instructionType, instructions, visibility, steps)
 				if err != nil {
 					http.Error(w, fmt.Sprintf("creating new recipe: %v", err), http.StatusInternalServerError)
 					return
 				}
 
-				ingredients, err := repo.ListIngredients(r.Context(), recipeToCopyID)
-				if err != nil {
-					http.Error(w, fmt.Sprintf("listing ingredients to copy: %v", err), http.StatusInternalServerError)
+				if err := repo.SetRecipeTags(r.Context(), newListID, tags); err != nil {
+					http.Error(w, fmt.Sprintf("setting copied recipe tags: %v", err), http.StatusInternalServerError)
 					return
 				}
 
-				for _, ingredient := range ingredients {
-					if err := repo.CreateIngredient(r.Context(), ingredient.ProductID, newListID, ingredient.Quantity, ingredient.Staple); err != nil {
-						http.Error(w, fmt.Sprintf("adding ingredient to copied recipe: %v", err), http.StatusInternalServerError)
-						return
-					}
-				}
-
 				w.Header().Add("HX-Trigger", "recipe-update")
 				w.WriteHeader(http.StatusOK)
 			})
@@ -287,11 +652,20 @@ func NewRecipesMux(config Config, repo *data.Repository, cache *data.Cache) func
 					http.Error(w, fmt.Sprintf("deleting recipe: %v", err), http.StatusInternalServerError)
 					return
 				}
+				if recipe.PhotoID != nil {
+					deleteRecipePhoto(r.Context(), config.Photos, *recipe.PhotoID)
+				}
 
 				w.Header().Add("HX-Trigger", "recipe-update")
 				w.WriteHeader(http.StatusOK)
 			})
 
+			r.Route("/reviews", NewRecipeReviewsMux(repo))
+			r.Route("/history", NewRecipeHistoryMux(config, repo, cache))
+			r.Route("/source", NewRecipeSourceMux(config, repo, cache))
+			r.Route("/photo", NewRecipePhotoMux(config, repo))
+			r.Route("/import", NewRecipeImportQueueMux(config, repo, cache))
+
 			r.Post("/favorite", func(w http.ResponseWriter, r *http.Request) {
 				authCookies, err := GetAuthCookies(r)
 				if err != nil {
@@ -12,6 +12,7 @@ import (
 
 	"github.com/densestvoid/krogerrecipeshopper/assets"
 	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/storage"
 	"github.com/densestvoid/krogerrecipeshopper/templates"
 )
 
@@ -19,6 +20,10 @@ type Config struct {
 	ClientID     string
 	ClientSecret string
 	Domain       string
+	// TokenVault keeps Kroger tokens for background operations, nil if no vault key is configured
+	TokenVault *data.KrogerTokenVault
+	// Photos stores uploaded recipe photos
+	Photos storage.Storage
 }
 
 func New(ctx context.Context, logger *slog.Logger, config Config, repo *data.Repository, cache *data.Cache) http.Handler {
@@ -30,14 +35,19 @@ func New(ctx context.Context, logger *slog.Logger, config Config, repo *data.Rep
 		}),
 		NewErrorStatusMiddleware(),
 		NewSlogMiddleware(),
+		NewCSRFMiddleware(),
 	)
 
+	// Handlers for vendored frontend assets
+	mux.Handle(assets.Prefix+"*", assets.Handler())
+
 	// Handlers for favicons
 	mux.Handle("/*", middleware.SetHeader("Cache-Control", "max-age=86400")(
 		http.FileServer(http.FS(assets.Files)),
 	))
 
-	mux.Route("/auth", NewAuthMux(config, repo))
+	mux.Route("/auth", NewAuthMux(config, repo, cache))
+	mux.Route("/api/v1", NewAPIMux(config, repo, cache))
 	mux.Group(func(r chi.Router) {
 		r.Use(AuthenticationMiddleware(config, repo))
 		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
@@ -79,6 +89,7 @@ func New(ctx context.Context, logger *slog.Logger, config Config, repo *data.Rep
 		r.Route("/locations", NewLocationsMux(config))
 		r.Route("/cart", NewCartMux(config, repo, cache))
 		r.Route("/shopping-list", NewShoppingListMux(config, repo, cache))
+		r.Route("/bundles", NewBundlesMux(repo))
 	})
 
 	return mux
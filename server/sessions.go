@@ -0,0 +1,110 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/templates"
+)
+
+func NewSessionsMux(config Config, repo *data.Repository) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			requestedAccountID := uuid.MustParse(chi.URLParam(r, "accountID"))
+			if authCookies.AccountID != requestedAccountID {
+				http.Error(w, "listing sessions", http.StatusUnauthorized)
+				return
+			}
+
+			sessions, err := repo.ListSessions(r.Context(), requestedAccountID)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("listing sessions: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			if err := templates.SessionsTable(requestedAccountID, authCookies.SessionID, sessions).Render(w); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+
+		// Log out everywhere
+		r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			requestedAccountID := uuid.MustParse(chi.URLParam(r, "accountID"))
+			if authCookies.AccountID != requestedAccountID {
+				http.Error(w, "revoking sessions", http.StatusUnauthorized)
+				return
+			}
+
+			if err := repo.DeleteAccountSessions(r.Context(), requestedAccountID); err != nil {
+				http.Error(w, fmt.Sprintf("revoking sessions: %v", err), http.StatusInternalServerError)
+				return
+			}
+			// Logging out everywhere also stops the server from acting for the account
+			if config.TokenVault != nil {
+				if err := config.TokenVault.Delete(r.Context(), requestedAccountID); err != nil {
+					http.Error(w, fmt.Sprintf("deleting kroger tokens: %v", err), http.StatusInternalServerError)
+					return
+				}
+			}
+			ClearAuthCookies(w)
+		})
+
+		r.Delete("/{sessionID}", func(w http.ResponseWriter, r *http.Request) {
+			authCookies, err := GetAuthCookies(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			requestedAccountID := uuid.MustParse(chi.URLParam(r, "accountID"))
+			if authCookies.AccountID != requestedAccountID {
+				http.Error(w, "revoking session", http.StatusUnauthorized)
+				return
+			}
+
+			sessionID, err := uuid.Parse(chi.URLParam(r, "sessionID"))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid session id: %v", err), http.StatusBadRequest)
+				return
+			}
+
+			session, err := repo.GetSessionByID(r.Context(), sessionID)
+			if errors.Is(err, sql.ErrNoRows) || (err == nil && session.AccountID != requestedAccountID) {
+				http.Error(w, "session not found", http.StatusNotFound)
+				return
+			} else if err != nil {
+				http.Error(w, fmt.Sprintf("getting session: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			if err := repo.DeleteSession(r.Context(), session.ID); err != nil {
+				http.Error(w, fmt.Sprintf("revoking session: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			if session.ID == authCookies.SessionID {
+				ClearAuthCookies(w)
+				return
+			}
+			w.Header().Add("HX-Trigger", "session-update")
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+}
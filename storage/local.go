@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+
+	"github.com/google/uuid"
+)
+
+// Local stores files in a directory of the local filesystem, keys can't escape the directory
+type Local struct {
+	root *os.Root
+}
+
+func NewLocal(dir string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Local{root: root}, nil
+}
+
+func (l *Local) Close() error {
+	return l.root.Close()
+}
+
+func (l *Local) Put(ctx context.Context, key string, body io.Reader) (retErr error) {
+	if !fs.ValidPath(key) {
+		return fmt.Errorf("invalid key %q", key)
+	}
+	if err := l.root.MkdirAll(path.Dir(key), 0o750); err != nil {
+		return err
+	}
+
+	// Written to a temporary file first so a partial file is never read
+	tmp := fmt.Sprintf("%s.%s.tmp", key, uuid.New())
+	file, err := l.root.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if retErr != nil {
+			_ = l.root.Remove(tmp)
+		}
+	}()
+
+	if _, err := io.Copy(file, body); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return l.root.Rename(tmp, key)
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if !fs.ValidPath(key) {
+		return nil, fmt.Errorf("invalid key %q", key)
+	}
+	file, err := l.root.Open(key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotExist
+	}
+	return file, err
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	if !fs.ValidPath(key) {
+		return fmt.Errorf("invalid key %q", key)
+	}
+	if err := l.root.Remove(key); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+// Package storage keeps uploaded files, such as recipe photos, outside of the database
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotExist is returned when getting a key that isn't stored
+var ErrNotExist = errors.New("storage: key does not exist")
+
+// Storage stores files by slash separated keys, like "recipes/photo/small.jpg"
+type Storage interface {
+	// Put stores the body under the key, replacing anything already stored under it
+	Put(ctx context.Context, key string, body io.Reader) error
+	// Get returns ErrNotExist if nothing is stored under the key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete does nothing if nothing is stored under the key
+	Delete(ctx context.Context, key string) error
+}
@@ -2,6 +2,7 @@ package templates
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/densestvoid/krogerrecipeshopper/data"
 	"github.com/google/uuid"
@@ -15,6 +16,8 @@ type Account struct {
 	ImageSize string
 	Location  *data.CacheLocation
 	Homepage  string
+	// How an ingredient's product is picked from its alternatives
+	AlternativeChoice string
 }
 
 func AccountPage(account Account, profile *data.Profile) gomponents.Node {
@@ -33,6 +36,9 @@ func AccountPage(account Account, profile *data.Profile) gomponents.Node {
 					Profile(account, profile),
 				),
 				Settings(account),
+				AccessTokens(account),
+				Sessions(account),
+				Backup(),
 				html.Button(
 					html.Type("button"),
 					html.Class("btn btn-danger"),
@@ -181,6 +187,10 @@ func Settings(account Account) gomponents.Node {
 					data.ImageSizeLarge,
 					data.ImageSizeExtraLarge,
 				}, nil),
+				Select("accountAlternativeChoice", "Ingredient alternatives", "alternativeChoice", account.AlternativeChoice, []string{
+					data.AlternativeChoiceInStock,
+					data.AlternativeChoiceCheapest,
+				}, nil),
 			),
 		),
 		html.Div(
@@ -195,6 +205,207 @@ func Settings(account Account) gomponents.Node {
 	)
 }
 
+func AccessTokens(account Account) gomponents.Node {
+	return html.Div(
+		html.Class("card m-1"),
+		html.Div(
+			html.Class("card-header"),
+			gomponents.Text("Access tokens"),
+		),
+		html.Div(
+			html.Class("card-body"),
+			html.P(
+				html.Class("text-body-secondary"),
+				gomponents.Text("Access tokens authenticate requests to the API with an Authorization: Bearer header. Sending your cart to Kroger still requires signing in through the website."),
+			),
+			html.Div(
+				htmx.Get(fmt.Sprintf("/accounts/%s/tokens", account.ID)),
+				htmx.Trigger("load, access-token-update from:body"),
+			),
+			html.Div(html.ID("access-token-created")),
+			html.Form(
+				html.ID("access-token-form"),
+				htmx.Post(fmt.Sprintf("/accounts/%s/tokens", account.ID)),
+				htmx.Target("#access-token-created"),
+				gomponents.Attr("hx-on::after-request", "if(event.detail.successful) this.reset()"),
+				FormInput(
+					"access-token-name",
+					"Name",
+					nil,
+					html.Input(
+						html.ID("access-token-name"),
+						html.Class("form-control"),
+						html.Required(),
+						html.Type("text"),
+						html.MaxLength("128"),
+						html.Name("name"),
+					),
+				),
+				Select("access-token-scope", "Scope", "scope", data.AccessTokenScopeRead, []string{
+					data.AccessTokenScopeRead,
+					data.AccessTokenScopeWrite,
+				}, nil),
+			),
+		),
+		html.Div(
+			html.Class("card-footer"),
+			html.Button(
+				html.Type("submit"),
+				html.Class("btn btn-primary"),
+				gomponents.Text("Create token"),
+				html.FormAttr("access-token-form"),
+			),
+		),
+	)
+}
+
+func AccessTokensTable(accountID uuid.UUID, tokens []data.AccessToken) gomponents.Node {
+	if len(tokens) == 0 {
+		return html.P(gomponents.Text("No access tokens"))
+	}
+
+	var tokenRows gomponents.Group
+	for _, token := range tokens {
+		lastUsed := "Never"
+		if token.LastUsedAt != nil {
+			lastUsed = token.LastUsedAt.Format(time.DateOnly)
+		}
+		tokenRows = append(tokenRows, html.Tr(
+			html.Td(gomponents.Text(token.Name)),
+			html.Td(gomponents.Text(token.Scope)),
+			html.Td(
+				// Hide if the screen is small
+				html.Class("d-none d-sm-table-cell"),
+				gomponents.Text(token.CreatedAt.Format(time.DateOnly)),
+			),
+			html.Td(gomponents.Text(lastUsed)),
+			html.Td(
+				html.Button(
+					html.Type("button"),
+					html.Class("btn btn-danger"),
+					gomponents.Text("Revoke"),
+					htmx.Delete(fmt.Sprintf("/accounts/%s/tokens/%s", accountID, token.ID)),
+					htmx.Swap("none"),
+					htmx.Confirm(fmt.Sprintf("Are you sure you want to revoke %q? Anything using it will no longer be able to access the API.", token.Name)),
+				),
+			),
+		))
+	}
+
+	return html.Table(
+		html.Class("table table-striped table-bordered text-center align-middle w-100"),
+		html.THead(
+			html.Tr(
+				html.Th(gomponents.Text("Name")),
+				html.Th(gomponents.Text("Scope")),
+				html.Th(
+					// Hide if the screen is small
+					html.Class("d-none d-sm-table-cell"),
+					gomponents.Text("Created"),
+				),
+				html.Th(gomponents.Text("Last used")),
+				html.Th(gomponents.Text("Actions")),
+			),
+		),
+		html.TBody(
+			html.Class("table-group-divider"),
+			tokenRows,
+		),
+	)
+}
+
+// AccessTokenCreated shows the new token, it can't be retrieved again after this
+func AccessTokenCreated(name, token string) gomponents.Node {
+	return html.Div(
+		html.Role("alert"),
+		html.Class("alert alert-success text-start"),
+		html.P(gomponents.Textf("Created %q. Copy the token now, it won't be shown again.", name)),
+		html.Code(
+			html.Class("user-select-all text-break"),
+			gomponents.Text(token),
+		),
+	)
+}
+
+func Sessions(account Account) gomponents.Node {
+	return html.Div(
+		html.Class("card m-1"),
+		html.Div(
+			html.Class("card-header"),
+			gomponents.Text("Active sessions"),
+		),
+		html.Div(
+			html.Class("card-body"),
+			htmx.Get(fmt.Sprintf("/accounts/%s/sessions", account.ID)),
+			htmx.Trigger("load, session-update from:body"),
+		),
+		html.Div(
+			html.Class("card-footer"),
+			html.Button(
+				html.Type("button"),
+				html.Class("btn btn-danger"),
+				gomponents.Text("Log out everywhere"),
+				htmx.Delete(fmt.Sprintf("/accounts/%s/sessions", account.ID)),
+				htmx.Swap("none"),
+				htmx.Confirm("Are you sure you want to log out of every session, including this one?"),
+			),
+		),
+	)
+}
+
+func SessionsTable(accountID, currentSessionID uuid.UUID, sessions []data.Session) gomponents.Node {
+	var sessionRows gomponents.Group
+	for _, session := range sessions {
+		current := session.ID == currentSessionID
+		sessionRows = append(sessionRows, html.Tr(
+			html.Td(
+				html.Class("text-break"),
+				gomponents.Text(session.UserAgent),
+				gomponents.If(current, html.Span(
+					html.Class("badge text-bg-primary ms-1"),
+					gomponents.Text("This session"),
+				)),
+			),
+			html.Td(
+				// Hide if the screen is small
+				html.Class("d-none d-sm-table-cell"),
+				gomponents.Text(session.CreatedAt.Format(time.DateOnly)),
+			),
+			html.Td(gomponents.Text(session.LastSeenAt.Format(time.DateTime))),
+			html.Td(
+				html.Button(
+					html.Type("button"),
+					html.Class("btn btn-danger"),
+					gomponents.Text("Revoke"),
+					htmx.Delete(fmt.Sprintf("/accounts/%s/sessions/%s", accountID, session.ID)),
+					htmx.Swap("none"),
+					gomponents.If(current, htmx.Confirm("This is your current session, revoking it will log you out. Continue?")),
+				),
+			),
+		))
+	}
+
+	return html.Table(
+		html.Class("table table-striped table-bordered text-center align-middle w-100"),
+		html.THead(
+			html.Tr(
+				html.Th(gomponents.Text("Device")),
+				html.Th(
+					// Hide if the screen is small
+					html.Class("d-none d-sm-table-cell"),
+					gomponents.Text("Signed in"),
+				),
+				html.Th(gomponents.Text("Last seen")),
+				html.Th(gomponents.Text("Actions")),
+			),
+		),
+		html.TBody(
+			html.Class("table-group-divider"),
+			sessionRows,
+		),
+	)
+}
+
 func LocationNode(location *data.CacheLocation) gomponents.Node {
 	if location == nil {
 		return html.P(gomponents.Text("None"))
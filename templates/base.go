@@ -1,6 +1,8 @@
 package templates
 
 import (
+	"fmt"
+
 	"maragu.dev/gomponents"
 	htmx "maragu.dev/gomponents-htmx"
 	"maragu.dev/gomponents/html"
@@ -8,6 +10,11 @@ import (
 	"github.com/densestvoid/krogerrecipeshopper/assets"
 )
 
+const (
+	CSRFCookie = "csrfToken"
+	CSRFHeader = "X-CSRF-Token"
+)
+
 func BasePage(title, baseURL string, bodyNodes gomponents.Group) gomponents.Node {
 	return html.Doctype(
 		html.HTML(
@@ -49,26 +56,26 @@ func baseHead(title, baseURL string) gomponents.Node {
 		),
 
 		// Bootstrap CSS
-		html.Link(
-			html.Href(assets.BootstrapCSS()),
-			html.Rel("stylesheet"),
-			html.CrossOrigin("anonymous"),
-		),
+		stylesheet(assets.BootstrapCSS()),
 
 		// Bootstrap Icons
-		html.Link(
-			html.Href(assets.BootstrapIconsCSS()),
-			html.Rel("stylesheet"),
-		),
+		stylesheet(assets.BootstrapIconsCSS()),
 
 		// Relative URLs base
 		html.Base(html.Href(baseURL)),
+
+		// Reads the CSRF token issued to this browser, sent with every htmx request
+		html.Script(gomponents.Rawf(`function csrfToken() {
+			const cookie = document.cookie.split("; ").find((cookie) => cookie.startsWith("%s="));
+			return cookie ? cookie.split("=")[1] : "";
+		}`, CSRFCookie)),
 	)
 }
 
 func baseBody(bodyNodes gomponents.Node) gomponents.Node {
 	return html.Body(
 		html.Class("min-vh-100 d-flex flex-column"),
+		htmx.Headers(fmt.Sprintf(`js:{"%s": csrfToken()}`, CSRFHeader)),
 
 		// HTMX response toast messages
 		html.Div(
@@ -103,19 +110,40 @@ func baseBody(bodyNodes gomponents.Node) gomponents.Node {
 		Modal(),
 
 		// Bootstrap JS
-		html.Script(
-			html.Src(assets.BootstrapJS()),
-			html.CrossOrigin("anonymous"),
-		),
+		script(assets.BootstrapJS()),
 
 		// Alpine JS
-		html.Script(
-			html.Src(assets.AlpineJS()),
-			html.Defer(),
-		),
+		script(assets.AlpineJS(), html.Defer()),
 
 		// HTMX
-		html.Script(html.Src(assets.HTMX())),
-		html.Script(html.Src(assets.HTMXRemoveMe())), // Auto remove elements (alerts)
+		script(assets.HTMX()),
+		script(assets.HTMXRemoveMe()), // Auto remove elements (alerts)
 	)
 }
+
+func stylesheet(asset assets.Asset) gomponents.Node {
+	return html.Link(
+		html.Href(asset.URL),
+		html.Rel("stylesheet"),
+		integrity(asset),
+	)
+}
+
+func script(asset assets.Asset, attributes ...gomponents.Node) gomponents.Node {
+	return html.Script(
+		html.Src(asset.URL),
+		integrity(asset),
+		gomponents.Group(attributes),
+	)
+}
+
+// Subresource integrity, only known when the asset has been vendored
+func integrity(asset assets.Asset) gomponents.Node {
+	if asset.Integrity == "" {
+		return nil
+	}
+	return gomponents.Group{
+		html.Integrity(asset.Integrity),
+		html.CrossOrigin("anonymous"),
+	}
+}
@@ -0,0 +1,128 @@
+package templates
+
+import (
+	"maragu.dev/gomponents"
+	htmx "maragu.dev/gomponents-htmx"
+	"maragu.dev/gomponents/html"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+)
+
+// Backup exports recipes and lists to a JSON bundle and imports them back
+func Backup() gomponents.Node {
+	return html.Div(
+		html.Class("card m-1"),
+		html.Div(
+			html.Class("card-header"),
+			gomponents.Text("Backup"),
+		),
+		html.Div(
+			html.Class("card-body"),
+			html.P(
+				html.Class("text-body-secondary"),
+				gomponents.Text("Export your recipes and lists to a JSON file, and import them back into this or another account."),
+			),
+			html.A(
+				html.Class("btn btn-primary m-1"),
+				html.Href("/bundles/export"),
+				gomponents.Text("Export everything"),
+			),
+			html.A(
+				html.Class("btn btn-secondary m-1"),
+				html.Role("button"),
+				html.Data("bs-toggle", "collapse"),
+				html.Href("#bundle-export-selection"),
+				gomponents.Text("Choose what to export"),
+			),
+			html.Form(
+				html.ID("bundle-export-selection"),
+				html.Class("collapse text-start"),
+				html.Method("get"),
+				html.Action("/bundles/export"),
+				html.Div(
+					htmx.Get("/bundles/selection"),
+					htmx.Trigger("load"),
+				),
+				html.Button(
+					html.Type("submit"),
+					html.Class("btn btn-primary"),
+					gomponents.Text("Export selected"),
+				),
+			),
+			html.Hr(),
+			html.Form(
+				htmx.Post("/bundles/import"),
+				htmx.Encoding("multipart/form-data"),
+				htmx.Target("#bundle-import-result"),
+				gomponents.Attr("hx-on::after-request", "if(event.detail.successful) this.reset()"),
+				html.Input(
+					html.Class("form-control"),
+					html.Type("file"),
+					html.Name("bundle"),
+					html.Accept(".json,application/json"),
+					html.Required(),
+				),
+				Select("bundle-duplicates", "Recipes and lists with names you already have", "duplicates", data.BundleDuplicatesSkip, []string{
+					data.BundleDuplicatesSkip,
+					data.BundleDuplicatesRename,
+				}, nil),
+				html.Button(
+					html.Type("submit"),
+					html.Class("btn btn-primary mt-2"),
+					gomponents.Text("Import"),
+				),
+			),
+			html.Div(html.ID("bundle-import-result"), html.Class("mt-2")),
+		),
+	)
+}
+
+func BundleExportSelection(recipes []data.Recipe, lists []data.List) gomponents.Node {
+	var recipeChecks, listChecks gomponents.Group
+	for _, recipe := range recipes {
+		id := "bundle-recipe-" + recipe.ListID.String()
+		recipeChecks = append(recipeChecks, FormCheck(id, recipe.Name, false, html.Input(
+			html.ID(id),
+			html.Class("form-check-input"),
+			html.Type("checkbox"),
+			html.Name("recipeID"),
+			html.Value(recipe.ListID.String()),
+		)))
+	}
+	for _, list := range lists {
+		id := "bundle-list-" + list.ID.String()
+		listChecks = append(listChecks, FormCheck(id, list.Name, false, html.Input(
+			html.ID(id),
+			html.Class("form-check-input"),
+			html.Type("checkbox"),
+			html.Name("listID"),
+			html.Value(list.ID.String()),
+		)))
+	}
+
+	return gomponents.Group{
+		html.H6(gomponents.Text("Recipes")),
+		gomponents.If(len(recipes) == 0, html.P(gomponents.Text("No recipes"))),
+		recipeChecks,
+		html.H6(html.Class("mt-2"), gomponents.Text("Lists")),
+		gomponents.If(len(lists) == 0, html.P(gomponents.Text("No lists"))),
+		listChecks,
+	}
+}
+
+func BundleImportResult(result data.BundleImportResult) gomponents.Node {
+	var renamed gomponents.Group
+	for _, rename := range result.Renamed {
+		renamed = append(renamed, html.Li(gomponents.Textf("%s was imported as %s", rename.From, rename.To)))
+	}
+	var skipped gomponents.Group
+	for _, name := range result.Skipped {
+		skipped = append(skipped, html.Li(gomponents.Textf("%s was skipped since you already have it", name)))
+	}
+
+	return html.Div(
+		html.Class("alert alert-success text-start"),
+		gomponents.Textf("Imported %d recipes and %d lists.", len(result.Recipes), len(result.Lists)),
+		gomponents.If(len(renamed) > 0 || len(skipped) > 0, html.Ul(html.Class("mb-0"), renamed, skipped)),
+	)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"maps"
 	"math"
+	"net/url"
 	"slices"
 
 	"github.com/densestvoid/krogerrecipeshopper/data"
@@ -67,7 +68,7 @@ func CartQuickAddModalContent() gomponents.Node {
 		"Quick add product",
 		ModalForm(
 			htmx.Post("/cart/quickadd"),
-			ProductsSearch(),
+			ProductsSearch(""),
 			FormInput("ingredient-quantity", "Ingredient quantity", nil,
 				html.Input(
 					html.Class("form-control"),
@@ -124,6 +125,15 @@ type CartProduct struct {
 	Staple      bool
 	ProductURL  string
 	Location    string
+	// The products of the ingredient the product was picked from, in order of preference
+	Alternatives []CartAlternative
+}
+
+// CartAlternative is one of the products a cart product was picked from, with its price and stock at the account's store
+type CartAlternative struct {
+	Product
+	Price      float64
+	OutOfStock bool
 }
 
 func CartTable(cartProducts []CartProduct) gomponents.Node {
@@ -192,6 +202,7 @@ func CartProductRow(cartProduct CartProduct) gomponents.Node {
 					gomponents.Text(cartProduct.Description),
 				),
 				html.Span(gomponents.Text(cartProduct.Size)),
+				CartAlternatives(cartProduct),
 			),
 		),
 		html.Td(
@@ -213,6 +224,15 @@ func CartProductRow(cartProduct CartProduct) gomponents.Node {
 				),
 				html.Ul(
 					html.Class("dropdown-menu"),
+					html.Li(
+						html.Class("dropdown-item"),
+						html.A(
+							html.Class("btn btn-secondary w-100"),
+							html.Href(fmt.Sprintf("/recipes/products?productID=%s", url.QueryEscape(cartProduct.ProductID))),
+							gomponents.Text("Recipes"),
+						),
+					),
+					html.Li(html.Hr(html.Class("dropdown-divider"))),
 					html.Li(
 						html.Class("dropdown-item"),
 						html.Button(
@@ -305,6 +325,7 @@ func ShoppingListRow(cartProduct CartProduct) gomponents.Node {
 					gomponents.Text(cartProduct.Description),
 				),
 				html.Span(gomponents.Text(cartProduct.Size)),
+				CartAlternatives(cartProduct),
 			),
 		),
 		html.Td(
@@ -326,3 +347,26 @@ func ShoppingListRow(cartProduct CartProduct) gomponents.Node {
 		),
 	)
 }
+
+// CartAlternatives lists the products the cart product was picked from, marking the picked one
+func CartAlternatives(cartProduct CartProduct) gomponents.Node {
+	if len(cartProduct.Alternatives) == 0 {
+		return nil
+	}
+
+	var items gomponents.Group
+	for _, alternative := range cartProduct.Alternatives {
+		items = append(items, html.Li(
+			gomponents.If(alternative.ProductID == cartProduct.ProductID, html.Class("fw-bold")),
+			gomponents.Text(alternative.Name()),
+			gomponents.If(alternative.Price > 0, html.Span(html.Class("ms-1"), gomponents.Textf("$%.2f", alternative.Price))),
+			gomponents.If(alternative.OutOfStock, html.Span(html.Class("badge text-bg-secondary ms-1"), gomponents.Text("Out of stock"))),
+			gomponents.If(alternative.ProductID == cartProduct.ProductID, html.Span(html.Class("badge text-bg-success ms-1"), gomponents.Text("Picked"))),
+		))
+	}
+	return html.Details(
+		html.Class("small text-start mt-1"),
+		html.Summary(gomponents.Textf("Picked from %d products", len(cartProduct.Alternatives))),
+		html.Ol(html.Class("mb-0"), items),
+	)
+}
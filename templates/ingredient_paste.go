@@ -0,0 +1,192 @@
+package templates
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"maragu.dev/gomponents"
+	htmx "maragu.dev/gomponents-htmx"
+	"maragu.dev/gomponents/html"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/parser"
+)
+
+// IngredientPaste parses pasted ingredient lines, each of which can then be searched for a product to add
+func IngredientPaste(listID uuid.UUID) gomponents.Node {
+	return gomponents.Group{
+		html.A(
+			html.Class("btn btn-secondary m-1"),
+			html.Role("button"),
+			html.Data("bs-toggle", "collapse"),
+			html.Href("#ingredient-paste"),
+			gomponents.Text("Paste ingredients"),
+		),
+		html.Div(
+			html.ID("ingredient-paste"),
+			html.Class("collapse mt-2"),
+			html.Form(
+				htmx.Post(fmt.Sprintf("/lists/%s/ingredients/paste", listID)),
+				htmx.Target("#ingredient-paste-results"),
+				FormInput("ingredient-paste-lines", "One ingredient per line, like 1 1/2 cups diced onion", nil, html.Textarea(
+					html.ID("ingredient-paste-lines"),
+					html.Class("form-control"),
+					html.Style("height: 10rem"),
+					html.Name("lines"),
+					html.Required(),
+				)),
+				html.Button(
+					html.Type("submit"),
+					html.Class("btn btn-primary mt-2"),
+					gomponents.Text("Read ingredients"),
+				),
+			),
+			html.Div(html.ID("ingredient-paste-results"), html.Class("mt-2")),
+		),
+	}
+}
+
+func IngredientPasteTable(listID uuid.UUID, ingredients []parser.Ingredient) gomponents.Node {
+	var rows gomponents.Group
+	for _, ingredient := range ingredients {
+		rows = append(rows, html.Tr(
+			html.Td(gomponents.Text(ingredient.Text)),
+			html.Td(gomponents.Text(parsedIngredientAmount(ingredient))),
+			html.Td(gomponents.Text(ingredient.Name)),
+			html.Td(gomponents.Text(ingredient.Notes)),
+			html.Td(
+				html.Div(
+					html.Class("btn-group"),
+					ModalButton(
+						"btn-primary",
+						"Add",
+						htmx.Get(fmt.Sprintf("/lists/%s/ingredients/paste/match?%s", listID, url.Values{"line": {ingredient.Text}}.Encode())),
+					),
+					html.Button(
+						html.Type("button"),
+						html.Class("btn btn-secondary"),
+						gomponents.Attr("x-on:click", "$el.closest('tr').remove()"),
+						gomponents.Text("Done"),
+					),
+				),
+			),
+		))
+	}
+	return html.Table(
+		gomponents.Attr("x-data"),
+		html.Class("table table-striped table-bordered text-center align-middle w-100"),
+		html.THead(
+			html.Tr(
+				html.Th(gomponents.Text("Line")),
+				html.Th(gomponents.Text("Amount")),
+				html.Th(gomponents.Text("Item")),
+				html.Th(gomponents.Text("Notes")),
+				html.Th(gomponents.Text("Actions")),
+			),
+		),
+		html.TBody(
+			html.Class("table-group-divider"),
+			rows,
+		),
+	)
+}
+
+// IngredientPasteMatchModalContent searches products for a pasted line's item, and adds the selected one to the list
+func IngredientPasteMatchModalContent(listID uuid.UUID, ingredient parser.Ingredient, sections []string) gomponents.Node {
+	return ModalContent(
+		"Add ingredient",
+		ModalForm(
+			htmx.Post(fmt.Sprintf("/lists/%s/ingredients", listID)),
+			html.P(html.Class("lead"), gomponents.Text(ingredient.Text)),
+			ParsedIngredientSummary(ingredient),
+			ProductsSearch(parsedIngredientSearch(ingredient)),
+			IngredientQuantityInput(parsedIngredientAmountInput(ingredient)),
+			IngredientDetailsInputs(parsedIngredientDetails(ingredient), sections),
+		),
+		gomponents.Group{
+			ModalDismiss(),
+			ModalSubmit(),
+		},
+	)
+}
+
+// ParsedIngredientSummary lists what was read from an ingredient line
+func ParsedIngredientSummary(ingredient parser.Ingredient) gomponents.Node {
+	var items gomponents.Group
+	if amount := parsedIngredientAmount(ingredient); amount != "" {
+		items = append(items, html.Li(html.Class("list-inline-item"), html.Strong(gomponents.Text("Amount: ")), gomponents.Text(amount)))
+	}
+	if ingredient.Name != "" {
+		items = append(items, html.Li(html.Class("list-inline-item"), html.Strong(gomponents.Text("Item: ")), gomponents.Text(ingredient.Name)))
+	}
+	if ingredient.Notes != "" {
+		items = append(items, html.Li(html.Class("list-inline-item"), html.Strong(gomponents.Text("Notes: ")), gomponents.Text(ingredient.Notes)))
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return html.Ul(html.Class("list-inline text-body-secondary"), items)
+}
+
+// parsedIngredientAmount is the quantity, range, package size and unit of the line, like "2 (14 oz) can"
+func parsedIngredientAmount(ingredient parser.Ingredient) string {
+	amount := ""
+	if ingredient.Quantity != 0 {
+		amount = formatAmount(ingredient.Quantity)
+		if ingredient.MaxQuantity != 0 {
+			amount += "-" + formatAmount(ingredient.MaxQuantity)
+		}
+	}
+	if ingredient.Size != "" {
+		amount += fmt.Sprintf(" (%s)", ingredient.Size)
+	}
+	if ingredient.Unit != "" {
+		amount += " " + ingredient.Unit
+	}
+	return strings.TrimSpace(amount)
+}
+
+// parsedIngredientAmountInput starts the quantity input at the line's amount, when its unit can be converted to a package size.
+// Ranges use their upper end, so there is enough of the product.
+// Lines with a package size, like "2 (14 oz) cans", use the total size, since the product bought may be a different size.
+func parsedIngredientAmountInput(ingredient parser.Ingredient) data.Ingredient {
+	amount, unit := ingredient.Quantity, ingredient.Unit
+	if ingredient.MaxQuantity != 0 {
+		amount = ingredient.MaxQuantity
+	}
+	if size := parser.ParseSize(ingredient.Size); len(size) > 0 {
+		// The last measure is the total of a size like "6 pk / 12 fl oz"
+		total := size[len(size)-1]
+		amount, unit = amount*total.Amount, total.Unit
+	}
+	if unit == "" {
+		unit = parser.UnitEach
+	}
+	if amount == 0 || !parser.ConvertibleUnit(unit) {
+		return data.Ingredient{}
+	}
+	return data.Ingredient{Amount: amount, Unit: unit}
+}
+
+// parsedIngredientDetails starts the note at the line's notes, cut to the longest note an ingredient can have
+func parsedIngredientDetails(ingredient parser.Ingredient) data.Ingredient {
+	note := []rune(ingredient.Notes)
+	return data.Ingredient{Note: string(note[:min(len(note), data.IngredientNoteMaxLength)])}
+}
+
+// parsedIngredientSearch is the product search for the line, its item name if one was read
+func parsedIngredientSearch(ingredient parser.Ingredient) string {
+	if ingredient.Name != "" {
+		return ingredient.Name
+	}
+	return ingredient.Text
+}
+
+// formatAmount rounds to two decimal places, so thirds don't print as 0.3333333333333333
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(math.Round(amount*100)/100, 'f', -1, 64)
+}
@@ -2,6 +2,7 @@ package templates
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 	"maragu.dev/gomponents"
@@ -9,6 +10,7 @@ import (
 	"maragu.dev/gomponents/html"
 
 	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/parser"
 )
 
 func Ingredients(accountID uuid.UUID, list data.List) gomponents.Node {
@@ -23,16 +25,19 @@ func Ingredients(accountID uuid.UUID, list data.List) gomponents.Node {
 				htmx.Swap("innerHTML"),
 				htmx.Trigger("load,ingredient-update from:body"),
 			),
-			gomponents.If(accountID == list.AccountID, ModalButton(
-				"btn-primary",
-				"Add ingredient",
-				htmx.Get(fmt.Sprintf("/lists/%v/ingredients//details", list.ID)),
-			)),
+			gomponents.If(accountID == list.AccountID, gomponents.Group{
+				ModalButton(
+					"btn-primary",
+					"Add ingredient",
+					htmx.Get(fmt.Sprintf("/lists/%v/ingredients//details", list.ID)),
+				),
+				IngredientPaste(list.ID),
+			}),
 		),
 	})
 }
 
-func IngredientDetailsModalContent(listID uuid.UUID, ingredient data.Ingredient) gomponents.Node {
+func IngredientDetailsModalContent(listID uuid.UUID, ingredient data.Ingredient, sections []string) gomponents.Node {
 	ifExists := func(node gomponents.Node) gomponents.Node {
 		return gomponents.If(ingredient.ProductID != "", node)
 	}
@@ -50,37 +55,9 @@ func IngredientDetailsModalContent(listID uuid.UUID, ingredient data.Ingredient)
 				html.Name("productID"),
 				html.Value(ingredient.ProductID),
 			)),
-			ifNotExists(ProductsSearch()),
-			html.Div(
-				gomponents.Attr("x-data", fmt.Sprintf("{staple : %t}", ingredient.Staple)),
-				html.Class("input-group"),
-				html.Div(
-					html.Class("input-group-text"),
-					FormCheck("ingredient-staple", "Staple", true, html.Input(
-						gomponents.Attr("x-on:change", "staple = !staple"),
-						html.ID("ingredient-staple"),
-						html.Class("form-check-input"),
-						html.Name("staple"),
-						html.Role("switch"),
-						html.Type("checkbox"),
-						html.Value("true"),
-						Checked(ingredient.Staple),
-					)),
-				),
-				FormInput("ingredient-quantity", "Ingredient quantity", nil,
-					html.Input(
-						gomponents.Attr("x-bind:value", fmt.Sprintf("staple ? '1' : '%s'", ingredient.QuantityDecimalString())),
-						gomponents.Attr("x-bind:disabled", "staple"),
-						html.Class("form-control"),
-						html.Type("number"),
-						html.Name("quantity"),
-						html.Min("0.01"),
-						html.Step("0.01"),
-						html.Required(),
-						ifExists(html.Value(ingredient.QuantityDecimalString())),
-					),
-				),
-			),
+			ifNotExists(ProductsSearch("")),
+			IngredientQuantityInput(ingredient),
+			IngredientDetailsInputs(ingredient, sections),
 		),
 		gomponents.Group{
 			ModalDismiss(),
@@ -89,6 +66,117 @@ func IngredientDetailsModalContent(listID uuid.UUID, ingredient data.Ingredient)
 	)
 }
 
+// IngredientQuantityInput is the staple switch and the quantity, which staples don't need.
+// The quantity is a fraction of the product's package, unless a unit is picked to enter a recipe amount instead.
+func IngredientQuantityInput(ingredient data.Ingredient) gomponents.Node {
+	quantity := ingredient.QuantityDecimalString()
+	if ingredient.Unit != "" {
+		quantity = formatAmount(ingredient.Amount)
+	}
+
+	unitOptions := gomponents.Group{
+		html.Option(html.Value(""), gomponents.Text("of the package"), gomponents.If(ingredient.Unit == "", html.Selected())),
+	}
+	for _, unit := range parser.Units {
+		unitOptions = append(unitOptions, html.Option(html.Value(unit), gomponents.Text(unit), gomponents.If(ingredient.Unit == unit, html.Selected())))
+	}
+
+	return html.Div(
+		gomponents.Attr("x-data", fmt.Sprintf("{staple : %t}", ingredient.Staple)),
+		html.Class("input-group"),
+		html.Div(
+			html.Class("input-group-text"),
+			FormCheck("ingredient-staple", "Staple", true, html.Input(
+				gomponents.Attr("x-on:change", "staple = !staple"),
+				html.ID("ingredient-staple"),
+				html.Class("form-check-input"),
+				html.Name("staple"),
+				html.Role("switch"),
+				html.Type("checkbox"),
+				html.Value("true"),
+				Checked(ingredient.Staple),
+			)),
+		),
+		FormInput("ingredient-quantity", "Ingredient quantity", nil,
+			html.Input(
+				gomponents.Attr("x-bind:value", fmt.Sprintf("staple ? '1' : '%s'", quantity)),
+				gomponents.Attr("x-bind:disabled", "staple"),
+				html.ID("ingredient-quantity"),
+				html.Class("form-control"),
+				html.Type("number"),
+				html.Name("quantity"),
+				html.Min("0.01"),
+				html.Step("0.01"),
+				html.Required(),
+				gomponents.If(quantity != "", html.Value(quantity)),
+			),
+		),
+		FormInput("ingredient-unit", "Unit", nil,
+			html.Select(
+				gomponents.Attr("x-bind:disabled", "staple"),
+				html.ID("ingredient-unit"),
+				html.Class("form-select"),
+				html.Name("unit"),
+				unitOptions,
+			),
+		),
+	)
+}
+
+// IngredientDetailsInputs are the display name, section and note of an ingredient.
+// The section suggests the list's existing sections.
+func IngredientDetailsInputs(ingredient data.Ingredient, sections []string) gomponents.Node {
+	var sectionOptions gomponents.Group
+	for _, section := range sections {
+		sectionOptions = append(sectionOptions, html.Option(html.Value(section)))
+	}
+
+	return html.Div(
+		html.Class("d-flex flex-column gap-2 mt-2"),
+		FormInput("ingredient-display-name", "Display name, in place of the product description", nil,
+			html.Input(
+				html.ID("ingredient-display-name"),
+				html.Class("form-control"),
+				html.Type("text"),
+				html.Name("displayName"),
+				html.MaxLength(fmt.Sprintf("%d", data.IngredientDisplayNameMaxLength)),
+				html.Value(ingredient.DisplayName),
+			),
+		),
+		FormInput("ingredient-section", "Section, like Sauce or Topping", nil,
+			html.Input(
+				html.ID("ingredient-section"),
+				html.Class("form-control"),
+				html.Type("text"),
+				html.Name("section"),
+				html.MaxLength(fmt.Sprintf("%d", data.IngredientSectionMaxLength)),
+				html.List("ingredient-section-options"),
+				html.AutoComplete("off"),
+				html.Value(ingredient.Section),
+			),
+		),
+		html.DataList(html.ID("ingredient-section-options"), sectionOptions),
+		FormInput("ingredient-note", "Note, like finely chopped", nil,
+			html.Input(
+				html.ID("ingredient-note"),
+				html.Class("form-control"),
+				html.Type("text"),
+				html.Name("note"),
+				html.MaxLength(fmt.Sprintf("%d", data.IngredientNoteMaxLength)),
+				html.Value(ingredient.Note),
+			),
+		),
+	)
+}
+
+// ingredientQuantityText is the quantity as a fraction of the product's package, after the recipe amount it was computed from
+func ingredientQuantityText(quantity int, amount float64, unit string) string {
+	if unit == "" {
+		return fmt.Sprintf("%.2f", float64(quantity)/100)
+	}
+	return fmt.Sprintf("%s %s (%.2f)", formatAmount(amount), unit, float64(quantity)/100)
+}
+
 func Checked(b bool) gomponents.Node {
 	if b {
 		return html.Checked()
@@ -96,7 +184,18 @@ func Checked(b bool) gomponents.Node {
 	return nil
 }
 
-func ProductsSearch() gomponents.Node {
+// ProductsSearch starts with the search results for the search text, if there is any
+func ProductsSearch(search string) gomponents.Node {
+	return productsSearch(search, true)
+}
+
+// productsSearch can leave selecting a product optional, for forms that do more than add the selected product
+func productsSearch(search string, required bool) gomponents.Node {
+	trigger := "input changed delay:1s, keyup[key=='Enter']"
+	if search != "" {
+		trigger = "load, " + trigger
+	}
+
 	return html.Div(
 		html.H3(gomponents.Text("Search products")),
 		html.Div(
@@ -106,9 +205,10 @@ func ProductsSearch() gomponents.Node {
 					html.Type("search"),
 					html.Name("search"),
 					html.Placeholder("Begin typing to seach products"),
+					gomponents.If(search != "", html.Value(search)),
 					htmx.Post("/products/search"),
 					htmx.Swap("innerHTML"),
-					htmx.Trigger("input changed delay:1s, keyup[key=='Enter']"),
+					htmx.Trigger(trigger),
 					htmx.Target("#products-search-table"),
 					htmx.Indicator(".htmx-indicator"),
 				),
@@ -119,7 +219,7 @@ func ProductsSearch() gomponents.Node {
 			html.Class("d-none"),
 			html.Type("radio"),
 			html.Name("productID"),
-			html.Required(),
+			gomponents.If(required, html.Required()),
 		),
 		html.Div(html.ID("products-search-table")),
 	)
@@ -138,6 +238,7 @@ func ProductsSearchTable(products []Product) gomponents.Node {
 				html.Th(gomponents.Text("Brand")),
 				html.Th(gomponents.Text("Description")),
 				html.Th(gomponents.Text("Size")),
+				html.Th(gomponents.Text("Recipes")),
 			),
 		),
 		html.TBody(productRows),
@@ -166,6 +267,7 @@ func ProductSearchRow(product Product) gomponents.Node {
 			gomponents.Text(product.Description),
 		)),
 		html.Td(gomponents.Text(product.Size)),
+		html.Td(ProductRecipesLink(product.ProductID)),
 	)
 }
 
@@ -178,52 +280,125 @@ type Product struct {
 	ProductURL  string
 }
 
+// Name is the product's brand, description and size, or its ID when it wasn't found
+func (p Product) Name() string {
+	if p.Description == "" {
+		return p.ProductID
+	}
+	return fmt.Sprintf("%s %s %s", p.Brand, p.Description, p.Size)
+}
+
 type Ingredient struct {
 	Product
-	ListID   uuid.UUID
-	Quantity int
-	Staple   bool
+	ListID      uuid.UUID
+	Quantity    int
+	Staple      bool
+	Amount      float64
+	Unit        string
+	Note        string
+	DisplayName string
+	Section     string
+	// Products that can be bought in place of the ingredient's product, in order of preference
+	Alternatives []Product
 }
 
-func IngredientsTable(accountID, listAccountID uuid.UUID, ingredients []Ingredient) gomponents.Node {
-	var ingredientRows, stapleRows gomponents.Group
+// IngredientsTable groups the ingredients by section, unsectioned ingredients first and staples last.
+// The owner can drag ingredients within their section to reorder them.
+func IngredientsTable(accountID, listAccountID, listID uuid.UUID, ingredients []Ingredient) gomponents.Node {
+	owner := accountID == listAccountID
+
+	sections := []string{""}
+	sectionRows := map[string]gomponents.Group{}
+	var stapleRows gomponents.Group
 	for _, ingredient := range ingredients {
+		row := IngredientRow(accountID, listAccountID, ingredient)
 		if ingredient.Staple {
-			stapleRows = append(stapleRows, IngredientRow(accountID, listAccountID, ingredient))
-		} else {
-			ingredientRows = append(ingredientRows, IngredientRow(accountID, listAccountID, ingredient))
+			stapleRows = append(stapleRows, row)
+			continue
+		}
+		if _, ok := sectionRows[ingredient.Section]; !ok && ingredient.Section != "" {
+			sections = append(sections, ingredient.Section)
 		}
+		sectionRows[ingredient.Section] = append(sectionRows[ingredient.Section], row)
 	}
-	return html.Table(
+
+	var bodies gomponents.Group
+	for _, section := range sections {
+		label := section
+		if section == "" {
+			// Without sections the unsectioned ingredients are all of them
+			if len(sections) > 1 && len(sectionRows[section]) == 0 {
+				continue
+			}
+			label = "Ingredients"
+		}
+		bodies = append(bodies, html.TBody(
+			html.Class("table-group-divider"),
+			html.Tr(html.Td(html.ColSpan("6"), gomponents.Text(label))),
+			sectionRows[section],
+		))
+	}
+	bodies = append(bodies, html.TBody(
+		html.Class("table-group-divider"),
+		html.Tr(html.Td(html.ColSpan("6"), gomponents.Text("Staples"))),
+		stapleRows,
+	))
+
+	table := html.Table(
 		html.Class("table table-striped table-bordered text-center align-middle w-100"),
 		html.THead(
 			html.Tr(
 				html.Th(gomponents.Text("Product")),
 				html.Th(gomponents.Text("Quantity")),
-				gomponents.If(accountID == listAccountID, html.Th(gomponents.Text("Actions"))),
+				gomponents.If(owner, html.Th(gomponents.Text("Actions"))),
 			),
 		),
-		html.TBody(
-			html.Class("table-group-divider"),
-			html.Tr(html.Td(html.ColSpan("6"), gomponents.Text("Ingredients"))),
-			ingredientRows,
-		),
-		html.TBody(
-			html.Class("table-group-divider"),
-			html.Tr(html.Td(html.ColSpan("6"), gomponents.Text("Staples"))),
-			stapleRows,
-		),
+		bodies,
+	)
+	if !owner {
+		return table
+	}
+
+	// Rows are moved while dragging, and the new order of the product IDs is saved when the drag ends
+	return html.Form(
+		gomponents.Attr("x-data", "{ dragged: null, moved: false }"),
+		htmx.Post(fmt.Sprintf("/lists/%v/ingredients/order", listID)),
+		htmx.Trigger("reorder"),
+		htmx.Swap("none"),
+		table,
 	)
 }
 
 func IngredientRow(accountID, recipeAccountID uuid.UUID, ingredient Ingredient) gomponents.Node {
+	owner := accountID == recipeAccountID
 	return html.Tr(
+		gomponents.If(owner, gomponents.Group{
+			html.Draggable("true"),
+			html.Style("cursor: grab"),
+			gomponents.Attr("x-on:dragstart", "dragged = $el; $event.dataTransfer.effectAllowed = 'move'"),
+			gomponents.Attr("x-on:dragover", `
+				if (dragged && dragged !== $el && dragged.parentNode === $el.parentNode) {
+					$event.preventDefault();
+					const rect = $el.getBoundingClientRect();
+					$el.parentNode.insertBefore(dragged, $event.clientY > rect.top + rect.height / 2 ? $el.nextSibling : $el);
+					moved = true;
+				}
+			`),
+			gomponents.Attr("x-on:drop.prevent", ""),
+			gomponents.Attr("x-on:dragend", "if (moved) $dispatch('reorder'); dragged = null; moved = false"),
+		}),
 		html.Td(
 			html.Class("d-flex flex-column align-items-center"),
+			gomponents.If(owner, html.Input(
+				html.Type("hidden"),
+				html.Name("productID"),
+				html.Value(ingredient.ProductID),
+			)),
 			html.Img(
 				html.Class("row img-fluid img-thumbnail"),
 				html.Src(ingredient.ImageURL),
 			),
+			gomponents.If(ingredient.DisplayName != "", html.Strong(gomponents.Text(ingredient.DisplayName))),
 			html.Span(gomponents.Text(ingredient.Brand)),
 			html.A(
 				html.Href(ingredient.ProductURL),
@@ -231,15 +406,19 @@ func IngredientRow(accountID, recipeAccountID uuid.UUID, ingredient Ingredient)
 				gomponents.Text(ingredient.Description),
 			),
 			html.Span(gomponents.Text(ingredient.Size)),
+			IngredientAlternativesText(ingredient.Alternatives),
+		),
+		html.Td(
+			html.Div(gomponents.Text(ingredientQuantityText(ingredient.Quantity, ingredient.Amount, ingredient.Unit))),
+			gomponents.If(ingredient.Note != "", html.Div(html.Class("small text-body-secondary"), gomponents.Text(ingredient.Note))),
 		),
-		html.Td(gomponents.Textf("%.2f", float64(ingredient.Quantity)/100)),
-		gomponents.If(accountID == recipeAccountID, html.Td(
+		gomponents.If(owner, html.Td(
 			html.Div(
 				html.Class("btn-group dropdown-center"),
 				ModalButton(
 					"btn-primary",
 					"Edit details",
-					htmx.Get(fmt.Sprintf("/recipes/%v/ingredients/%s/details", ingredient.ListID, ingredient.ProductID)),
+					htmx.Get(fmt.Sprintf("/lists/%v/ingredients/%s/details", ingredient.ListID, ingredient.ProductID)),
 				),
 				html.Button(
 					html.Type("button"),
@@ -248,13 +427,22 @@ func IngredientRow(accountID, recipeAccountID uuid.UUID, ingredient Ingredient)
 				),
 				html.Ul(
 					html.Class("dropdown-menu"),
+					html.Li(
+						html.Class("dropdown-item"),
+						ModalButton(
+							"btn-secondary w-100",
+							"Alternatives",
+							htmx.Get(fmt.Sprintf("/lists/%v/ingredients/%s/alternatives", ingredient.ListID, ingredient.ProductID)),
+						),
+					),
+					html.Li(html.Hr(html.Class("dropdown-divider"))),
 					html.Li(
 						html.Class("dropdown-item"),
 						html.Button(
 							html.Type("button"),
 							html.Class("btn btn-danger w-100"),
 							gomponents.Text("Delete"),
-							htmx.Delete(fmt.Sprintf("/recipes/%v/ingredients/%s", ingredient.ListID, ingredient.ProductID)),
+							htmx.Delete(fmt.Sprintf("/lists/%v/ingredients/%s", ingredient.ListID, ingredient.ProductID)),
 							htmx.Swap("none"),
 							htmx.Confirm("Are you sure you want to remove this ingredient from the recipe?"),
 						),
@@ -264,3 +452,61 @@ func IngredientRow(accountID, recipeAccountID uuid.UUID, ingredient Ingredient)
 		)),
 	)
 }
+
+// IngredientAlternativesText names the products that can be bought in place of the ingredient's product
+func IngredientAlternativesText(alternatives []Product) gomponents.Node {
+	if len(alternatives) == 0 {
+		return nil
+	}
+	names := []string{}
+	for _, alternative := range alternatives {
+		names = append(names, alternative.Name())
+	}
+	return html.Span(html.Class("small text-body-secondary"), gomponents.Textf("or %s", strings.Join(names, ", ")))
+}
+
+// IngredientAlternativesModalContent orders the products that can be bought in place of the ingredient's product, and searches for one to add.
+// When the ingredient is added to the cart, the first in stock or the cheapest of them all is bought, depending on the account's preference.
+func IngredientAlternativesModalContent(listID uuid.UUID, productID string, alternatives []Product) gomponents.Node {
+	alternativeButton := func(icon, label, onClick string) gomponents.Node {
+		return html.Button(
+			html.Type("button"),
+			html.Class("btn btn-outline-secondary btn-sm"),
+			html.Title(label),
+			gomponents.Attr("x-on:click", onClick),
+			html.I(html.Class("bi "+icon)),
+		)
+	}
+
+	var items gomponents.Group
+	for _, alternative := range alternatives {
+		items = append(items, html.Li(
+			html.Class("list-group-item d-flex align-items-center gap-2"),
+			html.Input(html.Type("hidden"), html.Name("alternative"), html.Value(alternative.ProductID)),
+			html.Span(html.Class("me-auto text-start"), gomponents.Text(alternative.Name())),
+			alternativeButton("bi-arrow-up", "Move up", "$el.closest('li').previousElementSibling?.before($el.closest('li'))"),
+			alternativeButton("bi-arrow-down", "Move down", "$el.closest('li').nextElementSibling?.after($el.closest('li'))"),
+			alternativeButton("bi-x-lg", "Remove", "$el.closest('li').remove()"),
+		))
+	}
+
+	return ModalContent(
+		"Ingredient alternatives",
+		ModalForm(
+			htmx.Post(fmt.Sprintf("/lists/%v/ingredients/%s/alternatives", listID, productID)),
+			html.P(html.Class("text-body-secondary"), gomponents.Text(
+				"Alternatives can be bought in place of the ingredient's product, in this order. Your account settings pick the first in stock or the cheapest.",
+			)),
+			gomponents.If(len(items) > 0, html.Ol(
+				gomponents.Attr("x-data"),
+				html.Class("list-group list-group-numbered mb-3"),
+				items,
+			)),
+			productsSearch("", false),
+		),
+		gomponents.Group{
+			ModalDismiss(),
+			ModalSubmit(),
+		},
+	)
+}
@@ -0,0 +1,203 @@
+package templates
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"maragu.dev/gomponents"
+	htmx "maragu.dev/gomponents-htmx"
+	"maragu.dev/gomponents/html"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+)
+
+func ListScheduleModalContent(list data.List, schedule *data.ListSchedule, runs []data.ListScheduleRun, canPush bool) gomponents.Node {
+	footer := gomponents.Group{ModalDismiss()}
+	if schedule != nil {
+		footer = append(footer,
+			html.Button(
+				html.Type("button"),
+				html.Class("btn btn-danger"),
+				gomponents.Text("Remove schedule"),
+				htmx.Delete(fmt.Sprintf("/lists/%s/schedule", list.ID)),
+				htmx.Target("#modal-content"),
+				htmx.Confirm("Are you sure you want to remove this schedule?"),
+			),
+		)
+	}
+	footer = append(footer, ModalSubmit())
+
+	return ModalContent(
+		fmt.Sprintf("Schedule %s", list.Name),
+		gomponents.Group{
+			gomponents.If(schedule != nil, ListScheduleStatus(list, schedule)),
+			ListScheduleForm(list, schedule, canPush),
+			html.H6(html.Class("mt-3"), gomponents.Text("Run history")),
+			ListScheduleRunsTable(runs),
+		},
+		footer,
+	)
+}
+
+func ListScheduleStatus(list data.List, schedule *data.ListSchedule) gomponents.Node {
+	nextRun := schedule.NextRunAt.Format("Monday, January 2")
+	if schedule.Paused {
+		nextRun = "Paused"
+	} else if schedule.SkipNext {
+		nextRun += " (skipped)"
+	}
+
+	return html.Div(
+		html.Class("d-flex align-items-center justify-content-between mb-2"),
+		html.Span(gomponents.Textf("Next run: %s", nextRun)),
+		html.Div(
+			html.Class("btn-group"),
+			gomponents.If(!schedule.Paused, html.Button(
+				html.Type("button"),
+				html.Class("btn btn-secondary"),
+				gomponents.If(schedule.SkipNext, gomponents.Text("Don't skip next")),
+				gomponents.If(!schedule.SkipNext, gomponents.Text("Skip next")),
+				htmx.Post(fmt.Sprintf("/lists/%s/schedule/skip-next", list.ID)),
+				htmx.Vals(fmt.Sprintf(`{"skip": %t}`, !schedule.SkipNext)),
+				htmx.Target("#modal-content"),
+			)),
+			html.Button(
+				html.Type("button"),
+				html.Class("btn btn-warning"),
+				gomponents.If(schedule.Paused, gomponents.Text("Resume")),
+				gomponents.If(!schedule.Paused, gomponents.Text("Pause")),
+				htmx.Post(fmt.Sprintf("/lists/%s/schedule/pause", list.ID)),
+				htmx.Vals(fmt.Sprintf(`{"paused": %t}`, !schedule.Paused)),
+				htmx.Target("#modal-content"),
+			),
+		),
+	)
+}
+
+func ListScheduleForm(list data.List, schedule *data.ListSchedule, canPush bool) gomponents.Node {
+	if schedule == nil {
+		schedule = &data.ListSchedule{Frequency: data.ListScheduleFrequencyWeekly}
+	}
+	valueOr := func(value *int, fallback int) string {
+		if value == nil {
+			return strconv.Itoa(fallback)
+		}
+		return strconv.Itoa(*value)
+	}
+
+	var weekdayOptions gomponents.Group
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		weekdayOptions = append(weekdayOptions, html.Option(
+			gomponents.If(schedule.Weekday != nil && *schedule.Weekday == int(weekday), html.Selected()),
+			html.Value(strconv.Itoa(int(weekday))),
+			gomponents.Text(weekday.String()),
+		))
+	}
+
+	return ModalForm(
+		htmx.Put(fmt.Sprintf("/lists/%s/schedule", list.ID)),
+		html.Div(
+			gomponents.Attr("x-data", fmt.Sprintf("{frequency: '%s'}", schedule.Frequency)),
+			Select(
+				"list-schedule-frequency",
+				"Frequency",
+				"frequency",
+				schedule.Frequency,
+				[]string{
+					data.ListScheduleFrequencyWeekly,
+					data.ListScheduleFrequencyDays,
+					data.ListScheduleFrequencyMonthly,
+				},
+				gomponents.Attr("x-on:change", "frequency = $event.target.value"),
+			),
+			html.Div(
+				html.Class("form-floating"),
+				gomponents.Attr("x-show", fmt.Sprintf("frequency == '%s'", data.ListScheduleFrequencyWeekly)),
+				html.Select(
+					html.ID("list-schedule-weekday"),
+					html.Class("form-select"),
+					html.Name("weekday"),
+					gomponents.Attr("x-bind:disabled", fmt.Sprintf("frequency != '%s'", data.ListScheduleFrequencyWeekly)),
+					weekdayOptions,
+				),
+				html.Label(html.For("list-schedule-weekday"), gomponents.Text("Day of the week")),
+			),
+			html.Div(
+				gomponents.Attr("x-show", fmt.Sprintf("frequency == '%s'", data.ListScheduleFrequencyDays)),
+				FormInput("list-schedule-interval-days", "Every number of days", nil, html.Input(
+					html.ID("list-schedule-interval-days"),
+					html.Class("form-control"),
+					html.Type("number"),
+					html.Name("intervalDays"),
+					html.Min("1"),
+					html.Required(),
+					html.Value(valueOr(schedule.IntervalDays, 7)),
+					gomponents.Attr("x-bind:disabled", fmt.Sprintf("frequency != '%s'", data.ListScheduleFrequencyDays)),
+				)),
+			),
+			html.Div(
+				gomponents.Attr("x-show", fmt.Sprintf("frequency == '%s'", data.ListScheduleFrequencyMonthly)),
+				FormInput("list-schedule-day-of-month", "Day of the month", nil, html.Input(
+					html.ID("list-schedule-day-of-month"),
+					html.Class("form-control"),
+					html.Type("number"),
+					html.Name("dayOfMonth"),
+					html.Min("1"),
+					html.Max("28"),
+					html.Required(),
+					html.Value(valueOr(schedule.DayOfMonth, 1)),
+					gomponents.Attr("x-bind:disabled", fmt.Sprintf("frequency != '%s'", data.ListScheduleFrequencyMonthly)),
+				)),
+			),
+		),
+		FormCheck("list-schedule-push", "Send straight to the Kroger cart instead of the app cart", true, html.Input(
+			html.ID("list-schedule-push"),
+			html.Class("form-check-input"),
+			html.Name("pushToKroger"),
+			html.Role("switch"),
+			html.Type("checkbox"),
+			html.Value("true"),
+			Checked(schedule.PushToKroger),
+			Disabled(!canPush),
+		)),
+		gomponents.If(!canPush, html.Small(
+			html.Class("text-body-secondary"),
+			gomponents.Text("Sending to the Kroger cart isn't available on this server."),
+		)),
+	)
+}
+
+func ListScheduleRunsTable(runs []data.ListScheduleRun) gomponents.Node {
+	if len(runs) == 0 {
+		return html.P(gomponents.Text("No runs yet"))
+	}
+
+	var runRows gomponents.Group
+	for _, run := range runs {
+		errMsg := ""
+		if run.Error != nil {
+			errMsg = *run.Error
+		}
+		runRows = append(runRows, html.Tr(
+			html.Td(gomponents.Text(run.RanAt.Format(time.DateTime))),
+			html.Td(gomponents.Text(run.Status)),
+			html.Td(html.Class("text-break"), gomponents.Text(errMsg)),
+		))
+	}
+
+	return html.Table(
+		html.Class("table table-striped table-bordered text-center align-middle w-100"),
+		html.THead(
+			html.Tr(
+				html.Th(gomponents.Text("Ran")),
+				html.Th(gomponents.Text("Status")),
+				html.Th(gomponents.Text("Error")),
+			),
+		),
+		html.TBody(
+			html.Class("table-group-divider"),
+			runRows,
+		),
+	)
+}
@@ -148,12 +148,9 @@ func ListRow(accountID uuid.UUID, list data.List) gomponents.Node {
 		html.Li(
 			html.Class("dropdown-item"),
 			html.A(
+				html.Class("btn btn-secondary w-100"),
 				html.Href(fmt.Sprintf("/lists/%v/ingredients", list.ID)),
-				html.Button(
-					html.Type("button"),
-					html.Class("btn btn-secondary w-100"),
-					gomponents.Text("Ingredients"),
-				),
+				gomponents.Text("Ingredients"),
 			),
 		),
 		html.Li(
@@ -167,6 +164,14 @@ func ListRow(accountID uuid.UUID, list data.List) gomponents.Node {
 	}
 	if accountID == list.AccountID {
 		actions = append(actions,
+			html.Li(
+				html.Class("dropdown-item"),
+				ModalButton(
+					"btn-secondary w-100",
+					"Schedule",
+					htmx.Get(fmt.Sprintf("/lists/%s/schedule", list.ID.String())),
+				),
+			),
 			html.Li(html.Hr(html.Class("dropdown-divider"))),
 			html.Li(
 				html.Class("dropdown-item"),
@@ -0,0 +1,86 @@
+package templates
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"maragu.dev/gomponents"
+	"maragu.dev/gomponents/html"
+
+	"github.com/densestvoid/krogerrecipeshopper/app"
+)
+
+// markdown leaves out raw HTML, which goldmark omits unless rendering unsafely
+var markdown = goldmark.New(
+	goldmark.WithParserOptions(
+		parser.WithASTTransformers(util.Prioritized(markdownSanitizer{}, 100)),
+	),
+)
+
+// markdownSanitizer keeps links to http and https URLs, opening them in a new tab, and replaces other links and images with their text.
+// Headings start below the recipe name's heading.
+type markdownSanitizer struct{}
+
+func (markdownSanitizer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	unwrap := []ast.Node{}
+	replace := map[ast.Node]ast.Node{}
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := node.(type) {
+		case *ast.Heading:
+			node.Level = min(node.Level+2, 6)
+		case *ast.Link:
+			if app.WebURL(string(node.Destination)) {
+				setMarkdownLinkAttributes(node)
+			} else {
+				unwrap = append(unwrap, node)
+			}
+		case *ast.AutoLink:
+			if node.AutoLinkType == ast.AutoLinkURL && app.WebURL(string(node.URL(source))) {
+				setMarkdownLinkAttributes(node)
+			} else {
+				replace[node] = ast.NewString(node.Label(source))
+			}
+		case *ast.Image:
+			unwrap = append(unwrap, node)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, node := range unwrap {
+		parent := node.Parent()
+		for child := node.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			parent.InsertBefore(parent, node, child)
+			child = next
+		}
+		parent.RemoveChild(parent, node)
+	}
+	for node, replacement := range replace {
+		node.Parent().ReplaceChild(node.Parent(), node, replacement)
+	}
+}
+
+func setMarkdownLinkAttributes(node ast.Node) {
+	node.SetAttributeString("target", []byte("_blank"))
+	node.SetAttributeString("rel", []byte("noopener noreferrer nofollow"))
+}
+
+// Markdown renders sanitized Markdown, falling back to the plain text if it can't be rendered
+func Markdown(source string) gomponents.Node {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(source), &buf); err != nil {
+		return html.Div(html.Style("white-space: pre-wrap"), gomponents.Text(source))
+	}
+	return html.Div(
+		html.Class("markdown"),
+		gomponents.Raw(buf.String()),
+	)
+}
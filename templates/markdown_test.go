@@ -0,0 +1,76 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     string
+		contains   []string
+		notContain []string
+	}{
+		{
+			name:     "formatting",
+			source:   "Stir **well**\n\n- salt\n- pepper",
+			contains: []string{"<strong>well</strong>", "<li>salt</li>"},
+		},
+		{
+			name:     "headings start below the recipe name",
+			source:   "# Sauce\n\n#### Garnish",
+			contains: []string{"<h3>Sauce</h3>", "<h6>Garnish</h6>"},
+		},
+		{
+			name:     "web link",
+			source:   "[video](https://example.com/video)",
+			contains: []string{`<a href="https://example.com/video" target="_blank" rel="noopener noreferrer nofollow">video</a>`},
+		},
+		{
+			name:       "script link",
+			source:     "[click](javascript:alert(1))",
+			contains:   []string{"click"},
+			notContain: []string{"<a", "javascript"},
+		},
+		{
+			name:     "web autolink",
+			source:   "<https://example.com>",
+			contains: []string{`<a href="https://example.com" target="_blank" rel="noopener noreferrer nofollow">https://example.com</a>`},
+		},
+		{
+			name:       "email autolink",
+			source:     "<cook@example.com>",
+			contains:   []string{"cook@example.com"},
+			notContain: []string{"<a", "mailto"},
+		},
+		{
+			name:       "image",
+			source:     "![plated](https://example.com/plated.jpg)",
+			contains:   []string{"plated"},
+			notContain: []string{"<img", "plated.jpg"},
+		},
+		{
+			name:       "raw html",
+			source:     "<script>alert(1)</script>\n\nfine <b onclick=\"alert(1)\">bold</b>",
+			notContain: []string{"<script", "onclick"},
+		},
+	}
+	for _, test := range tests {
+		var builder strings.Builder
+		if err := Markdown(test.source).Render(&builder); err != nil {
+			t.Fatalf("%s: rendering: %v", test.name, err)
+		}
+		rendered := builder.String()
+		for _, s := range test.contains {
+			if !strings.Contains(rendered, s) {
+				t.Errorf("%s: Markdown(%q) = %q, want it to contain %q", test.name, test.source, rendered, s)
+			}
+		}
+		for _, s := range test.notContain {
+			if strings.Contains(rendered, s) {
+				t.Errorf("%s: Markdown(%q) = %q, want it not to contain %q", test.name, test.source, rendered, s)
+			}
+		}
+	}
+}
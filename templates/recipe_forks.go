@@ -0,0 +1,104 @@
+package templates
+
+import (
+	"fmt"
+
+	"maragu.dev/gomponents"
+	htmx "maragu.dev/gomponents-htmx"
+	"maragu.dev/gomponents/html"
+
+	"github.com/densestvoid/krogerrecipeshopper/app"
+	"github.com/densestvoid/krogerrecipeshopper/data"
+)
+
+// RecipeLineage shows the recipe a fork was copied from and how many times the recipe has been forked
+func RecipeLineage(recipe data.Recipe) gomponents.Node {
+	var source gomponents.Node
+	if recipe.SourceListID != nil {
+		if recipe.SourceName == nil {
+			source = html.Div(gomponents.Text("Forked from a recipe you can't view"))
+		} else {
+			owner := "unknown"
+			if recipe.SourceOwnerName != nil {
+				owner = *recipe.SourceOwnerName
+			}
+			source = html.Div(
+				gomponents.Text("Forked from "),
+				html.Strong(gomponents.Text(*recipe.SourceName)),
+				gomponents.Textf(" by %s", owner),
+			)
+		}
+	}
+
+	var forks gomponents.Node
+	switch recipe.ForkCount {
+	case 0:
+	case 1:
+		forks = html.Div(gomponents.Text("Forked 1 time"))
+	default:
+		forks = html.Div(gomponents.Textf("Forked %d times", recipe.ForkCount))
+	}
+
+	if source == nil && forks == nil {
+		return nil
+	}
+	return html.Small(
+		html.Class("d-block text-body-secondary mb-2"),
+		source,
+		forks,
+	)
+}
+
+// RecipeSourceChangedBadge marks forks whose source recipe changed since they last synced
+func RecipeSourceChangedBadge(recipe data.Recipe) gomponents.Node {
+	if !recipe.SourceChanged() {
+		return nil
+	}
+	return html.A(
+		html.Href(fmt.Sprintf("/recipes/%s/source", recipe.ListID)),
+		html.Class("badge text-bg-info text-decoration-none ms-1"),
+		gomponents.Text("Source updated"),
+	)
+}
+
+// RecipeSourceChanges is the page where a fork's owner reviews the source's changes since the fork last synced
+func RecipeSourceChanges(recipe data.Recipe, diff app.RecipeDiff, products map[string]Product) gomponents.Node {
+	sync := func(label, class string, pull bool, confirm string) gomponents.Node {
+		return html.Button(
+			html.Type("button"),
+			html.Class("btn "+class),
+			gomponents.Text(label),
+			htmx.Post(fmt.Sprintf("/recipes/%s/source/sync", recipe.ListID)),
+			gomponents.If(pull, htmx.Vals(`{"pull": "true"}`)),
+			htmx.Swap("none"),
+			htmx.Confirm(confirm),
+		)
+	}
+
+	return BasePage(fmt.Sprintf("%s source changes", recipe.Name), "/", gomponents.Group{
+		html.Div(
+			html.Class("text-center"),
+			html.H3(gomponents.Textf("Changes to %s", *recipe.SourceName)),
+			html.P(gomponents.Textf("Changes made by %s since %s was forked or last synced", sourceOwnerName(recipe), recipe.Name)),
+			html.Div(
+				html.Class("card card-body my-2"),
+				RecipeDiffView(diff, products),
+			),
+			gomponents.If(recipe.SourceChanged(), html.Div(
+				html.Class("d-flex justify-content-center gap-2"),
+				gomponents.If(len(diff.Ingredients) > 0, sync(
+					"Pull ingredient changes", "btn-primary", true,
+					"Are you sure you want to apply these ingredient changes to your recipe? The current version stays in the history.",
+				)),
+				sync("Dismiss", "btn-secondary", false, "Are you sure you want to dismiss these changes?"),
+			)),
+		),
+	})
+}
+
+func sourceOwnerName(recipe data.Recipe) string {
+	if recipe.SourceOwnerName == nil {
+		return "the owner"
+	}
+	return *recipe.SourceOwnerName
+}
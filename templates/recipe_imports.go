@@ -0,0 +1,152 @@
+package templates
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"maragu.dev/gomponents"
+	htmx "maragu.dev/gomponents-htmx"
+	"maragu.dev/gomponents/html"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/densestvoid/krogerrecipeshopper/parser"
+)
+
+func RecipeImportModalContent() gomponents.Node {
+	return ModalContent(
+		"Import recipe",
+		ModalForm(
+			htmx.Post("/recipes/import"),
+			htmx.Encoding("multipart/form-data"),
+			html.P(
+				html.Class("text-body-secondary"),
+				gomponents.Text("Paste the HTML of a recipe page, or upload the saved page. "+
+					"The recipe is imported as a private draft, and its ingredients are queued to be matched to products."),
+			),
+			FormInput("recipe-import-html", "Page HTML", nil, html.Textarea(
+				html.ID("recipe-import-html"),
+				html.Class("form-control"),
+				html.Style("height: 10rem"),
+				html.Name("html"),
+			)),
+			html.Div(
+				html.Class("mt-2"),
+				html.Label(
+					html.Class("form-label"),
+					html.For("recipe-import-file"),
+					gomponents.Text("Or upload an .html file"),
+				),
+				html.Input(
+					html.ID("recipe-import-file"),
+					html.Class("form-control"),
+					html.Type("file"),
+					html.Name("file"),
+					html.Accept(".html,.htm,text/html"),
+				),
+			),
+		),
+		gomponents.Group{
+			ModalDismiss(),
+			ModalSubmit(),
+		},
+	)
+}
+
+// RecipeImportQueue is the page for matching an imported recipe's ingredient lines to products
+func RecipeImportQueue(recipe data.Recipe) gomponents.Node {
+	return BasePage(fmt.Sprintf("Match %s ingredients", recipe.Name), "/", gomponents.Group{
+		html.Div(
+			html.Class("text-center"),
+			html.H3(gomponents.Textf("Match %s ingredients", recipe.Name)),
+			html.P(
+				html.Class("text-body-secondary"),
+				gomponents.Text("Search for a product for each ingredient line of the imported recipe, or skip lines that don't need one."),
+			),
+			html.Div(
+				htmx.Get(fmt.Sprintf("/recipes/%s/import/table", recipe.ListID)),
+				htmx.Swap("innerHTML"),
+				htmx.Trigger("load,ingredient-update from:body"),
+			),
+			html.A(
+				html.Class("btn btn-secondary"),
+				html.Href(fmt.Sprintf("/lists/%s/ingredients", recipe.ListID)),
+				gomponents.Text("View ingredients"),
+			),
+		),
+	})
+}
+
+func RecipeImportQueueTable(listID uuid.UUID, lines []data.RecipeImportIngredient) gomponents.Node {
+	if len(lines) == 0 {
+		return html.P(gomponents.Text("Every ingredient line has been matched or skipped."))
+	}
+
+	var rows gomponents.Group
+	for _, line := range lines {
+		rows = append(rows, html.Tr(
+			html.Td(gomponents.Text(line.Text)),
+			html.Td(
+				html.Div(
+					html.Class("btn-group"),
+					ModalButton(
+						"btn-primary",
+						"Match",
+						htmx.Get(fmt.Sprintf("/recipes/%s/import/%d", listID, line.Number)),
+					),
+					html.Button(
+						html.Type("button"),
+						html.Class("btn btn-secondary"),
+						gomponents.Text("Skip"),
+						htmx.Delete(fmt.Sprintf("/recipes/%s/import/%d", listID, line.Number)),
+						htmx.Swap("none"),
+					),
+				),
+			),
+		))
+	}
+	return html.Table(
+		html.Class("table table-striped table-bordered text-center align-middle w-100"),
+		html.THead(
+			html.Tr(
+				html.Th(gomponents.Text("Ingredient line")),
+				html.Th(gomponents.Text("Actions")),
+			),
+		),
+		html.TBody(
+			html.Class("table-group-divider"),
+			rows,
+		),
+	)
+}
+
+// RecipeImportMatchModalContent searches products for the ingredient line, starting with the item read from the line as the search
+func RecipeImportMatchModalContent(line data.RecipeImportIngredient, sections []string) gomponents.Node {
+	parsed := parser.ParseIngredient(line.Text)
+	return ModalContent(
+		"Match ingredient",
+		ModalForm(
+			htmx.Post(fmt.Sprintf("/recipes/%s/import/%d", line.ListID, line.Number)),
+			html.P(html.Class("lead"), gomponents.Text(line.Text)),
+			ParsedIngredientSummary(parsed),
+			ProductsSearch(parsedIngredientSearch(parsed)),
+			IngredientQuantityInput(parsedIngredientAmountInput(parsed)),
+			IngredientDetailsInputs(parsedIngredientDetails(parsed), sections),
+		),
+		gomponents.Group{
+			ModalDismiss(),
+			ModalSubmit(),
+		},
+	)
+}
+
+// RecipeImportBadge links the owner of an imported recipe to the ingredient lines left to match
+func RecipeImportBadge(recipe data.Recipe) gomponents.Node {
+	if recipe.ImportIngredientCount == 0 {
+		return nil
+	}
+	return html.A(
+		html.Class("badge text-bg-warning ms-1"),
+		html.Href(fmt.Sprintf("/recipes/%s/import", recipe.ListID)),
+		gomponents.Textf("%d ingredients to match", recipe.ImportIngredientCount),
+	)
+}
@@ -0,0 +1,72 @@
+package templates
+
+import (
+	"fmt"
+	"strings"
+
+	"maragu.dev/gomponents"
+	htmx "maragu.dev/gomponents-htmx"
+	"maragu.dev/gomponents/html"
+
+	"github.com/densestvoid/krogerrecipeshopper/app"
+	"github.com/densestvoid/krogerrecipeshopper/data"
+)
+
+// RecipePhotoLink is the link to the recipe photo's variant for the image size, the recipe must have a photo
+func RecipePhotoLink(recipe data.Recipe, imageSize string) string {
+	return fmt.Sprintf("/recipes/%s/photo/%s/%s", recipe.ListID, recipe.PhotoID, imageSize)
+}
+
+// RecipePhoto is empty if the recipe has no photo
+func RecipePhoto(recipe data.Recipe, imageSize string) gomponents.Node {
+	if recipe.PhotoID == nil {
+		return nil
+	}
+	return html.Img(
+		html.Class("img-fluid img-thumbnail"),
+		html.Src(RecipePhotoLink(recipe, imageSize)),
+		html.Alt(recipe.Name),
+		html.Loading("lazy"),
+	)
+}
+
+// RecipePhotoForm uploads or removes the recipe's photo.
+// It can't be part of the recipe's edit form since the photo is sent as multipart form data.
+func RecipePhotoForm(recipe data.Recipe, imageSize string) gomponents.Node {
+	return html.Form(
+		html.ID("recipe-photo"),
+		html.Class("text-center mt-3"),
+		htmx.Post(fmt.Sprintf("/recipes/%s/photo", recipe.ListID)),
+		htmx.Encoding("multipart/form-data"),
+		htmx.Target("this"),
+		htmx.Swap("outerHTML"),
+		html.H5(gomponents.Text("Photo")),
+		RecipePhoto(recipe, imageSize),
+		html.Div(
+			html.Class("input-group mt-2"),
+			html.Input(
+				html.Class("form-control"),
+				html.Type("file"),
+				html.Name("photo"),
+				html.Accept(strings.Join(app.RecipePhotoContentTypes, ",")),
+				html.Required(),
+			),
+			html.Button(
+				html.Type("submit"),
+				html.Class("btn btn-primary"),
+				gomponents.Text("Upload"),
+			),
+			gomponents.If(recipe.PhotoID != nil, html.Button(
+				html.Type("button"),
+				html.Class("btn btn-danger"),
+				htmx.Delete(fmt.Sprintf("/recipes/%s/photo", recipe.ListID)),
+				htmx.Confirm("Are you sure you want to remove this photo?"),
+				gomponents.Text("Remove"),
+			)),
+		),
+		html.Div(
+			html.Class("form-text"),
+			gomponents.Textf("JPEG, PNG or WebP, up to %d MB", app.RecipePhotoMaxBytes>>20),
+		),
+	)
+}
@@ -0,0 +1,137 @@
+package templates
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"maragu.dev/gomponents"
+	htmx "maragu.dev/gomponents-htmx"
+	"maragu.dev/gomponents/html"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/google/uuid"
+)
+
+// RatingStars shows the rating rounded to the nearest half star
+func RatingStars(rating float64) gomponents.Node {
+	halves := int(math.Round(rating * 2))
+	var stars gomponents.Group
+	for star := 1; star <= data.RecipeRatingMax; star++ {
+		class := "bi bi-star"
+		if halves >= star*2 {
+			class = "bi bi-star-fill"
+		} else if halves == star*2-1 {
+			class = "bi bi-star-half"
+		}
+		stars = append(stars, html.I(html.Class(class)))
+	}
+	return html.Span(
+		html.Class("text-warning"),
+		html.Title(fmt.Sprintf("%.1f out of %d", rating, data.RecipeRatingMax)),
+		stars,
+	)
+}
+
+func RecipeRating(recipe data.Recipe) gomponents.Node {
+	if recipe.ReviewCount == 0 {
+		return nil
+	}
+	return html.Div(
+		html.Class("small"),
+		RatingStars(recipe.Rating),
+		gomponents.Textf(" %.1f (%d)", recipe.Rating, recipe.ReviewCount),
+	)
+}
+
+// RecipeReviewsSection loads the reviews of the recipe
+func RecipeReviewsSection(listID uuid.UUID) gomponents.Node {
+	return html.Div(
+		html.ID("recipe-reviews"),
+		html.Class("text-start mt-3"),
+		htmx.Get(fmt.Sprintf("/recipes/%s/reviews", listID)),
+		htmx.Trigger("load"),
+		htmx.Swap("innerHTML"),
+	)
+}
+
+func RecipeReviews(accountID uuid.UUID, recipe data.Recipe, reviews []data.RecipeReview, reviewable bool) gomponents.Node {
+	var ownReview *data.RecipeReview
+	var reviewNodes gomponents.Group
+	for _, review := range reviews {
+		if review.AccountID == accountID {
+			ownReview = &review
+		}
+
+		reviewer := "Anonymous"
+		if review.DisplayName != nil {
+			reviewer = *review.DisplayName
+		}
+		reviewNodes = append(reviewNodes, html.Li(
+			html.Class("list-group-item"),
+			html.Div(
+				html.Class("d-flex justify-content-between"),
+				html.Strong(gomponents.Text(reviewer)),
+				html.Small(gomponents.Text(review.UpdatedAt.Format(time.DateOnly))),
+			),
+			RatingStars(float64(review.Rating)),
+			gomponents.If(review.Review != "", html.P(html.Class("mb-0"), gomponents.Text(review.Review))),
+		))
+	}
+
+	return gomponents.Group{
+		html.H4(gomponents.Text("Reviews")),
+		RecipeRating(recipe),
+		gomponents.If(reviewable, RecipeReviewForm(recipe.ListID, ownReview)),
+		gomponents.If(len(reviews) == 0, html.P(gomponents.Text("No reviews yet"))),
+		html.Ul(
+			html.Class("list-group"),
+			reviewNodes,
+		),
+	}
+}
+
+func RecipeReviewForm(listID uuid.UUID, review *data.RecipeReview) gomponents.Node {
+	ratings := []int{}
+	for rating := data.RecipeRatingMax; rating >= data.RecipeRatingMin; rating-- {
+		ratings = append(ratings, rating)
+	}
+	rating, text := data.RecipeRatingMax, ""
+	if review != nil {
+		rating, text = review.Rating, review.Review
+	}
+
+	return html.Form(
+		html.Class("card card-body my-2"),
+		htmx.Post(fmt.Sprintf("/recipes/%s/reviews", listID)),
+		htmx.Target("#recipe-reviews"),
+		htmx.Swap("innerHTML"),
+		Select("recipe-review-rating", "Rating", "rating", rating, ratings, nil),
+		FormInput("recipe-review-text", "Review", nil, html.Textarea(
+			html.ID("recipe-review-text"),
+			html.Class("form-control"),
+			html.Name("review"),
+			html.MaxLength(fmt.Sprintf("%d", data.RecipeReviewMaxLength)),
+			html.Style("height: 6rem"),
+			gomponents.Text(text),
+		)),
+		html.Div(
+			html.Class("d-flex gap-2 mt-2"),
+			html.Button(
+				html.Type("submit"),
+				html.Class("btn btn-primary"),
+				gomponents.If(review == nil, gomponents.Text("Add review")),
+				gomponents.If(review != nil, gomponents.Text("Update review")),
+			),
+			gomponents.If(review != nil, html.Button(
+				html.Type("button"),
+				html.Class("btn btn-danger"),
+				gomponents.Text("Delete review"),
+				htmx.Delete(fmt.Sprintf("/recipes/%s/reviews", listID)),
+				htmx.Target("#recipe-reviews"),
+				htmx.Swap("innerHTML"),
+				htmx.Confirm("Are you sure you want to delete your review?"),
+			)),
+		),
+	)
+}
@@ -0,0 +1,177 @@
+package templates
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"maragu.dev/gomponents"
+	htmx "maragu.dev/gomponents-htmx"
+	"maragu.dev/gomponents/html"
+
+	"github.com/densestvoid/krogerrecipeshopper/app"
+	"github.com/densestvoid/krogerrecipeshopper/data"
+)
+
+// RecipeRevision is a revision with its changes from the previous revision
+type RecipeRevision struct {
+	data.RecipeRevision
+	Diff app.RecipeDiff
+}
+
+func RecipeHistory(recipe data.Recipe, revisions []RecipeRevision, products map[string]Product) gomponents.Node {
+	numbers := []int{}
+	for _, revision := range revisions {
+		numbers = append(numbers, revision.Number)
+	}
+
+	var revisionCards gomponents.Group
+	for i, revision := range revisions {
+		revisionCards = append(revisionCards, RecipeRevisionCard(recipe, revision, products, i != 0))
+	}
+
+	var compare gomponents.Node
+	if len(revisions) > 1 {
+		compare = html.Form(
+			html.Class("card card-body my-2"),
+			htmx.Get(fmt.Sprintf("/recipes/%s/history/diff", recipe.ListID)),
+			htmx.Target("#recipe-revision-diff"),
+			htmx.Swap("innerHTML"),
+			htmx.Trigger("load,change"),
+			html.H5(gomponents.Text("Compare revisions")),
+			html.Div(
+				html.Class("d-flex gap-2"),
+				Select("recipe-revision-from", "From", "from", numbers[1], numbers, nil),
+				Select("recipe-revision-to", "To", "to", numbers[0], numbers, nil),
+			),
+			html.Div(html.ID("recipe-revision-diff"), html.Class("mt-2")),
+		)
+	}
+
+	return BasePage(fmt.Sprintf("%s history", recipe.Name), "/", gomponents.Group{
+		html.Div(
+			html.Class("text-center"),
+			html.H3(gomponents.Textf("%s history", recipe.Name)),
+			compare,
+			revisionCards,
+		),
+	})
+}
+
+func RecipeRevisionCard(recipe data.Recipe, revision RecipeRevision, products map[string]Product, canRestore bool) gomponents.Node {
+	return html.Div(
+		html.Class("card my-2"),
+		html.Div(
+			html.Class("card-header d-flex justify-content-between align-items-center"),
+			html.Span(
+				gomponents.Textf("Revision %d", revision.Number),
+				gomponents.Iff(revision.RestoredFrom != nil, func() gomponents.Node {
+					return gomponents.Textf(" (restored from %d)", *revision.RestoredFrom)
+				}),
+			),
+			html.Small(gomponents.Text(revision.CreatedAt.Format(time.DateTime))),
+			gomponents.If(canRestore, html.Button(
+				html.Type("button"),
+				html.Class("btn btn-secondary btn-sm"),
+				gomponents.Text("Restore this version"),
+				htmx.Post(fmt.Sprintf("/recipes/%s/history/%d/restore", recipe.ListID, revision.Number)),
+				htmx.Swap("none"),
+				htmx.Confirm("Are you sure you want to restore this version? The current version stays in the history."),
+			)),
+		),
+		html.Div(
+			html.Class("card-body"),
+			RecipeDiffView(revision.Diff, products),
+		),
+	)
+}
+
+func RecipeDiffView(diff app.RecipeDiff, products map[string]Product) gomponents.Node {
+	if diff.Empty() {
+		return html.P(gomponents.Text("No changes"))
+	}
+
+	var fieldRows gomponents.Group
+	for _, field := range diff.Fields {
+		fieldRows = append(fieldRows, html.Tr(
+			html.Td(gomponents.Text(field.Field)),
+			html.Td(html.Class("text-danger text-break"), html.Style("white-space: pre-wrap"), gomponents.Text(field.From)),
+			html.Td(html.Class("text-success text-break"), html.Style("white-space: pre-wrap"), gomponents.Text(field.To)),
+		))
+	}
+
+	var ingredientRows gomponents.Group
+	for _, change := range diff.Ingredients {
+		var changeText string
+		switch {
+		case change.Added():
+			changeText = "Added"
+		case change.Removed():
+			changeText = "Removed"
+		default:
+			changeText = "Changed"
+		}
+		ingredientRows = append(ingredientRows, html.Tr(
+			html.Td(gomponents.Text(productName(products, change.ProductID))),
+			html.Td(gomponents.Text(changeText)),
+			html.Td(html.Class("text-danger"), snapshotIngredientText(change.From)),
+			html.Td(html.Class("text-success"), snapshotIngredientText(change.To)),
+		))
+	}
+
+	return gomponents.Group{
+		gomponents.If(len(fieldRows) > 0, html.Table(
+			html.Class("table table-bordered align-middle w-100"),
+			html.THead(html.Tr(
+				html.Th(gomponents.Text("Field")),
+				html.Th(gomponents.Text("Before")),
+				html.Th(gomponents.Text("After")),
+			)),
+			html.TBody(fieldRows),
+		)),
+		gomponents.If(len(ingredientRows) > 0, html.Table(
+			html.Class("table table-bordered align-middle w-100"),
+			html.THead(html.Tr(
+				html.Th(gomponents.Text("Ingredient")),
+				html.Th(gomponents.Text("Change")),
+				html.Th(gomponents.Text("Before")),
+				html.Th(gomponents.Text("After")),
+			)),
+			html.TBody(ingredientRows),
+		)),
+	}
+}
+
+func productName(products map[string]Product, productID string) string {
+	product, ok := products[productID]
+	if !ok {
+		return productID
+	}
+	return product.Name()
+}
+
+func snapshotIngredientText(ingredient *data.RecipeSnapshotIngredient) gomponents.Node {
+	if ingredient == nil {
+		return nil
+	}
+	text := ingredientQuantityText(ingredient.Quantity, ingredient.Amount, ingredient.Unit)
+	if ingredient.Staple {
+		text = fmt.Sprintf("%.2f (staple)", float64(ingredient.Quantity)/100)
+	}
+	// Details are listed so changes to only them, or to the order, don't look like no change
+	details := []string{text}
+	if ingredient.DisplayName != "" {
+		details = append(details, fmt.Sprintf("shown as %q", ingredient.DisplayName))
+	}
+	if ingredient.Section != "" {
+		details = append(details, fmt.Sprintf("in %s", ingredient.Section))
+	}
+	if ingredient.Note != "" {
+		details = append(details, ingredient.Note)
+	}
+	if len(ingredient.Alternatives) > 0 {
+		details = append(details, fmt.Sprintf("or %s", strings.Join(ingredient.Alternatives, ", ")))
+	}
+	details = append(details, fmt.Sprintf("position %d", ingredient.Position+1))
+	return gomponents.Text(strings.Join(details, ", "))
+}
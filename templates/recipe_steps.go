@@ -0,0 +1,352 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/google/uuid"
+	"maragu.dev/gomponents"
+	"maragu.dev/gomponents/html"
+
+	"github.com/densestvoid/krogerrecipeshopper/app"
+	"github.com/densestvoid/krogerrecipeshopper/data"
+)
+
+// ingredientName is the ingredient's display name, or its product without one
+func ingredientName(ingredient Ingredient) string {
+	if ingredient.DisplayName != "" {
+		return ingredient.DisplayName
+	}
+	return ingredient.Product.Name()
+}
+
+// RecipeStepsInput edits the steps of text instructions, each with a timer in minutes and the ingredients it uses.
+// It's placed inside the instructions input, which tracks the instruction type.
+func RecipeStepsInput(steps []data.RecipeStep, ingredients []Ingredient) gomponents.Node {
+	type stepInput struct {
+		ID         int      `json:"id"`
+		Text       string   `json:"text"`
+		Minutes    string   `json:"minutes"`
+		ProductIDs []string `json:"productIDs"`
+	}
+	stepInputs := []stepInput{}
+	for i, step := range steps {
+		input := stepInput{ID: i, Text: step.Text, ProductIDs: step.ProductIDs}
+		if step.TimerSeconds > 0 {
+			input.Minutes = strconv.FormatFloat(math.Round(float64(step.TimerSeconds)/60*100)/100, 'f', -1, 64)
+		}
+		if input.ProductIDs == nil {
+			input.ProductIDs = []string{}
+		}
+		stepInputs = append(stepInputs, input)
+	}
+	if len(stepInputs) == 0 {
+		stepInputs = append(stepInputs, stepInput{ProductIDs: []string{}})
+	}
+	stepsJSON, _ := json.Marshal(stepInputs)
+
+	var ingredientChecks gomponents.Group
+	for _, ingredient := range ingredients {
+		id := fmt.Sprintf("'step-' + step.id + '-ingredient-%s'", ingredient.ProductID)
+		ingredientChecks = append(ingredientChecks, html.Div(
+			html.Class("form-check form-check-inline"),
+			html.Input(
+				gomponents.Attr("x-bind:id", id),
+				gomponents.Attr("x-bind:name", "'step-ingredients-' + index"),
+				gomponents.Attr("x-bind:disabled", "instructionType != 'text'"),
+				gomponents.Attr("x-model", "step.productIDs"),
+				html.Class("form-check-input"),
+				html.Type("checkbox"),
+				html.Value(ingredient.ProductID),
+			),
+			html.Label(
+				gomponents.Attr("x-bind:for", id),
+				html.Class("form-check-label"),
+				gomponents.Text(ingredientName(ingredient)),
+			),
+		))
+	}
+
+	stepButton := func(icon, label, onClick string, attributes ...gomponents.Node) gomponents.Node {
+		return html.Button(
+			html.Type("button"),
+			html.Class("btn btn-outline-secondary btn-sm"),
+			html.Title(label),
+			gomponents.Attr("x-on:click", onClick),
+			gomponents.Group(attributes),
+			html.I(html.Class("bi "+icon)),
+		)
+	}
+
+	return html.Div(
+		gomponents.Attr("x-show", "instructionType == 'text'"),
+		gomponents.Attr("x-data", fmt.Sprintf(`{
+			steps: %s,
+			nextID: %d,
+			move(index, offset) {
+				const [step] = this.steps.splice(index, 1);
+				this.steps.splice(index + offset, 0, step);
+			},
+		}`, stepsJSON, len(stepInputs))),
+		html.Class("text-start"),
+		html.Template(
+			gomponents.Attr("x-for", "(step, index) in steps"),
+			gomponents.Attr("x-bind:key", "step.id"),
+			html.Div(
+				html.Class("card card-body my-2"),
+				html.Div(
+					html.Class("d-flex justify-content-between align-items-center mb-2"),
+					html.H6(html.Class("mb-0"), gomponents.Text("Step "), html.Span(gomponents.Attr("x-text", "index + 1"))),
+					html.Div(
+						html.Class("btn-group"),
+						stepButton("bi-arrow-up", "Move up", "move(index, -1)", gomponents.Attr("x-bind:disabled", "index == 0")),
+						stepButton("bi-arrow-down", "Move down", "move(index, 1)", gomponents.Attr("x-bind:disabled", "index == steps.length - 1")),
+						stepButton("bi-trash", "Remove step", "steps.splice(index, 1)", gomponents.Attr("x-bind:disabled", "steps.length == 1")),
+					),
+				),
+				html.Textarea(
+					gomponents.Attr("x-model", "step.text"),
+					gomponents.Attr("x-bind:disabled", "instructionType != 'text'"),
+					html.Class("form-control mb-2"),
+					html.Name("step-text"),
+					html.Rows("2"),
+					html.MaxLength(strconv.Itoa(data.RecipeStepMaxLength)),
+					html.Placeholder("What to do"),
+					html.Required(),
+				),
+				html.Div(
+					html.Class("input-group input-group-sm mb-2"),
+					html.Span(html.Class("input-group-text"), html.I(html.Class("bi bi-stopwatch"))),
+					html.Input(
+						gomponents.Attr("x-model", "step.minutes"),
+						gomponents.Attr("x-bind:disabled", "instructionType != 'text'"),
+						html.Class("form-control"),
+						html.Type("number"),
+						html.Name("step-timer"),
+						html.Min("0"),
+						html.Step("any"),
+						html.Placeholder("Timer in minutes (optional)"),
+					),
+				),
+				gomponents.If(len(ingredientChecks) > 0, html.Div(
+					html.Small(html.Class("text-body-secondary d-block"), gomponents.Text("Ingredients used")),
+					ingredientChecks,
+				)),
+			),
+		),
+		html.Button(
+			html.Type("button"),
+			html.Class("btn btn-secondary btn-sm"),
+			gomponents.Attr("x-on:click", "steps.push({id: nextID++, text: '', minutes: '', productIDs: []})"),
+			gomponents.Text("Add step"),
+		),
+	)
+}
+
+// RecipeStepsView lists the steps with their timers, linking to the cook mode
+func RecipeStepsView(recipe data.Recipe) gomponents.Node {
+	var stepItems gomponents.Group
+	for _, step := range recipe.Steps {
+		stepItems = append(stepItems, html.Li(
+			html.Class("mb-1"),
+			Markdown(step.Text),
+			gomponents.If(step.TimerSeconds > 0, html.Span(
+				html.Class("badge text-bg-secondary ms-1"),
+				html.I(html.Class("bi bi-stopwatch me-1")),
+				gomponents.Text(app.FormatTimer(step.TimerSeconds)),
+			)),
+		))
+	}
+
+	return html.Div(
+		html.Class("card"),
+		html.H4(gomponents.Text("Instructions")),
+		html.Hr(),
+		html.Ol(
+			html.Class("text-start"),
+			stepItems,
+		),
+		// Previews of unsaved recipes have nothing to cook yet
+		gomponents.If(recipe.ListID != uuid.Nil, html.A(
+			html.Href(fmt.Sprintf("/recipes/%s/cook", recipe.ListID)),
+			html.Class("btn btn-primary m-2"),
+			html.I(html.Class("bi bi-fire me-1")),
+			gomponents.Text("Cook mode"),
+		)),
+	)
+}
+
+// RecipeCookMode shows one step at a time in large text with its timer and ingredients,
+// keeping the screen awake while the page is open
+func RecipeCookMode(recipe data.Recipe, ingredients []Ingredient) gomponents.Node {
+	var stepSections gomponents.Group
+	for i, step := range recipe.Steps {
+		var stepIngredients gomponents.Group
+		for _, ingredient := range ingredients {
+			if !slices.Contains(step.ProductIDs, ingredient.ProductID) {
+				continue
+			}
+			quantity := data.Ingredient{Quantity: ingredient.Quantity}.QuantityDecimalString()
+			if ingredient.Unit != "" {
+				quantity = fmt.Sprintf("%s %s", formatAmount(ingredient.Amount), ingredient.Unit)
+			}
+			stepIngredients = append(stepIngredients, html.Li(
+				html.Class("list-group-item fs-4"),
+				gomponents.Text(ingredientName(ingredient)),
+				gomponents.If(quantity != "", html.Span(html.Class("badge text-bg-primary ms-2"), gomponents.Text(quantity))),
+				gomponents.If(ingredient.Note != "", html.Div(html.Class("fs-6 text-body-secondary"), gomponents.Text(ingredient.Note))),
+			))
+		}
+
+		stepSections = append(stepSections, html.Section(
+			gomponents.Attr("x-show", fmt.Sprintf("step == %d", i)),
+			html.Class("text-center"),
+			html.P(html.Class("text-body-secondary fs-5"), gomponents.Textf("Step %d of %d", i+1, len(recipe.Steps))),
+			html.Div(html.Class("display-5"), Markdown(step.Text)),
+			gomponents.If(step.TimerSeconds > 0, cookModeTimer(step.TimerSeconds)),
+			gomponents.If(len(stepIngredients) > 0, html.Ul(
+				html.Class("list-group mx-auto my-3"),
+				html.Style("max-width: 40rem"),
+				stepIngredients,
+			)),
+		))
+	}
+
+	return BasePage(fmt.Sprintf("Cooking %s", recipe.Name), "/", gomponents.Group{
+		html.Div(
+			gomponents.Attr("x-data", fmt.Sprintf(`{
+				step: 0,
+				steps: %d,
+				wakeLock: null,
+				async lockScreen() {
+					if (!('wakeLock' in navigator) || document.visibilityState != 'visible') return;
+					// The screen can still sleep if the lock is refused, e.g. in battery saver mode
+					try {
+						this.wakeLock = await navigator.wakeLock.request('screen');
+					} catch {}
+				},
+			}`, len(recipe.Steps))),
+			// The wake lock is released whenever the page is hidden, so it's requested again when it's shown
+			gomponents.Attr("x-init", "lockScreen()"),
+			gomponents.Attr("x-on:visibilitychange.document", "lockScreen()"),
+			gomponents.Attr("x-on:keydown.right.window", "step = Math.min(step + 1, steps - 1)"),
+			gomponents.Attr("x-on:keydown.left.window", "step = Math.max(step - 1, 0)"),
+			html.Class("container d-flex flex-column min-vh-100 py-3"),
+			html.Div(
+				html.Class("d-flex justify-content-between align-items-center"),
+				html.H3(html.Class("mb-0"), gomponents.Text(recipe.Name)),
+				html.Button(
+					html.Type("button"),
+					html.Class("btn btn-outline-secondary"),
+					html.Title("Full screen"),
+					gomponents.Attr("x-on:click", "document.fullscreenElement ? document.exitFullscreen() : document.documentElement.requestFullscreen()"),
+					html.I(html.Class("bi bi-arrows-fullscreen")),
+				),
+			),
+			html.Div(
+				html.Class("progress my-3"),
+				html.Style("height: 0.5rem"),
+				html.Div(
+					html.Class("progress-bar"),
+					gomponents.Attr("x-bind:style", "`width: ${(step + 1) / steps * 100}%`"),
+				),
+			),
+			gomponents.If(len(recipe.Steps) == 0, html.P(html.Class("display-6 text-center"), gomponents.Text("This recipe has no steps"))),
+			html.Div(
+				html.Class("flex-grow-1 d-flex flex-column justify-content-center"),
+				stepSections,
+			),
+			html.Div(
+				html.Class("d-flex justify-content-between"),
+				html.Button(
+					html.Type("button"),
+					html.Class("btn btn-secondary btn-lg"),
+					gomponents.Attr("x-on:click", "step--"),
+					gomponents.Attr("x-bind:disabled", "step == 0"),
+					html.I(html.Class("bi bi-arrow-left me-1")),
+					gomponents.Text("Previous"),
+				),
+				html.Button(
+					html.Type("button"),
+					html.Class("btn btn-primary btn-lg"),
+					gomponents.Attr("x-on:click", "step++"),
+					gomponents.Attr("x-bind:disabled", "step >= steps - 1"),
+					gomponents.Text("Next"),
+					html.I(html.Class("bi bi-arrow-right ms-1")),
+				),
+			),
+		),
+	})
+}
+
+// cookModeTimer counts down from the step's timer, it keeps running while other steps are shown
+func cookModeTimer(seconds int) gomponents.Node {
+	return html.Div(
+		gomponents.Attr("x-data", fmt.Sprintf(`{
+			seconds: %d,
+			remaining: %d,
+			interval: null,
+			get display() {
+				const hours = Math.floor(this.remaining / 3600);
+				const minutes = Math.floor(this.remaining / 60) %% 60;
+				const seconds = String(this.remaining %% 60).padStart(2, '0');
+				return hours > 0 ? `+"`${hours}:${String(minutes).padStart(2, '0')}:${seconds}`"+` : `+"`${minutes}:${seconds}`"+`;
+			},
+			start() {
+				if (this.interval || this.remaining == 0) return;
+				this.interval = setInterval(() => {
+					this.remaining--;
+					if (this.remaining == 0) {
+						this.pause();
+						navigator.vibrate?.([300, 100, 300]);
+					}
+				}, 1000);
+			},
+			pause() {
+				clearInterval(this.interval);
+				this.interval = null;
+			},
+			reset() {
+				this.pause();
+				this.remaining = this.seconds;
+			},
+		}`, seconds, seconds)),
+		html.Class("my-3"),
+		html.Div(
+			html.Class("display-1 font-monospace"),
+			gomponents.Attr("x-bind:class", "remaining == 0 && 'text-danger'"),
+			gomponents.Attr("x-text", "display"),
+			gomponents.Text(app.FormatTimer(seconds)),
+		),
+		html.Div(
+			html.Class("btn-group btn-group-lg"),
+			html.Button(
+				html.Type("button"),
+				html.Class("btn btn-success"),
+				gomponents.Attr("x-show", "!interval"),
+				gomponents.Attr("x-on:click", "start()"),
+				gomponents.Attr("x-bind:disabled", "remaining == 0"),
+				html.I(html.Class("bi bi-play-fill me-1")),
+				gomponents.Text("Start"),
+			),
+			html.Button(
+				html.Type("button"),
+				html.Class("btn btn-warning"),
+				gomponents.Attr("x-show", "interval"),
+				gomponents.Attr("x-on:click", "pause()"),
+				html.I(html.Class("bi bi-pause-fill me-1")),
+				gomponents.Text("Pause"),
+			),
+			html.Button(
+				html.Type("button"),
+				html.Class("btn btn-outline-secondary"),
+				gomponents.Attr("x-on:click", "reset()"),
+				html.I(html.Class("bi bi-arrow-counterclockwise me-1")),
+				gomponents.Text("Reset"),
+			),
+		),
+	)
+}
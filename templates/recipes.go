@@ -2,12 +2,14 @@ package templates
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
 
 	"maragu.dev/gomponents"
 	htmx "maragu.dev/gomponents-htmx"
 	"maragu.dev/gomponents/html"
 
+	"github.com/densestvoid/krogerrecipeshopper/app"
 	"github.com/densestvoid/krogerrecipeshopper/data"
 	"github.com/google/uuid"
 )
@@ -33,7 +35,7 @@ func Recipes(accountID uuid.UUID) gomponents.Node {
 				html.Class("card card-body collapse"),
 				FormInput(
 					"recipe-name",
-					"Name",
+					"Search",
 					nil,
 					html.Input(
 						html.Name("name"),
@@ -77,6 +79,8 @@ func Recipes(accountID uuid.UUID) gomponents.Node {
 						),
 					),
 				),
+				RecipeSortSelect(),
+				RecipeTagFacetsContainer(),
 				htmx.Post("/recipes/search"),
 				htmx.Target("#recipe-table"),
 				htmx.Swap("innerHTML"),
@@ -89,6 +93,11 @@ func Recipes(accountID uuid.UUID) gomponents.Node {
 				"Add recipe",
 				htmx.Get("/recipes//details"),
 			),
+			ModalButton(
+				"btn-secondary",
+				"Import recipe",
+				htmx.Get("/recipes/import"),
+			),
 		),
 	})
 }
@@ -114,13 +123,15 @@ func FavoriteRecipes() gomponents.Node {
 				html.Class("card card-body collapse"),
 				FormInput(
 					"recipe-name",
-					"Name",
+					"Search",
 					nil,
 					html.Input(
 						html.Name("name"),
 						html.Class("form-control"),
 					),
 				),
+				RecipeSortSelect(),
+				RecipeTagFacetsContainer(),
 				htmx.Post("/recipes/search"),
 				htmx.Target("#recipe-table"),
 				htmx.Swap("innerHTML"),
@@ -142,36 +153,105 @@ func ExploreRecipes() gomponents.Node {
 			html.H3(
 				gomponents.Text("Explore"),
 			),
-			html.Div(
+			html.Form(
 				html.H3(gomponents.Text("Search recipes")),
 				html.Input(
 					html.Class("form-control"),
 					html.Type("search"),
 					html.Name("name"),
 					html.Placeholder("Begin typing to seach recipes"),
-					htmx.Post("/recipes/search"),
-					htmx.Trigger("input changed delay:500ms, keyup[key=='Enter']"),
-					htmx.Target("#recipes-search-table"),
-					htmx.Vals(fmt.Sprintf(`{
-						"visibility": ["%s", "%s", "%s"]
-					}`, data.VisibilityPublic, data.VisibilityFriends, data.VisibilityPrivate)),
-					htmx.Indicator(".htmx-indicator"),
 				),
-				html.Span(html.Class("htmx-indicator"), gomponents.Text("Searching...")),
-				html.Div(html.ID("recipes-search-table")),
+				RecipeSortSelect(),
+				RecipeTagFacetsContainer(),
+				htmx.Post("/recipes/search"),
+				htmx.Trigger("load,change delay:500ms,input changed delay:500ms,submit"),
+				htmx.Target("#recipes-search-table"),
+				htmx.Swap("innerHTML"),
+				htmx.Vals(fmt.Sprintf(`{
+					"visibility": ["%s", "%s", "%s"]
+				}`, data.VisibilityPublic, data.VisibilityFriends, data.VisibilityPrivate)),
+				htmx.Indicator(".htmx-indicator"),
+			),
+			html.Span(html.Class("htmx-indicator"), gomponents.Text("Searching...")),
+			html.Div(html.ID("recipes-search-table")),
+		),
+	})
+}
+
+const (
+	RecipeSortName   = "name"
+	RecipeSortRating = "rating"
+)
+
+func RecipeSortSelect() gomponents.Node {
+	return Select("recipe-sort", "Sort by", "sort", RecipeSortName, []string{RecipeSortName, RecipeSortRating}, nil)
+}
+
+// ProductRecipes lists the visible recipes that use the products
+func ProductRecipes(products []Product, productMatch string) gomponents.Node {
+	var productChecks gomponents.Group
+	for i, product := range products {
+		id := fmt.Sprintf("recipe-product-%d", i)
+		productChecks = append(productChecks, FormCheck(id, fmt.Sprintf("%s %s %s", product.Brand, product.Description, product.Size), false, html.Input(
+			html.ID(id),
+			html.Class("form-check-input"),
+			html.Type("checkbox"),
+			html.Name("productID"),
+			html.Value(product.ProductID),
+			html.Checked(),
+		)))
+	}
+
+	return BasePage("Recipes with products", "/", gomponents.Group{
+		html.Div(
+			html.Class("text-center"),
+			html.H3(
+				gomponents.Text("Recipes with products"),
+			),
+			html.Form(
+				html.ID("recipe-filters"),
+				html.Class("card card-body text-start"),
+				productChecks,
+				gomponents.If(len(products) > 1, Select("recipe-product-match", "Product match", "product-match", productMatch, []string{MatchAny, MatchAll}, nil)),
+				RecipeSortSelect(),
+				RecipeTagFacetsContainer(),
+				htmx.Post("/recipes/search"),
+				htmx.Target("#recipe-table"),
+				htmx.Swap("innerHTML"),
+				htmx.Vals(fmt.Sprintf(`{
+					"visibility": ["%s", "%s", "%s"]
+				}`, data.VisibilityPublic, data.VisibilityFriends, data.VisibilityPrivate)),
+				htmx.Trigger("load,change delay:500ms,recipe-update from:body"),
 			),
+			html.Div(html.ID("recipe-table")),
 		),
 	})
 }
 
-func RecipeDetailsModalContent(accountID uuid.UUID, recipe data.Recipe, copy bool) gomponents.Node {
+// ProductRecipesLink opens the recipes that use the product
+func ProductRecipesLink(productID string) gomponents.Node {
+	return html.A(
+		html.Class("btn btn-secondary btn-sm"),
+		html.Href(fmt.Sprintf("/recipes/products?productID=%s", url.QueryEscape(productID))),
+		html.Target("_blank"),
+		html.Title("Recipes with this product"),
+		gomponents.Text("Recipes"),
+	)
+}
+
+// RecipeDetailsModalContent is given the recipe's ingredients when editing it, for linking them to its steps
+func RecipeDetailsModalContent(accountID uuid.UUID, imageSize string, recipe data.Recipe, curatedTags []data.Tag, ingredients []Ingredient, copy bool) gomponents.Node {
 	viewOnly := recipe.ListID != uuid.Nil && recipe.AccountID != accountID && !copy
 
 	return ModalContent(
 		"Recipe details",
 		gomponents.Group{
-			gomponents.If(viewOnly, RecipeDetailsView(recipe)),
-			gomponents.If(!viewOnly, RecipeDetailsEdit(recipe, copy)),
+			gomponents.If(viewOnly, RecipeDetailsView(recipe, imageSize)),
+			gomponents.If(!viewOnly && !copy, RecipeLineage(recipe)),
+			gomponents.If(!viewOnly, RecipeDetailsEdit(recipe, curatedTags, ingredients, copy)),
+			gomponents.If(!viewOnly && !copy && recipe.ListID != uuid.Nil, RecipePhotoForm(recipe, imageSize)),
+			// The edit form can't contain the review form, so the author sees the reviews after it
+			gomponents.If(!viewOnly && !copy && recipe.ListID != uuid.Nil, RecipeReviewsSection(recipe.ListID)),
 		},
 		gomponents.Group{
 			ModalDismiss(),
@@ -180,41 +260,52 @@ func RecipeDetailsModalContent(accountID uuid.UUID, recipe data.Recipe, copy boo
 	)
 }
 
-func RecipeDetailsView(recipe data.Recipe) gomponents.Node {
+func RecipeDetailsView(recipe data.Recipe, imageSize string) gomponents.Node {
 	return html.Div(
 		html.Class("text-center"),
 		html.H2(gomponents.Text(recipe.Name)),
-		html.P(gomponents.Text(recipe.Description)),
-		gomponents.If(recipe.InstructionType != data.InstructionTypeNone,
-			html.Div(
-				gomponents.Iff(recipe.InstructionType == data.InstructionTypeText, func() gomponents.Node {
-					instructionNodes := gomponents.Group{}
-					for line := range strings.Lines(recipe.Instructions) {
-						instructionNodes = append(instructionNodes, html.Span(gomponents.Text(line)), html.Br())
-					}
-					return html.Div(
-						html.Class("card"),
-						html.H4(gomponents.Text("Instructions")),
-						html.Hr(),
-						html.Div(
-							html.Class("text-start"),
-							instructionNodes,
-						),
-					)
-				}),
-				gomponents.If(recipe.InstructionType == data.InstructionTypeLink, html.A(
-					html.Href(recipe.Instructions),
-					html.Target("_blank"),
-					html.Class("btn btn-primary"),
-					html.Type("button"),
-					gomponents.Text("View instructions on site"),
-				)),
-			),
-		),
+		RecipePhoto(recipe, imageSize),
+		RecipeLineage(recipe),
+		RecipeContentView(recipe),
+		RecipeReviewsSection(recipe.ListID),
+	)
+}
+
+// RecipeContentView is the recipe's description and instructions, with the tags between them
+func RecipeContentView(recipe data.Recipe) gomponents.Node {
+	return gomponents.Group{
+		gomponents.If(recipe.Description != "", Markdown(recipe.Description)),
+		gomponents.If(len(recipe.Tags) > 0, html.P(TagBadges(recipe.Tags))),
+		gomponents.If(recipe.InstructionType == data.InstructionTypeText, RecipeStepsView(recipe)),
+		gomponents.If(recipe.InstructionType == data.InstructionTypeLink, gomponents.Iff(app.WebURL(recipe.Instructions), func() gomponents.Node {
+			return html.A(
+				html.Href(recipe.Instructions),
+				html.Target("_blank"),
+				html.Rel("noopener noreferrer nofollow"),
+				html.Class("btn btn-primary"),
+				html.Type("button"),
+				gomponents.Text("View instructions on site"),
+			)
+		})),
+		gomponents.If(recipe.InstructionType == data.InstructionTypeLink && !app.WebURL(recipe.Instructions), html.P(
+			html.Class("text-warning"),
+			gomponents.Text("The instructions link is hidden because it isn't an http or https URL"),
+		)),
+	}
+}
+
+// RecipePreview shows the description and instructions being edited
+func RecipePreview(recipe data.Recipe) gomponents.Node {
+	if recipe.Description == "" && recipe.InstructionType == data.InstructionTypeNone {
+		return html.P(html.Class("text-body-secondary"), gomponents.Text("Nothing to preview"))
+	}
+	return html.Div(
+		html.Class("text-center"),
+		RecipeContentView(recipe),
 	)
 }
 
-func RecipeDetailsEdit(recipe data.Recipe, copy bool) gomponents.Node {
+func RecipeDetailsEdit(recipe data.Recipe, curatedTags []data.Tag, ingredients []Ingredient, copy bool) gomponents.Node {
 	ifExists := func(node gomponents.Node) gomponents.Node {
 		return gomponents.If(recipe.ListID != uuid.Nil, node)
 	}
@@ -227,64 +318,98 @@ func RecipeDetailsEdit(recipe data.Recipe, copy bool) gomponents.Node {
 			html.Name("id"),
 			html.Value(recipe.ListID.String()),
 		)),
-		FormInput("recipe-name", "Recipe name", nil, html.Input(
-			html.ID("recipe-name"),
-			html.Class("form-control"),
-			html.Type("text"),
-			html.Name("name"),
-			ifExists(html.Value(recipe.Name)),
-			html.Required(),
-		)),
-		FormInput("recipe-description", "Recipe description", nil, html.Input(
-			html.ID("recipe-description"),
-			html.Class("form-control"),
-			html.Type("text"),
-			html.Name("description"),
-			ifExists(html.Value(recipe.Description)),
-		)),
-		Select("recipeVisibility", "Visibility", "visibility", recipe.Visibility, []string{
-			data.VisibilityPublic,
-			data.VisibilityFriends,
-			data.VisibilityPrivate,
-		}, nil),
 		html.Div(
-			gomponents.Attr("x-data", fmt.Sprintf("{instructionType: '%s'}", recipe.InstructionType)),
-			html.Div(
-				html.Class("input-group"),
-				html.Span(
-					html.Class("input-group-text"),
-					gomponents.Text("Recipe instructions"),
+			gomponents.Attr("x-data", "{tab: 'edit'}"),
+			html.Ul(
+				html.Class("nav nav-tabs mb-2"),
+				html.Li(
+					html.Class("nav-item"),
+					html.Button(
+						html.Type("button"),
+						html.Class("nav-link"),
+						gomponents.Attr("x-bind:class", "tab == 'edit' && 'active'"),
+						gomponents.Attr("x-on:click", "tab = 'edit'"),
+						gomponents.Text("Edit"),
+					),
 				),
-				Select(
-					"recipe-instruction-type",
-					"Instruction type",
-					"instruction-type",
-					recipe.InstructionType,
-					[]string{
-						data.InstructionTypeNone,
-						data.InstructionTypeText,
-						data.InstructionTypeLink,
-					},
-					gomponents.Attr("x-on:change", "instructionType = $event.target.value"),
+				html.Li(
+					html.Class("nav-item"),
+					// Posts the form's fields to render them, the form itself submits on its own
+					html.Button(
+						html.Type("button"),
+						html.Class("nav-link"),
+						gomponents.Attr("x-bind:class", "tab == 'preview' && 'active'"),
+						gomponents.Attr("x-on:click", "tab = 'preview'"),
+						htmx.Post("/recipes/preview"),
+						htmx.Target("#recipe-preview"),
+						htmx.Swap("innerHTML"),
+						gomponents.Text("Preview"),
+					),
 				),
 			),
-			html.Textarea(
-				gomponents.Attr("x-show", "instructionType == 'text'"),
-				gomponents.Attr("x-bind:disabled", "instructionType != 'text'"),
-				html.Class("form-control"),
-				html.Name("instructions"),
-				html.Rows("10"),
-				html.Required(),
-				gomponents.If(recipe.InstructionType == data.InstructionTypeText, gomponents.Text(recipe.Instructions)),
+			html.Div(
+				gomponents.Attr("x-show", "tab == 'edit'"),
+				FormInput("recipe-name", "Recipe name", nil, html.Input(
+					html.ID("recipe-name"),
+					html.Class("form-control"),
+					html.Type("text"),
+					html.Name("name"),
+					ifExists(html.Value(recipe.Name)),
+					html.Required(),
+					html.MaxLength(fmt.Sprintf("%d", data.ListNameMaxLength)),
+				)),
+				FormInput("recipe-description", "Recipe description (Markdown)", nil, html.Textarea(
+					html.ID("recipe-description"),
+					html.Class("form-control"),
+					html.Style("height: 6rem"),
+					html.Name("description"),
+					html.MaxLength(fmt.Sprintf("%d", data.ListDescriptionMaxLength)),
+					ifExists(gomponents.Text(recipe.Description)),
+				)),
+				Select("recipeVisibility", "Visibility", "visibility", recipe.Visibility, []string{
+					data.VisibilityPublic,
+					data.VisibilityFriends,
+					data.VisibilityPrivate,
+				}, nil),
+				html.Div(
+					gomponents.Attr("x-data", fmt.Sprintf("{instructionType: '%s'}", recipe.InstructionType)),
+					html.Div(
+						html.Class("input-group"),
+						html.Span(
+							html.Class("input-group-text"),
+							gomponents.Text("Recipe instructions"),
+						),
+						Select(
+							"recipe-instruction-type",
+							"Instruction type",
+							"instruction-type",
+							recipe.InstructionType,
+							[]string{
+								data.InstructionTypeNone,
+								data.InstructionTypeText,
+								data.InstructionTypeLink,
+							},
+							gomponents.Attr("x-on:change", "instructionType = $event.target.value"),
+						),
+					),
+					RecipeStepsInput(recipe.Steps, ingredients),
+					html.Input(
+						gomponents.Attr("x-show", "instructionType == 'link'"),
+						gomponents.Attr("x-bind:disabled", "instructionType != 'link'"),
+						html.Class("form-control"),
+						html.Type("url"),
+						html.Pattern("https?://.+"),
+						html.Title("An http or https URL"),
+						html.Name("instructions"),
+						gomponents.If(recipe.InstructionType == data.InstructionTypeLink, html.Value(recipe.Instructions)),
+						html.Required(),
+					),
+				),
+				RecipeTagsInput(recipe.Tags, curatedTags),
 			),
-			html.Input(
-				gomponents.Attr("x-show", "instructionType == 'link'"),
-				gomponents.Attr("x-bind:disabled", "instructionType != 'link'"),
-				html.Class("form-control"),
-				html.Type("url"),
-				html.Name("instructions"),
-				gomponents.If(recipe.InstructionType == data.InstructionTypeLink, html.Value(recipe.Instructions)),
-				html.Required(),
+			html.Div(
+				gomponents.Attr("x-show", "tab == 'preview'"),
+				html.ID("recipe-preview"),
 			),
 		),
 	)
@@ -325,10 +450,10 @@ func Disabled(b bool) gomponents.Node {
 	return nil
 }
 
-func RecipeTable(accountID uuid.UUID, recipes []data.Recipe) gomponents.Node {
+func RecipeTable(accountID uuid.UUID, imageSize string, recipes []data.Recipe) gomponents.Node {
 	var recipeRows gomponents.Group
 	for _, recipe := range recipes {
-		recipeRows = append(recipeRows, RecipeRow(accountID, recipe))
+		recipeRows = append(recipeRows, RecipeRow(accountID, imageSize, recipe))
 	}
 	return html.Table(
 		html.Class("table table-striped table-bordered text-center align-middle w-100"),
@@ -350,7 +475,7 @@ func RecipeTable(accountID uuid.UUID, recipes []data.Recipe) gomponents.Node {
 	)
 }
 
-func RecipeRow(accountID uuid.UUID, recipe data.Recipe) gomponents.Node {
+func RecipeRow(accountID uuid.UUID, imageSize string, recipe data.Recipe) gomponents.Node {
 	actions := gomponents.Group{
 		html.Li(
 			html.Class("dropdown-item"),
@@ -363,14 +488,19 @@ func RecipeRow(accountID uuid.UUID, recipe data.Recipe) gomponents.Node {
 		html.Li(
 			html.Class("dropdown-item"),
 			html.A(
+				html.Class("btn btn-secondary w-100"),
 				html.Href(fmt.Sprintf("/lists/%v/ingredients", recipe.ListID)),
-				html.Button(
-					html.Type("button"),
-					html.Class("btn btn-secondary w-100"),
-					gomponents.Text("Ingredients"),
-				),
+				gomponents.Text("Ingredients"),
 			),
 		),
+		gomponents.If(recipe.InstructionType == data.InstructionTypeText, html.Li(
+			html.Class("dropdown-item"),
+			html.A(
+				html.Class("btn btn-secondary w-100"),
+				html.Href(fmt.Sprintf("/recipes/%v/cook", recipe.ListID)),
+				gomponents.Text("Cook"),
+			),
+		)),
 		FavoriteButton(recipe.ListID, recipe.Favorite),
 		html.Li(
 			html.Class("dropdown-item"),
@@ -380,9 +510,33 @@ func RecipeRow(accountID uuid.UUID, recipe data.Recipe) gomponents.Node {
 				htmx.Get(fmt.Sprintf("/recipes/%s/copy", recipe.ListID.String())),
 			),
 		),
+		html.Li(
+			html.Class("dropdown-item"),
+			html.A(
+				html.Class("btn btn-secondary w-100"),
+				html.Href(fmt.Sprintf("/bundles/export?recipeID=%v", recipe.ListID)),
+				gomponents.Text("Export"),
+			),
+		),
 	}
 	if accountID == recipe.AccountID {
 		actions = append(actions,
+			html.Li(
+				html.Class("dropdown-item"),
+				html.A(
+					html.Class("btn btn-secondary w-100"),
+					html.Href(fmt.Sprintf("/recipes/%v/history", recipe.ListID)),
+					gomponents.Text("History"),
+				),
+			),
+			gomponents.If(recipe.SourceName != nil, html.Li(
+				html.Class("dropdown-item"),
+				html.A(
+					html.Class("btn btn-secondary w-100"),
+					html.Href(fmt.Sprintf("/recipes/%v/source", recipe.ListID)),
+					gomponents.Text("Source changes"),
+				),
+			)),
 			html.Li(html.Hr(html.Class("dropdown-divider"))),
 			html.Li(
 				html.Class("dropdown-item"),
@@ -399,11 +553,20 @@ func RecipeRow(accountID uuid.UUID, recipe data.Recipe) gomponents.Node {
 	}
 
 	return html.Tr(
-		html.Td(gomponents.Text(recipe.Name)),
+		html.Td(
+			gomponents.If(recipe.PhotoID != nil, html.Div(RecipePhoto(recipe, imageSize))),
+			gomponents.Text(recipe.Name),
+			gomponents.If(accountID == recipe.AccountID, RecipeSourceChangedBadge(recipe)),
+			gomponents.If(accountID == recipe.AccountID, RecipeImportBadge(recipe)),
+			RecipeRating(recipe),
+			gomponents.If(len(recipe.Tags) > 0, html.Div(TagBadges(recipe.Tags))),
+			SearchSnippet(recipe.SearchSnippet),
+		),
 		html.Td(
 			// Hide if the screen is small
 			html.Class("d-none d-sm-table-cell"),
-			gomponents.Text(recipe.Description)),
+			gomponents.Text(recipe.Description),
+		),
 		html.Td(
 			html.Div(
 				html.Class("btn-group dropdown-center"),
@@ -428,6 +591,27 @@ func RecipeRow(accountID uuid.UUID, recipe data.Recipe) gomponents.Node {
 	)
 }
 
+// SearchSnippet highlights the matched words of a search snippet, or is empty if nothing matched
+func SearchSnippet(snippet string) gomponents.Node {
+	if !strings.Contains(snippet, data.SearchSnippetStart) {
+		return nil
+	}
+
+	var nodes gomponents.Group
+	for i, part := range strings.Split(snippet, data.SearchSnippetStart) {
+		if i == 0 {
+			nodes = append(nodes, gomponents.Text(part))
+			continue
+		}
+		match, rest, _ := strings.Cut(part, data.SearchSnippetStop)
+		nodes = append(nodes, html.Mark(gomponents.Text(match)), gomponents.Text(rest))
+	}
+	return html.Div(
+		html.Class("small text-body-secondary"),
+		gomponents.Text("…"), nodes, gomponents.Text("…"),
+	)
+}
+
 func FavoriteButton(listID uuid.UUID, favorite bool) gomponents.Node {
 	if favorite {
 		return html.Li(
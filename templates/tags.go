@@ -0,0 +1,236 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"maragu.dev/gomponents"
+	htmx "maragu.dev/gomponents-htmx"
+	"maragu.dev/gomponents/html"
+
+	"github.com/densestvoid/krogerrecipeshopper/data"
+	"github.com/google/uuid"
+)
+
+// MatchAny and MatchAll are the options of the tag and product filters that match several values
+const (
+	MatchAny = "any"
+	MatchAll = "all"
+)
+
+var tagCategoryLabels = []struct {
+	Category string
+	Label    string
+}{
+	{data.TagCategoryCuisine, "Cuisine"},
+	{data.TagCategoryMeal, "Meal type"},
+	{data.TagCategoryDiet, "Diet"},
+	{"", "Tags"},
+}
+
+func tagCategory(tag data.Tag) string {
+	if tag.Category == nil {
+		return ""
+	}
+	return *tag.Category
+}
+
+func TagBadges(tags []string) gomponents.Node {
+	var badges gomponents.Group
+	for _, tag := range tags {
+		badges = append(badges, html.Span(
+			html.Class("badge rounded-pill text-bg-secondary me-1"),
+			gomponents.Text(tag),
+		))
+	}
+	return badges
+}
+
+// RecipeTagsInput is the curated tag checkboxes and a free-form tag picker for the recipe edit form
+func RecipeTagsInput(recipeTags []string, curatedTags []data.Tag) gomponents.Node {
+	curatedGroups := gomponents.Group{}
+	curatedNames := []string{}
+	for _, categoryLabel := range tagCategoryLabels[:3] {
+		var checks gomponents.Group
+		for _, tag := range curatedTags {
+			if tagCategory(tag) != categoryLabel.Category {
+				continue
+			}
+			curatedNames = append(curatedNames, tag.Name)
+			id := fmt.Sprintf("recipe-tag-%s", tag.ID)
+			checks = append(checks,
+				html.Input(
+					html.ID(id),
+					html.Class("btn-check"),
+					html.Type("checkbox"),
+					html.Name("tags"),
+					html.Value(tag.Name),
+					html.AutoComplete("off"),
+					gomponents.If(slices.Contains(recipeTags, tag.Name), html.Checked()),
+				),
+				html.Label(
+					html.Class("btn btn-outline-secondary btn-sm m-1"),
+					html.For(id),
+					gomponents.Text(tag.Name),
+				),
+			)
+		}
+		curatedGroups = append(curatedGroups, html.Div(
+			html.Class("mb-2"),
+			html.H6(gomponents.Text(categoryLabel.Label)),
+			checks,
+		))
+	}
+
+	freeFormTags := []string{}
+	for _, tag := range recipeTags {
+		if !slices.Contains(curatedNames, tag) {
+			freeFormTags = append(freeFormTags, tag)
+		}
+	}
+	freeFormJSON, _ := json.Marshal(freeFormTags)
+
+	return html.Div(
+		html.Class("card card-body text-start"),
+		curatedGroups,
+		html.Div(
+			gomponents.Attr("x-data", fmt.Sprintf(`{
+				tags: %s,
+				tag: '',
+				add() {
+					const tag = this.tag.trim().toLowerCase().split(/\s+/).join(' ');
+					if (tag && !this.tags.includes(tag)) this.tags.push(tag);
+					this.tag = '';
+				},
+			}`, freeFormJSON)),
+			html.H6(gomponents.Text("Tags")),
+			html.Div(
+				html.Class("mb-1"),
+				html.Template(
+					gomponents.Attr("x-for", "tag in tags"),
+					gomponents.Attr("x-bind:key", "tag"),
+					html.Span(
+						html.Class("badge rounded-pill text-bg-secondary me-1"),
+						html.Span(gomponents.Attr("x-text", "tag")),
+						html.Button(
+							html.Type("button"),
+							html.Class("btn-close btn-close-white ms-1"),
+							html.Aria("label", "Remove"),
+							gomponents.Attr("x-on:click", "tags = tags.filter(t => t !== tag)"),
+						),
+						html.Input(
+							html.Type("hidden"),
+							html.Name("tags"),
+							gomponents.Attr("x-bind:value", "tag"),
+						),
+					),
+				),
+			),
+			html.Div(
+				html.Class("input-group"),
+				html.Input(
+					html.Class("form-control"),
+					html.Type("text"),
+					html.Name("tag"),
+					html.Placeholder("Add a tag"),
+					html.MaxLength(fmt.Sprintf("%d", data.TagNameMaxLength)),
+					html.List("recipe-tag-options"),
+					html.AutoComplete("off"),
+					gomponents.Attr("x-model", "tag"),
+					gomponents.Attr("x-on:keydown.enter.prevent", "add()"),
+					htmx.Get("/recipes/tags"),
+					htmx.Trigger("input changed delay:300ms"),
+					htmx.Target("#recipe-tag-options"),
+					htmx.Swap("innerHTML"),
+				),
+				html.Button(
+					html.Type("button"),
+					html.Class("btn btn-secondary"),
+					gomponents.Text("Add"),
+					gomponents.Attr("x-on:click", "add()"),
+				),
+			),
+			html.DataList(html.ID("recipe-tag-options")),
+		),
+	)
+}
+
+func TagOptions(tags []data.Tag) gomponents.Node {
+	var options gomponents.Group
+	for _, tag := range tags {
+		options = append(options, html.Option(html.Value(tag.Name)))
+	}
+	return options
+}
+
+// RecipeTagFacets is the tag filter of a recipe filter panel with the number of matching recipes per tag
+func RecipeTagFacets(facets []data.TagFacet, selected []string, tagMatch string) gomponents.Node {
+	// Keep selected tags without matches visible so they can be deselected
+	for _, tag := range selected {
+		if !slices.ContainsFunc(facets, func(facet data.TagFacet) bool { return facet.Name == tag }) {
+			facets = append(facets, data.TagFacet{Tag: data.Tag{Name: tag}})
+		}
+	}
+
+	groups := gomponents.Group{}
+	facetIndex := 0
+	for _, categoryLabel := range tagCategoryLabels {
+		var checks gomponents.Group
+		for _, facet := range facets {
+			if tagCategory(facet.Tag) != categoryLabel.Category {
+				continue
+			}
+			id := fmt.Sprintf("recipe-facet-%d", facetIndex)
+			facetIndex++
+			checks = append(checks,
+				html.Input(
+					html.ID(id),
+					html.Class("btn-check"),
+					html.Type("checkbox"),
+					html.Name("tags"),
+					html.Value(facet.Name),
+					html.AutoComplete("off"),
+					gomponents.If(slices.Contains(selected, facet.Name), html.Checked()),
+				),
+				html.Label(
+					html.Class("btn btn-outline-secondary btn-sm m-1"),
+					html.For(id),
+					gomponents.Textf("%s (%d)", facet.Name, facet.Count),
+				),
+			)
+		}
+		if len(checks) == 0 {
+			continue
+		}
+		groups = append(groups, html.Div(
+			html.H6(gomponents.Text(categoryLabel.Label)),
+			checks,
+		))
+	}
+
+	return gomponents.Group{
+		Select("recipe-tag-match", "Tag match", "tag-match", tagMatch, []string{MatchAny, MatchAll}, nil),
+		groups,
+	}
+}
+
+// RecipeSearchResults is the recipe table with an out of band update of the tag facets
+func RecipeSearchResults(accountID uuid.UUID, imageSize string, recipes []data.Recipe, facets []data.TagFacet, selected []string, tagMatch string) gomponents.Node {
+	return gomponents.Group{
+		RecipeTable(accountID, imageSize, recipes),
+		html.Div(
+			html.ID("recipe-tag-facets"),
+			html.Class("text-start"),
+			htmx.SwapOOB("true"),
+			RecipeTagFacets(facets, selected, tagMatch),
+		),
+	}
+}
+
+func RecipeTagFacetsContainer() gomponents.Node {
+	return html.Div(
+		html.ID("recipe-tag-facets"),
+		html.Class("text-start"),
+	)
+}